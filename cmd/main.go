@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
+	"google.golang.org/grpc"
+
 	"github.com/abhay786-20/fraud-auth-service/internal/bootstrap"
+	"github.com/abhay786-20/fraud-auth-service/internal/rules"
+	"github.com/abhay786-20/fraud-auth-service/internal/service"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	app, err := bootstrap.NewApplication()
 	if err != nil {
 		log.Fatal(err)
@@ -36,12 +50,34 @@ func main() {
 		}
 	}()
 
+	// Envoy ext_authz gRPC server, when enabled
+	if app.ExtAuthz != nil {
+		grpcAddr := app.Config.Server.ExtAuthzAddr
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatal("ext_authz listen failed: " + err.Error())
+		}
+		go func() {
+			app.Logger.Info("ext_authz gRPC server starting on " + grpcAddr)
+			if err := app.ExtAuthz.Serve(lis); err != nil {
+				log.Fatal("ext_authz server failed: " + err.Error())
+			}
+		}()
+	}
+
+	// Background jobs (signing key reload and scheduled rotation, fraud
+	// rule reload)
+	background, stopBackground := context.WithCancel(context.Background())
+	go app.KeyService.Run(background)
+	go app.Rules.Run(background)
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	app.Logger.Info("Received shutdown signal")
+	stopBackground()
 
 	// Give outstanding requests 30 seconds to complete
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -51,6 +87,136 @@ func main() {
 		app.Logger.Error("Server forced to shutdown: " + err.Error())
 	}
 
+	if app.ExtAuthz != nil {
+		stopGRPC(ctx, app.ExtAuthz)
+	}
+
 	// Cleanup application resources (DB, etc.)
 	app.Shutdown()
 }
+
+// stopGRPC drains in-flight Check calls, forcing the stop if ctx expires first.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// runCommand executes a one-off administrative subcommand instead of the server.
+func runCommand(name string, args []string) {
+	switch name {
+	case "rotate-keys":
+		rotateKeys(args)
+	case "import-users":
+		importUsers(args)
+	case "check-rules":
+		checkRules(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\nCommands:\n"+
+			"  rotate-keys [-compromised]              rotate the token signing key now\n"+
+			"  import-users [-format jsonl|csv] FILE   create accounts from a legacy user export\n"+
+			"  check-rules FILE                        validate a fraud rules file\n", name)
+		os.Exit(2)
+	}
+}
+
+func rotateKeys(args []string) {
+	fs := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	compromised := fs.Bool("compromised", false, "stop accepting tokens signed by the previous key immediately")
+	fs.Parse(args)
+
+	app, err := bootstrap.NewApplication()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer app.Shutdown()
+
+	key, err := app.KeyService.Rotate(*compromised)
+	if err != nil {
+		app.Logger.Error("Key rotation failed: " + err.Error())
+		app.Shutdown()
+		os.Exit(1)
+	}
+
+	fmt.Println("Activated signing key " + key.KID)
+}
+
+func importUsers(args []string) {
+	fs := flag.NewFlagSet("import-users", flag.ExitOnError)
+	format := fs.String("format", "", "input format, jsonl or csv (default: from the file extension)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: import-users [-format jsonl|csv] FILE")
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = service.ImportFormatJSONL
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			*format = service.ImportFormatCSV
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := service.DecodeUserImport(f, *format)
+	if err != nil {
+		log.Fatal(path + ": " + err.Error())
+	}
+
+	app, err := bootstrap.NewApplication()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer app.Shutdown()
+
+	result, err := app.UserImport.Import(records, "")
+	if err != nil {
+		app.Logger.Error("User import failed: " + err.Error())
+		app.Shutdown()
+		os.Exit(1)
+	}
+
+	for _, e := range result.Errors {
+		fmt.Printf("%s:%d: %s: %s\n", path, e.Line, e.Email, e.Error)
+	}
+	fmt.Printf("%d created, %d skipped (already exist), %d failed\n", result.Created, result.Skipped, result.Failed)
+}
+
+// checkRules compiles a fraud rules file without starting the service, so
+// rules can be checked before they are deployed.
+func checkRules(args []string) {
+	fs := flag.NewFlagSet("check-rules", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: check-rules FILE")
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+
+	defs, err := rules.NewFileSource(path).List()
+	if err != nil {
+		log.Fatal(err)
+	}
+	set, err := rules.Compile(defs, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, path+":\n"+err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Printf("%d rules OK\n", set.Len())
+}
@@ -1,21 +1,44 @@
 package bootstrap
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+
 	"github.com/abhay786-20/fraud-auth-service/internal/config"
 	"github.com/abhay786-20/fraud-auth-service/internal/db"
+	"github.com/abhay786-20/fraud-auth-service/internal/extauthz"
+	"github.com/abhay786-20/fraud-auth-service/internal/geoip"
 	"github.com/abhay786-20/fraud-auth-service/internal/handler"
+	"github.com/abhay786-20/fraud-auth-service/internal/ratelimit"
 	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
 	"github.com/abhay786-20/fraud-auth-service/internal/router"
+	"github.com/abhay786-20/fraud-auth-service/internal/rules"
 	"github.com/abhay786-20/fraud-auth-service/internal/service"
+	"github.com/abhay786-20/fraud-auth-service/internal/stuffing"
 	"github.com/abhay786-20/fraud-auth-service/pkg/env"
 	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/mailer"
+	"github.com/abhay786-20/fraud-auth-service/pkg/password"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
 )
 
 type Application struct {
-	Config *config.Config
-	Logger *logger.Logger
-	DB     *db.Postgres
-	Router *router.Router
+	Config     *config.Config
+	Logger     *logger.Logger
+	DB         *db.Postgres
+	Router     *router.Router
+	KeyService *service.KeyService
+	UserImport *service.UserImportService
+	Rules      *service.RulesService
+	ExtAuthz   *grpc.Server       // nil unless AUTH_GRPC_EXT_AUTHZ_ADDR is set
+	Redis      *redis.Client      // nil unless a feature is configured to use Redis
+	GeoIP      *geoip.MMDBLocator // nil unless AUTH_GEOIP_DB is set
 }
 
 func NewApplication() (*Application, error) {
@@ -44,21 +67,382 @@ func NewApplication() (*Application, error) {
 	// Repository - User
 	userRepo := repository.NewPostgresUserRepository(pg.DB, log)
 
+	// Repository - Refresh Tokens
+	refreshRepo := repository.NewPostgresRefreshTokenRepository(pg.DB, log)
+
+	// Revocation Store - Access Tokens
+	var revocations repository.RevocationStore
+	switch cfg.Auth.RevocationStore {
+	case "memory":
+		revocations = repository.NewMemoryRevocationStore()
+		log.Info("Using in-memory token revocation store")
+	case "postgres":
+		revocations = repository.NewPostgresRevocationStore(pg.DB, log)
+	default:
+		return nil, fmt.Errorf("unknown revocation store %q", cfg.Auth.RevocationStore)
+	}
+
+	// Signing Keys - a static key from config, or a managed rotating key ring
+	var keysKey []byte
+	if cfg.Auth.JWTKeysKey != "" {
+		keysKey, err = utils.ParseEncryptionKey(cfg.Auth.JWTKeysKey)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_JWT_KEYS_ENCRYPTION_KEY: %w", err)
+		}
+	}
+	keys := utils.NewKeyRing(nil)
+	keyService := service.NewKeyService(
+		repository.NewPostgresSigningKeyRepository(pg.DB, log),
+		keys,
+		log,
+		service.KeyServiceConfig{
+			Algorithm:      cfg.Auth.JWTAlgorithm,
+			EncryptionKey:  keysKey,
+			KeysDir:        cfg.Auth.JWTKeysDir,
+			RotationPeriod: cfg.Auth.KeyRotation,
+			Overlap:        cfg.Auth.KeyOverlap,
+		},
+	)
+	if keyService.Managed() {
+		if cfg.Auth.JWTAlgorithm == utils.AlgHS256 {
+			return nil, errors.New("managed key rotation requires RS256, ES256 or EdDSA")
+		}
+		if keysKey == nil {
+			log.Warn("AUTH_JWT_KEYS_ENCRYPTION_KEY is not set; every replica must share AUTH_JWT_KEYS_DIR")
+		}
+		if cfg.Auth.KeyOverlap < cfg.Auth.AccessTokenTTL {
+			log.Warn("AUTH_JWT_KEY_OVERLAP_MIN is shorter than the access token TTL; rotation will invalidate live tokens")
+		}
+		if err := keyService.Load(); err != nil {
+			log.Error("Failed to load managed signing keys: " + err.Error())
+			return nil, err
+		}
+	} else {
+		signingKey, err := loadSigningKey(cfg.Auth)
+		if err != nil {
+			log.Error("Failed to load signing key: " + err.Error())
+			return nil, err
+		}
+		keys.Replace(signingKey, nil)
+	}
+	if active, err := keys.SigningKey(); err == nil {
+		log.Info("Signing tokens with " + active.Method.Alg() + " (kid " + active.ID + ")")
+	}
+
+	switch cfg.Email.UnverifiedLogin {
+	case service.UnverifiedLoginAllow, service.UnverifiedLoginRestrict, service.UnverifiedLoginDeny:
+	default:
+		return nil, fmt.Errorf("unknown AUTH_UNVERIFIED_LOGIN policy %q", cfg.Email.UnverifiedLogin)
+	}
+
+	auditRepo := repository.NewPostgresAuditRepository(pg.DB, log)
+
+	// Service - Lockout (failed login backoff and account locking)
+	lockoutService := service.NewLockoutService(
+		repository.NewPostgresLoginFailureRepository(pg.DB, log),
+		userRepo,
+		auditRepo,
+		log,
+		service.LockoutConfig{
+			Threshold: cfg.Auth.LockoutThreshold,
+			Duration:  cfg.Auth.LockoutDuration,
+		},
+	)
+
+	// Password Hashing - new hashes use the configured algorithm; older ones
+	// are upgraded at the next successful login
+	hasher, err := newPasswordHasher(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Hashing new passwords with " + hasher.Algorithm())
+
+	// Password Policy - applied on signup, reset and change
+	policy, err := newPasswordPolicy(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Breached != nil {
+		log.Info(fmt.Sprintf("Checking passwords against %s (%d MB of breached password hashes)",
+			cfg.Password.BreachedFile, policy.Breached.Size()>>20))
+	}
+
+	// Rate Limiting - per IP, per submitted email and per user
+	if _, err := ratelimit.ParseAlgorithm(cfg.RateLimit.Algorithm); err != nil {
+		return nil, err
+	}
+	var rdb *redis.Client
+	var limiter ratelimit.Limiter
+	switch cfg.RateLimit.Backend {
+	case "memory":
+		limiter = ratelimit.NewMemoryLimiter()
+		log.Info("Using in-memory rate limiter")
+	case "redis":
+		rdb, err = newRedis(cfg.Redis)
+		if err != nil {
+			log.Error("Redis connection failed: " + err.Error())
+			return nil, err
+		}
+		limiter = ratelimit.NewRedisLimiter(rdb)
+		log.Info("Using Redis rate limiter at " + cfg.Redis.Addr)
+	case "off":
+		log.Warn("Rate limiting is disabled")
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.RateLimit.Backend)
+	}
+
+	// Credential Stuffing Detection - failed logins across accounts, counted
+	// in the rate limiter's backend (in memory when rate limiting is off),
+	// which also enforces the tighter limits during an attack
+	var detector *stuffing.Detector
+	var attackLimiter ratelimit.Limiter
+	if cfg.Stuffing.Enabled {
+		var (
+			counters ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+			attacks  stuffing.Store    = stuffing.NewMemoryStore()
+		)
+		if limiter != nil {
+			counters = limiter
+		}
+		attackLimiter = counters
+		if rdb != nil {
+			attacks = stuffing.NewRedisStore(rdb)
+		}
+		var passwordKey []byte
+		if cfg.Stuffing.PasswordKey != "" {
+			passwordKey, err = utils.ParseEncryptionKey(cfg.Stuffing.PasswordKey)
+			if err != nil {
+				return nil, fmt.Errorf("AUTH_STUFFING_PASSWORD_KEY: %w", err)
+			}
+		} else if rdb != nil {
+			log.Warn("AUTH_STUFFING_PASSWORD_KEY is not set; each replica counts sprayed passwords on its own")
+		}
+		detector = stuffing.NewDetector(counters, attacks, stuffing.Config{
+			Window:            cfg.Stuffing.Window,
+			IPThreshold:       cfg.Stuffing.IPThreshold,
+			NetworkThreshold:  cfg.Stuffing.NetworkThreshold,
+			PasswordThreshold: cfg.Stuffing.PasswordThreshold,
+			AttackTTL:         cfg.Stuffing.AttackTTL,
+			PasswordKey:       passwordKey,
+		})
+	}
+	stuffingService := service.NewStuffingService(detector, auditRepo, log)
+
+	// Mailer - verification links and other transactional email
+	mail, err := newMailer(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	// Risk Engine - scores password logins against the user's login history
+	switch cfg.Risk.ChallengeFallback {
+	case service.RiskFallbackAllow, service.RiskFallbackDeny:
+	default:
+		return nil, fmt.Errorf("unknown AUTH_RISK_CHALLENGE_FALLBACK %q", cfg.Risk.ChallengeFallback)
+	}
+	riskEngine, err := risk.NewEngine(cfg.Risk.ChallengeScore, cfg.Risk.DenyScore, risk.DefaultSignals()...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fraud Rules - analyst-maintained CEL rules on logins and signups
+	var ruleSource rules.Source
+	switch cfg.Rules.Source {
+	case "":
+	case "file":
+		ruleSource = rules.NewFileSource(cfg.Rules.File)
+		log.Info("Loading fraud rules from " + cfg.Rules.File)
+	case "postgres":
+		ruleSource = repository.NewPostgresFraudRuleRepository(pg.DB, log)
+		log.Info("Loading fraud rules from the fraud_rules table")
+	default:
+		return nil, fmt.Errorf("unknown AUTH_RULES_SOURCE %q", cfg.Rules.Source)
+	}
+	rulesService := service.NewRulesService(ruleSource, log, service.RulesConfig{
+		Shadow:         cfg.Rules.Shadow,
+		ReloadInterval: cfg.Rules.ReloadInterval,
+	})
+	// A broken rule must not keep the service from starting; the reload
+	// job keeps trying until it is fixed
+	if err := rulesService.Load(); err != nil {
+		log.Error("Failed to load fraud rules, running without them: " + err.Error())
+	}
+
+	// GeoIP - locates logins for impossible-travel detection
+	var (
+		geoDB   *geoip.MMDBLocator
+		locator geoip.Locator
+	)
+	if cfg.Risk.GeoIPDB != "" {
+		geoDB, err = geoip.OpenMMDB(cfg.Risk.GeoIPDB)
+		if err != nil {
+			log.Error("Failed to open GeoIP database: " + err.Error())
+			return nil, err
+		}
+		locator = geoDB
+		log.Info("Locating logins with " + geoDB.DatabaseType() + " from " + cfg.Risk.GeoIPDB)
+	}
+
+	// Service - Devices (known and trusted devices per user)
+	deviceService := service.NewDeviceService(
+		repository.NewPostgresDeviceRepository(pg.DB, log),
+		mail,
+		log,
+		service.DeviceConfig{
+			TrustTTL:  cfg.Device.TrustTTL,
+			NotifyNew: cfg.Device.NotifyNew,
+		},
+	)
+
+	riskService := service.NewRiskService(
+		riskEngine,
+		rulesService,
+		repository.NewPostgresLoginHistoryRepository(pg.DB, log),
+		deviceService,
+		locator,
+		auditRepo,
+		log,
+		service.RiskConfig{
+			Enabled:           cfg.Risk.Enabled,
+			ChallengeFallback: cfg.Risk.ChallengeFallback,
+		},
+	)
+
 	// Service - Auth
-	authService := service.NewAuthService(userRepo, cfg.Auth.JWTSecret, cfg.Auth.TokenTTL)
+	authService := service.NewAuthService(
+		userRepo,
+		refreshRepo,
+		revocations,
+		lockoutService,
+		riskService,
+		stuffingService,
+		policy,
+		hasher,
+		log,
+		keys,
+		service.TokenConfig{
+			Issuer:          cfg.Auth.Issuer,
+			Audience:        cfg.Auth.Audience,
+			AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
+			RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+			UnverifiedLogin: cfg.Email.UnverifiedLogin,
+		},
+	)
+
+	// Service - Email Verification
+	verificationService := service.NewEmailVerificationService(
+		repository.NewPostgresEmailVerificationRepository(pg.DB, log),
+		userRepo,
+		mail,
+		keys,
+		log,
+		service.EmailVerificationConfig{
+			Issuer:         cfg.Auth.Issuer,
+			LinkURL:        cfg.Email.VerificationURL,
+			TokenTTL:       cfg.Email.VerificationTTL,
+			ResendInterval: cfg.Email.ResendInterval,
+			MaxPerDay:      cfg.Email.MaxEmailsPerDay,
+		},
+	)
+
+	// Service - Password recovery
+	passwordService := service.NewPasswordService(
+		repository.NewPostgresPasswordResetRepository(pg.DB, log),
+		userRepo,
+		authService,
+		mail,
+		log,
+		service.PasswordConfig{
+			ResetURL:      cfg.Email.PasswordResetURL,
+			ResetTokenTTL: cfg.Email.PasswordResetTTL,
+		},
+	)
+
+	// Service - MFA (TOTP secrets are encrypted with AUTH_MFA_ENCRYPTION_KEY)
+	var mfaKey []byte
+	if cfg.MFA.EncryptionKey != "" {
+		mfaKey, err = utils.ParseEncryptionKey(cfg.MFA.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_MFA_ENCRYPTION_KEY: %w", err)
+		}
+	} else {
+		log.Warn("AUTH_MFA_ENCRYPTION_KEY is not set; TOTP enrollment is disabled")
+	}
+	// Service - WebAuthn (security keys and passkeys)
+	webauthnService, err := service.NewWebAuthnService(
+		repository.NewPostgresWebAuthnRepository(pg.DB, log),
+		authService,
+		log,
+		service.WebAuthnConfig{
+			RPID:    cfg.WebAuthn.RPID,
+			RPName:  cfg.WebAuthn.RPName,
+			Origins: cfg.WebAuthn.Origins,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("webauthn configuration: %w", err)
+	}
+
+	mfaService := service.NewMFAService(
+		repository.NewPostgresMFARepository(pg.DB, log),
+		webauthnService,
+		authService,
+		lockoutService,
+		log,
+		service.MFAConfig{
+			EncryptionKey: mfaKey,
+			Issuer:        cfg.MFA.Issuer,
+			ChallengeTTL:  cfg.MFA.ChallengeTTL,
+		},
+	)
+
+	// Service - OAuth
+	oauthService := service.NewOAuthService(
+		repository.NewPostgresOAuthClientRepository(pg.DB, log),
+		repository.NewPostgresAuthorizationCodeRepository(pg.DB, log),
+		authService,
+		mfaService,
+		deviceService,
+		log,
+		cfg.Auth.Issuer,
+		cfg.Auth.AuthorizationCodeTTL,
+	)
+
+	// Service - User Import (accounts from legacy identity stores)
+	userImportService := service.NewUserImportService(userRepo, auditRepo, log)
 
 	// Handlers
-	authHandler := handler.NewAuthHandler(authService, log)
+	authHandler := handler.NewAuthHandler(authService, mfaService, deviceService, verificationService, log)
 	healthHandler := handler.NewHealthHandler(pg)
+	jwksHandler := handler.NewJWKSHandler(keys)
+	adminHandler := handler.NewAdminHandler(keyService, oauthService, lockoutService, userImportService, log)
+	oidcHandler := handler.NewOIDCHandler(authService, keys, cfg.Auth.Issuer, log)
+	oauthHandler := handler.NewOAuthHandler(oauthService, log)
+	mfaHandler := handler.NewMFAHandler(mfaService, authService, deviceService, log)
+	webauthnHandler := handler.NewWebAuthnHandler(webauthnService, authService, log)
+	passwordHandler := handler.NewPasswordHandler(passwordService, log)
+	deviceHandler := handler.NewDeviceHandler(deviceService, log)
 
 	// 5️⃣ Router
-	r := router.NewRouter(log, cfg, authHandler, healthHandler)
+	r := router.NewRouter(log, cfg, limiter, attackLimiter, stuffingService, authHandler, healthHandler, jwksHandler, adminHandler, oidcHandler, oauthHandler, mfaHandler, webauthnHandler, passwordHandler, deviceHandler)
+
+	// 6️⃣ Envoy ext_authz (optional)
+	var extAuthz *grpc.Server
+	if cfg.Server.ExtAuthzAddr != "" {
+		extAuthz = extauthz.NewGRPCServer(authService, log)
+	}
 
 	return &Application{
-		Config: cfg,
-		Logger: log,
-		DB:     pg,
-		Router: r,
+		Config:     cfg,
+		Logger:     log,
+		DB:         pg,
+		Router:     r,
+		KeyService: keyService,
+		UserImport: userImportService,
+		Rules:      rulesService,
+		ExtAuthz:   extAuthz,
+		Redis:      rdb,
+		GeoIP:      geoDB,
 	}, nil
 }
 
@@ -71,5 +455,134 @@ func (a *Application) Shutdown() {
 		a.Logger.Info("Database connection closed")
 	}
 
+	if a.Redis != nil {
+		if err := a.Redis.Close(); err != nil {
+			a.Logger.Error("Error closing Redis: " + err.Error())
+		} else {
+			a.Logger.Info("Redis connection closed")
+		}
+	}
+
+	if a.GeoIP != nil {
+		if err := a.GeoIP.Close(); err != nil {
+			a.Logger.Error("Error closing GeoIP database: " + err.Error())
+		}
+	}
+
 	a.Logger.Info("Application shutdown complete")
 }
+
+// loadSigningKey builds the token signing key from configuration:
+// a shared secret for HS256, or a PEM private key file for asymmetric algorithms.
+func loadSigningKey(cfg config.AuthConfig) (*utils.SigningKey, error) {
+	if cfg.JWTAlgorithm == utils.AlgHS256 {
+		if cfg.JWTSecret == "" {
+			return nil, errors.New("AUTH_JWT_SECRET is required for HS256")
+		}
+		kid := cfg.JWTKeyID
+		if kid == "" {
+			kid = "default"
+		}
+		return utils.NewHMACKey(kid, []byte(cfg.JWTSecret)), nil
+	}
+
+	if cfg.JWTKeyPath == "" {
+		return nil, fmt.Errorf("AUTH_JWT_PRIVATE_KEY_PATH is required for %s", cfg.JWTAlgorithm)
+	}
+	return utils.LoadPrivateKeyFile(cfg.JWTKeyID, cfg.JWTAlgorithm, cfg.JWTKeyPath)
+}
+
+// newPasswordHasher builds the hasher selected by AUTH_PASSWORD_HASH.
+func newPasswordHasher(cfg config.PasswordConfig) (password.Hasher, error) {
+	switch cfg.Hash {
+	case password.AlgorithmArgon2id:
+		if cfg.Argon2Memory < 1 || cfg.Argon2Time < 1 || cfg.Argon2Threads < 1 || cfg.Argon2Threads > 255 {
+			return nil, errors.New("AUTH_ARGON2_MEMORY_KIB, AUTH_ARGON2_TIME and AUTH_ARGON2_THREADS must be positive, threads at most 255")
+		}
+		return password.NewArgon2Hasher(password.Argon2Params{
+			Memory:  uint32(cfg.Argon2Memory),
+			Time:    uint32(cfg.Argon2Time),
+			Threads: uint8(cfg.Argon2Threads),
+		})
+	case password.AlgorithmBcrypt:
+		return password.NewBcryptHasher(cfg.BcryptCost)
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", cfg.Hash)
+	}
+}
+
+// newPasswordPolicy builds the password policy and loads the breached
+// password corpus, if one is configured.
+func newPasswordPolicy(cfg config.PasswordConfig) (*password.Policy, error) {
+	if cfg.MaxLength < 1 {
+		return nil, errors.New("AUTH_PASSWORD_MAX_LENGTH must be at least 1")
+	}
+	if cfg.Hash == password.AlgorithmBcrypt && cfg.MaxLength > password.BcryptMaxBytes {
+		return nil, fmt.Errorf("AUTH_PASSWORD_MAX_LENGTH cannot exceed %d bytes with bcrypt", password.BcryptMaxBytes)
+	}
+	if cfg.MinLength > cfg.MaxLength {
+		return nil, errors.New("AUTH_PASSWORD_MIN_LENGTH exceeds AUTH_PASSWORD_MAX_LENGTH")
+	}
+	if cfg.MinClasses < 0 || cfg.MinClasses > 4 {
+		return nil, errors.New("AUTH_PASSWORD_MIN_CLASSES must be between 0 and 4")
+	}
+	if cfg.MinStrength < 0 || cfg.MinStrength > 4 {
+		return nil, errors.New("AUTH_PASSWORD_MIN_STRENGTH must be between 0 and 4")
+	}
+
+	policy := &password.Policy{
+		MinLength:   cfg.MinLength,
+		MaxLength:   cfg.MaxLength,
+		MinClasses:  cfg.MinClasses,
+		MinStrength: cfg.MinStrength,
+	}
+	if cfg.BreachedFile != "" {
+		corpus, err := password.OpenCorpus(cfg.BreachedFile)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_PASSWORD_BREACHED_FILE: %w", err)
+		}
+		policy.Breached = corpus
+	}
+	return policy, nil
+}
+
+// newMailer builds the mail transport selected by AUTH_MAIL_DRIVER.
+func newMailer(cfg config.MailConfig) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, errors.New("AUTH_SMTP_HOST is required for the smtp mail driver")
+		}
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.File, cfg.From)
+	case "stdout":
+		return mailer.NewStdoutMailer(cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// newRedis connects to the Redis-compatible server in cfg and checks that it
+// answers, so a wrong address fails at startup rather than on first use.
+func newRedis(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
@@ -1,22 +1,39 @@
 package config
 
 import (
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/abhay786-20/fraud-auth-service/internal/ratelimit"
 	"github.com/abhay786-20/fraud-auth-service/pkg/constants"
 	"github.com/abhay786-20/fraud-auth-service/pkg/env"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	Password  PasswordConfig
+	MFA       MFAConfig
+	WebAuthn  WebAuthnConfig
+	Email     EmailConfig
+	Mail      MailConfig
+	Redis     RedisConfig
+	RateLimit RateLimitConfig
+	Risk      RiskConfig
+	Device    DeviceConfig
+	Stuffing  StuffingConfig
+	Rules     RulesConfig
 }
 
 type ServerConfig struct {
 	Host    string
 	Port    string
 	GinMode string
+
+	ExtAuthzAddr   string
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -31,16 +48,130 @@ type DatabaseConfig struct {
 }
 
 type AuthConfig struct {
-	JWTSecret string
-	TokenTTL  time.Duration
+	JWTSecret       string
+	JWTAlgorithm    string
+	JWTKeyPath      string
+	JWTKeyID        string
+	JWTKeysDir      string
+	JWTKeysKey      string
+	KeyRotation     time.Duration
+	KeyOverlap      time.Duration
+	Issuer          string
+	Audience        string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	RevocationStore string
+
+	AuthorizationCodeTTL time.Duration
+
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+}
+
+type PasswordConfig struct {
+	MinLength    int
+	MaxLength    int
+	MinClasses   int
+	MinStrength  int
+	BreachedFile string
+
+	Hash          string
+	BcryptCost    int
+	Argon2Memory  int // KiB
+	Argon2Time    int
+	Argon2Threads int
+}
+
+type MFAConfig struct {
+	EncryptionKey string
+	Issuer        string
+	ChallengeTTL  time.Duration
+}
+
+type WebAuthnConfig struct {
+	RPID    string
+	RPName  string
+	Origins []string
+}
+
+type EmailConfig struct {
+	UnverifiedLogin string
+	VerificationURL string
+	VerificationTTL time.Duration
+	ResendInterval  time.Duration
+	MaxEmailsPerDay int
+
+	PasswordResetURL string
+	PasswordResetTTL time.Duration
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+type RateLimitConfig struct {
+	Backend     string
+	Algorithm   string
+	IPPerMin    int
+	EmailPerMin int
+	UserPerMin  int
+}
+
+type RiskConfig struct {
+	Enabled           bool
+	ChallengeScore    int
+	DenyScore         int
+	ChallengeFallback string
+	GeoIPDB           string
+}
+
+type RulesConfig struct {
+	Source         string
+	File           string
+	ReloadInterval time.Duration
+	Shadow         bool
+}
+
+type StuffingConfig struct {
+	Enabled           bool
+	Window            time.Duration
+	IPThreshold       int
+	NetworkThreshold  int
+	PasswordThreshold int
+	AttackTTL         time.Duration
+	IPPerMin          int
+	EmailPerMin       int
+	PasswordKey       string
+}
+
+type DeviceConfig struct {
+	TrustTTL  time.Duration
+	NotifyNew bool
+}
+
+type MailConfig struct {
+	Driver       string
+	From         string
+	File         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
 }
 
 func LoadConfig(environment *env.Environment) *Config {
+	issuer := strings.TrimSuffix(environment.Get(constants.EnvIssuer, "http://localhost:8081"), "/")
+
 	return &Config{
 		Server: ServerConfig{
 			Host:    environment.Get(constants.EnvServerHost, "0.0.0.0"),
 			Port:    environment.Get(constants.EnvServerPort, "8081"),
 			GinMode: environment.Get(constants.EnvGinMode, "debug"),
+
+			ExtAuthzAddr:   environment.Get(constants.EnvGRPCExtAuthzAddr),
+			TrustedProxies: splitList(environment.Get(constants.EnvTrustedProxies)),
 		},
 		Database: DatabaseConfig{
 			Host:         environment.Get(constants.EnvDBHost, "localhost"),
@@ -53,8 +184,126 @@ func LoadConfig(environment *env.Environment) *Config {
 			MaxLifetime:  time.Duration(environment.GetInt(constants.EnvDBMaxLifetimeMin, 5)) * time.Minute,
 		},
 		Auth: AuthConfig{
-			JWTSecret: environment.Get(constants.EnvJWTSecret),
-			TokenTTL:  time.Duration(environment.GetInt(constants.EnvJWTTTLHours, 24)) * time.Hour,
+			JWTSecret:       environment.Get(constants.EnvJWTSecret),
+			JWTAlgorithm:    environment.Get(constants.EnvJWTAlgorithm, "HS256"),
+			JWTKeyPath:      environment.Get(constants.EnvJWTPrivateKeyPath),
+			JWTKeyID:        environment.Get(constants.EnvJWTKeyID),
+			JWTKeysDir:      environment.Get(constants.EnvJWTKeysDir),
+			JWTKeysKey:      environment.Get(constants.EnvJWTKeysEncryptionKey),
+			KeyRotation:     time.Duration(environment.GetInt(constants.EnvJWTKeyRotationDays, 30)) * 24 * time.Hour,
+			KeyOverlap:      time.Duration(environment.GetInt(constants.EnvJWTKeyOverlapMin, 60)) * time.Minute,
+			Issuer:          issuer,
+			Audience:        environment.Get(constants.EnvAudience, "fraud-platform"),
+			AccessTokenTTL:  time.Duration(environment.GetInt(constants.EnvJWTAccessTTLMin, 15)) * time.Minute,
+			RefreshTokenTTL: time.Duration(environment.GetInt(constants.EnvRefreshTokenTTLHours, 720)) * time.Hour,
+			RevocationStore: environment.Get(constants.EnvRevocationStore, "postgres"),
+
+			AuthorizationCodeTTL: time.Duration(environment.GetInt(constants.EnvOAuthCodeTTLSec, 60)) * time.Second,
+
+			LockoutThreshold: environment.GetInt(constants.EnvLockoutThreshold, 10),
+			LockoutDuration:  time.Duration(environment.GetInt(constants.EnvLockoutDurationMin, 15)) * time.Minute,
+		},
+		Password: PasswordConfig{
+			MinLength:    environment.GetInt(constants.EnvPasswordMinLength, 8),
+			MaxLength:    environment.GetInt(constants.EnvPasswordMaxLength, 72),
+			MinClasses:   environment.GetInt(constants.EnvPasswordMinClasses, 0),
+			MinStrength:  environment.GetInt(constants.EnvPasswordMinStrength, 2),
+			BreachedFile: environment.Get(constants.EnvPasswordBreachedFile),
+
+			Hash:          environment.Get(constants.EnvPasswordHash, "argon2id"),
+			BcryptCost:    environment.GetInt(constants.EnvBcryptCost, 10),
+			Argon2Memory:  environment.GetInt(constants.EnvArgon2MemoryKiB, 19*1024),
+			Argon2Time:    environment.GetInt(constants.EnvArgon2Time, 2),
+			Argon2Threads: environment.GetInt(constants.EnvArgon2Threads, 1),
+		},
+		MFA: MFAConfig{
+			EncryptionKey: environment.Get(constants.EnvMFAEncryptionKey),
+			Issuer:        environment.Get(constants.EnvMFAIssuer, "Fraud Auth Service"),
+			ChallengeTTL:  time.Duration(environment.GetInt(constants.EnvMFAChallengeTTLSec, 300)) * time.Second,
+		},
+		WebAuthn: WebAuthnConfig{
+			RPID:    environment.Get(constants.EnvWebAuthnRPID, hostname(issuer)),
+			RPName:  environment.Get(constants.EnvWebAuthnRPName, "Fraud Auth Service"),
+			Origins: splitList(environment.Get(constants.EnvWebAuthnOrigins, issuer)),
+		},
+		Email: EmailConfig{
+			UnverifiedLogin: environment.Get(constants.EnvUnverifiedLogin, "restrict"),
+			VerificationURL: environment.Get(constants.EnvEmailVerificationURL, issuer+"/verify-email"),
+			VerificationTTL: time.Duration(environment.GetInt(constants.EnvEmailVerificationTTLHours, 24)) * time.Hour,
+			ResendInterval:  time.Duration(environment.GetInt(constants.EnvEmailVerificationResendSec, 60)) * time.Second,
+			MaxEmailsPerDay: environment.GetInt(constants.EnvEmailVerificationMaxPerDay, 5),
+
+			PasswordResetURL: environment.Get(constants.EnvPasswordResetURL, issuer+"/reset-password"),
+			PasswordResetTTL: time.Duration(environment.GetInt(constants.EnvPasswordResetTTLMin, 30)) * time.Minute,
+		},
+		Mail: MailConfig{
+			Driver:       environment.Get(constants.EnvMailDriver, "stdout"),
+			From:         environment.Get(constants.EnvMailFrom, "Fraud Auth Service <no-reply@localhost>"),
+			File:         environment.Get(constants.EnvMailFile, "./mail.log"),
+			SMTPHost:     environment.Get(constants.EnvSMTPHost),
+			SMTPPort:     environment.GetInt(constants.EnvSMTPPort, 587),
+			SMTPUsername: environment.Get(constants.EnvSMTPUsername),
+			SMTPPassword: environment.Get(constants.EnvSMTPPassword),
 		},
+		Redis: RedisConfig{
+			Addr:     environment.Get(constants.EnvRedisAddr, "localhost:6379"),
+			Password: environment.Get(constants.EnvRedisPassword),
+			DB:       environment.GetInt(constants.EnvRedisDB, 0),
+		},
+		RateLimit: RateLimitConfig{
+			Backend:     environment.Get(constants.EnvRateLimitBackend, "memory"),
+			Algorithm:   environment.Get(constants.EnvRateLimitAlgorithm, string(ratelimit.SlidingWindow)),
+			IPPerMin:    environment.GetInt(constants.EnvRateLimitIPPerMin, 30),
+			EmailPerMin: environment.GetInt(constants.EnvRateLimitEmailPerMin, 10),
+			UserPerMin:  environment.GetInt(constants.EnvRateLimitUserPerMin, 120),
+		},
+		Risk: RiskConfig{
+			Enabled:           environment.GetBool(constants.EnvRiskEnabled, true),
+			ChallengeScore:    environment.GetInt(constants.EnvRiskChallengeScore, 50),
+			DenyScore:         environment.GetInt(constants.EnvRiskDenyScore, 90),
+			ChallengeFallback: environment.Get(constants.EnvRiskChallengeFallback, "allow"),
+			GeoIPDB:           environment.Get(constants.EnvGeoIPDB),
+		},
+		Rules: RulesConfig{
+			Source:         environment.Get(constants.EnvRulesSource),
+			File:           environment.Get(constants.EnvRulesFile, "./rules.yaml"),
+			ReloadInterval: time.Duration(environment.GetInt(constants.EnvRulesReloadSec, 10)) * time.Second,
+			Shadow:         environment.GetBool(constants.EnvRulesShadow, false),
+		},
+		Stuffing: StuffingConfig{
+			Enabled:           environment.GetBool(constants.EnvStuffingEnabled, true),
+			Window:            time.Duration(environment.GetInt(constants.EnvStuffingWindowMin, 10)) * time.Minute,
+			IPThreshold:       environment.GetInt(constants.EnvStuffingIPThreshold, 20),
+			NetworkThreshold:  environment.GetInt(constants.EnvStuffingNetworkThreshold, 50),
+			PasswordThreshold: environment.GetInt(constants.EnvStuffingPasswordThreshold, 25),
+			AttackTTL:         time.Duration(environment.GetInt(constants.EnvStuffingAttackMin, 60)) * time.Minute,
+			IPPerMin:          environment.GetInt(constants.EnvStuffingIPPerMin, 5),
+			EmailPerMin:       environment.GetInt(constants.EnvStuffingEmailPerMin, 3),
+			PasswordKey:       environment.Get(constants.EnvStuffingPasswordKey),
+		},
+		Device: DeviceConfig{
+			TrustTTL:  time.Duration(environment.GetInt(constants.EnvDeviceTrustDays, 30)) * 24 * time.Hour,
+			NotifyNew: environment.GetBool(constants.EnvNewDeviceEmail, true),
+		},
+	}
+}
+
+// hostname returns the host of a URL without the port, or "" if it does not parse.
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
@@ -0,0 +1,118 @@
+// Package device recognises the browsers and apps users log in from. A
+// client may send a fingerprint of its own (a random ID kept in storage, or
+// the output of a fingerprinting library); otherwise the device is known by
+// features derived from its request headers, which is coarser: two laptops
+// running the same browser and language look like one device.
+package device
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// MaxFingerprintLength bounds client fingerprints; longer ones are ignored.
+const MaxFingerprintLength = 512
+
+// Features are what the request headers reveal about a device. Versions
+// are left out so that browser updates do not make a device new.
+type Features struct {
+	Browser  string // e.g. "Firefox"; "" if not recognised
+	OS       string // e.g. "Windows"; "" if not recognised
+	Language string // Primary Accept-Language tag, lower case, e.g. "en-gb"
+}
+
+// Parse derives features from the User-Agent and Accept-Language headers.
+func Parse(userAgent, acceptLanguage string) Features {
+	return Features{
+		Browser:  browserOf(userAgent),
+		OS:       osOf(userAgent),
+		Language: languageOf(acceptLanguage),
+	}
+}
+
+// Name describes the device for people, e.g. "Firefox on Windows".
+func (f Features) Name() string {
+	switch {
+	case f.Browser != "" && f.OS != "":
+		return f.Browser + " on " + f.OS
+	case f.Browser != "":
+		return f.Browser
+	case f.OS != "":
+		return "Unknown browser on " + f.OS
+	default:
+		return "Unknown device"
+	}
+}
+
+// ID identifies a device: the client's fingerprint when it sent one, the
+// derived features otherwise. It is a hex SHA-256, so fingerprints are
+// never stored as sent.
+func ID(fingerprint string, f Features) string {
+	fingerprint = strings.TrimSpace(fingerprint)
+
+	var key string
+	if fingerprint != "" && len(fingerprint) <= MaxFingerprintLength {
+		key = "fp:" + fingerprint
+	} else {
+		key = "ua:" + f.Browser + "|" + f.OS + "|" + f.Language
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// browsers are matched in order: most browsers also claim to be Safari or
+// Chrome, so the more specific tokens come first.
+var browsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"okhttp/", "OkHttp"},
+}
+
+var systems = []struct{ token, name string }{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+}
+
+func browserOf(userAgent string) string {
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			return b.name
+		}
+	}
+	return ""
+}
+
+func osOf(userAgent string) string {
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			return s.name
+		}
+	}
+	return ""
+}
+
+func languageOf(acceptLanguage string) string {
+	first, _, _ := strings.Cut(acceptLanguage, ",")
+	tag, _, _ := strings.Cut(first, ";")
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
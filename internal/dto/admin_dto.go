@@ -0,0 +1,30 @@
+package dto
+
+import "github.com/abhay786-20/fraud-auth-service/internal/models"
+
+// ============== REQUESTS ==============
+
+type RotateKeysRequest struct {
+	// Compromised drops the previous key immediately instead of letting it
+	// verify existing tokens until they expire
+	Compromised bool `json:"compromised"`
+}
+
+// ============== RESPONSES ==============
+
+type SigningKeysResponse struct {
+	Keys []models.SigningKey `json:"keys"`
+}
+
+type ImportUsersResponse struct {
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"` // Email already has an account
+	Failed  int               `json:"failed"`
+	Errors  []ImportUserError `json:"errors"`
+}
+
+type ImportUserError struct {
+	Line  int    `json:"line"`
+	Email string `json:"email"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+// ============== RESPONSES ==============
+
+type DeviceResponse struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"` // e.g. "Firefox on Windows"
+	LastIP       string     `json:"last_ip"`
+	Current      bool       `json:"current"` // The device making this request
+	TrustedUntil *time.Time `json:"trusted_until,omitempty"`
+	FirstSeenAt  time.Time  `json:"first_seen_at"`
+	LastSeenAt   time.Time  `json:"last_seen_at"`
+}
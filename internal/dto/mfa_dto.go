@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ============== REQUESTS ==============
+
+// MFAVerifyRequest completes a login that returned mfa_required, with either
+// a code or a passkey assertion started at /mfa/webauthn/begin.
+type MFAVerifyRequest struct {
+	MFAToken        string          `json:"mfa_token" binding:"required"`
+	Code            string          `json:"code"` // TOTP code or recovery code
+	WebAuthnSession string          `json:"webauthn_session"`
+	Credential      json.RawMessage `json:"credential"` // PublicKeyCredential from navigator.credentials.get()
+
+	TrustDevice       bool   `json:"trust_device"`       // Set a device_trust cookie that skips the second factor for a while
+	DeviceFingerprint string `json:"device_fingerprint"` // Optional; overrides the X-Device-Fingerprint header
+}
+
+type MFATokenRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// WebAuthnRegisterRequest finishes a registration ceremony.
+type WebAuthnRegisterRequest struct {
+	SessionToken string          `json:"session_token" binding:"required"`
+	Name         string          `json:"name" binding:"max=64"` // Label such as "YubiKey" or "MacBook"
+	Credential   json.RawMessage `json:"credential" binding:"required"`
+}
+
+// WebAuthnLoginRequest finishes an assertion ceremony.
+type WebAuthnLoginRequest struct {
+	SessionToken string          `json:"session_token" binding:"required"`
+	Credential   json.RawMessage `json:"credential" binding:"required"`
+}
+
+// MFACodeRequest carries a TOTP or recovery code to authorize an MFA change.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ============== RESPONSES ==============
+
+// MFAChallengeResponse is returned by login instead of tokens when the user
+// has a second factor enabled.
+type MFAChallengeResponse struct {
+	MFARequired bool     `json:"mfa_required"`
+	MFAToken    string   `json:"mfa_token"`
+	Methods     []string `json:"methods"`    // "totp", "webauthn"
+	ExpiresIn   int64    `json:"expires_in"` // mfa_token lifetime in seconds
+}
+
+// WebAuthnBeginResponse carries the options for navigator.credentials.create()
+// or .get() and the session token to send back with the result.
+type WebAuthnBeginResponse struct {
+	SessionToken string `json:"session_token"`
+	Options      any    `json:"options"`
+}
+
+type WebAuthnCredentialResponse struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Attachment      string     `json:"attachment,omitempty"` // "platform" or "cross-platform"
+	Synced          bool       `json:"synced"`               // Passkey backed up to a cloud account
+	CloneDetectedAt *time.Time `json:"clone_detected_at,omitempty"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAStatusResponse struct {
+	TOTPEnabled            bool `json:"totp_enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+	Passkeys               int  `json:"passkeys"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+// ============== REQUESTS ==============
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ============== RESPONSES ==============
+
+// PasswordRejectedResponse explains why a new password was refused.
+// Reasons carry stable codes such as "too_short" or "breached".
+type PasswordRejectedResponse struct {
+	Error   string              `json:"error"`
+	Reasons []PasswordViolation `json:"reasons"`
+}
+
+type PasswordViolation struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,148 @@
+// Package extauthz implements Envoy's external authorization gRPC API
+// (envoy.service.auth.v3.Authorization) on top of the service's token
+// validation, so Envoy sidecars can authenticate requests without each
+// upstream validating JWTs itself.
+package extauthz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+// identityHeaderNames are always stripped from the incoming request unless
+// set by this server, so clients cannot spoof an identity upstream.
+var identityHeaderNames = []string{"X-User-Id", "X-User-Email", "X-User-Role", "X-Client-Id", "X-Scope"}
+
+// Server answers Envoy ext_authz Check calls.
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+
+	validator middleware.TokenValidator
+	log       *logger.Logger
+}
+
+func NewServer(validator middleware.TokenValidator, log *logger.Logger) *Server {
+	return &Server{
+		validator: validator,
+		log:       log,
+	}
+}
+
+// NewGRPCServer returns a gRPC server exposing the authorization service and
+// the standard health service (for Envoy cluster health checks).
+func NewGRPCServer(validator middleware.TokenValidator, log *logger.Logger) *grpc.Server {
+	srv := grpc.NewServer()
+	authv3.RegisterAuthorizationServer(srv, NewServer(validator, log))
+
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus(authv3.Authorization_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthSrv)
+
+	return srv
+}
+
+// Check validates the request's Authorization header with the same path as
+// the HTTP middleware and either allows it with identity headers injected
+// or denies it with a 401 (a 403 for restricted tokens).
+func (s *Server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	// Envoy lower-cases header names
+	headers := req.GetAttributes().GetRequest().GetHttp().GetHeaders()
+
+	token, ok := middleware.ExtractBearerToken(headers["authorization"])
+	if !ok {
+		return deny("missing or malformed authorization header"), nil
+	}
+
+	claims, err := s.validator.ValidateToken(token)
+	if err != nil {
+		message := "invalid token"
+		switch {
+		case errors.Is(err, utils.ErrExpiredToken):
+			message = "token has expired"
+		case errors.Is(err, utils.ErrRevokedToken):
+			message = "token has been revoked"
+		}
+		return deny(message), nil
+	}
+
+	// Restricted tokens (unverified email) never reach upstream services
+	if claims.Restricted {
+		return forbid("email verification required"), nil
+	}
+
+	return allow(claims), nil
+}
+
+func allow(claims *utils.Claims) *authv3.CheckResponse {
+	identity := middleware.IdentityHeaders(claims)
+
+	var (
+		set    []*corev3.HeaderValueOption
+		remove []string
+	)
+	for _, name := range identityHeaderNames {
+		value, ok := identity[name]
+		if !ok {
+			remove = append(remove, name)
+			continue
+		}
+		set = append(set, &corev3.HeaderValueOption{
+			Header:       &corev3.HeaderValue{Key: name, Value: value},
+			AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{
+			OkResponse: &authv3.OkHttpResponse{
+				Headers:         set,
+				HeadersToRemove: remove,
+			},
+		},
+	}
+}
+
+func deny(message string) *authv3.CheckResponse {
+	return denied(codes.Unauthenticated, typev3.StatusCode_Unauthorized, message,
+		&corev3.HeaderValueOption{Header: &corev3.HeaderValue{Key: "WWW-Authenticate", Value: `Bearer error="invalid_token"`}},
+	)
+}
+
+func forbid(message string) *authv3.CheckResponse {
+	return denied(codes.PermissionDenied, typev3.StatusCode_Forbidden, message)
+}
+
+func denied(code codes.Code, status typev3.StatusCode, message string, headers ...*corev3.HeaderValueOption) *authv3.CheckResponse {
+	body, _ := json.Marshal(dto.ErrorResponse{Error: message})
+
+	headers = append(headers, &corev3.HeaderValueOption{
+		Header: &corev3.HeaderValue{Key: "Content-Type", Value: "application/json"},
+	})
+
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(code), Message: message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: status},
+				Headers: headers,
+				Body:    string(body),
+			},
+		},
+	}
+}
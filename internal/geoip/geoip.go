@@ -0,0 +1,119 @@
+// Package geoip locates IP addresses using an offline database, so logins
+// can be placed on a map without calling an external service.
+package geoip
+
+import (
+	"math"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// earthRadiusKm is the mean radius of the Earth.
+const earthRadiusKm = 6371.0
+
+// Location is where an IP address is registered or was last seen.
+type Location struct {
+	Country   string  // ISO 3166-1 alpha-2 code, e.g. "DE"
+	City      string  // English name, if the database has one
+	Latitude  float64 // Degrees
+	Longitude float64 // Degrees
+	RadiusKm  int     // Accuracy radius around the coordinates
+}
+
+// String describes the location for logs, e.g. "Berlin, DE".
+func (l *Location) String() string {
+	switch {
+	case l.City != "" && l.Country != "":
+		return l.City + ", " + l.Country
+	case l.Country != "":
+		return l.Country
+	default:
+		return "unknown location"
+	}
+}
+
+// DistanceKm is the great-circle distance between two locations, by the
+// haversine formula.
+func (l *Location) DistanceKm(other *Location) float64 {
+	lat1, lat2 := radians(l.Latitude), radians(other.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(other.Longitude - l.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+// Locator looks up IP addresses.
+type Locator interface {
+	// Lookup returns the location of ip, or nil if it is unknown (private
+	// ranges, addresses missing from the database, unparsable input).
+	Lookup(ip string) (*Location, error)
+}
+
+// MMDBLocator reads a MaxMind-format .mmdb database with city data, such
+// as GeoLite2-City or DB-IP City Lite. The file is memory-mapped; replacing
+// it needs a restart.
+type MMDBLocator struct {
+	db *maxminddb.Reader
+}
+
+func OpenMMDB(path string) (*MMDBLocator, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MMDBLocator{db: db}, nil
+}
+
+// mmdbCity holds the fields of a City record that Lookup uses.
+type mmdbCity struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude       *float64 `maxminddb:"latitude"`
+		Longitude      *float64 `maxminddb:"longitude"`
+		AccuracyRadius int      `maxminddb:"accuracy_radius"`
+	} `maxminddb:"location"`
+}
+
+func (l *MMDBLocator) Lookup(ip string) (*Location, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, nil
+	}
+
+	var record mmdbCity
+	if err := l.db.Lookup(addr, &record); err != nil {
+		return nil, err
+	}
+	if record.Location.Latitude == nil || record.Location.Longitude == nil {
+		return nil, nil
+	}
+
+	return &Location{
+		Country:   record.Country.ISOCode,
+		City:      record.City.Names["en"],
+		Latitude:  *record.Location.Latitude,
+		Longitude: *record.Location.Longitude,
+		RadiusKm:  record.Location.AccuracyRadius,
+	}, nil
+}
+
+// DatabaseType names the database, e.g. "GeoLite2-City".
+func (l *MMDBLocator) DatabaseType() string {
+	return l.db.Metadata.DatabaseType
+}
+
+func (l *MMDBLocator) Close() error {
+	return l.db.Close()
+}
@@ -0,0 +1,61 @@
+package geoip
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	var (
+		berlin  = &Location{Latitude: 52.5200, Longitude: 13.4050}
+		paris   = &Location{Latitude: 48.8566, Longitude: 2.3522}
+		london  = &Location{Latitude: 51.5074, Longitude: -0.1278}
+		newYork = &Location{Latitude: 40.7128, Longitude: -74.0060}
+		sydney  = &Location{Latitude: -33.8688, Longitude: 151.2093}
+		origin  = &Location{}
+	)
+
+	tests := []struct {
+		name   string
+		a, b   *Location
+		wantKm float64
+		within float64
+	}{
+		{"same place", berlin, berlin, 0, 0.001},
+		{"quarter of the equator", origin, &Location{Longitude: 90}, math.Pi * earthRadiusKm / 2, 0.001},
+		{"antipodes", origin, &Location{Longitude: 180}, math.Pi * earthRadiusKm, 0.001},
+		{"pole to pole", &Location{Latitude: 90}, &Location{Latitude: -90}, math.Pi * earthRadiusKm, 0.001},
+		{"across the date line", &Location{Longitude: 179.5}, &Location{Longitude: -179.5}, math.Pi * earthRadiusKm / 180, 0.001},
+		{"Berlin to Paris", berlin, paris, 878, 5},
+		{"London to New York", london, newYork, 5570, 10},
+		{"London to Sydney", london, sydney, 16994, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.a.DistanceKm(tt.b)
+			if math.Abs(got-tt.wantKm) > tt.within {
+				t.Errorf("got %.3f km, want %.3f ± %.3f", got, tt.wantKm, tt.within)
+			}
+			if back := tt.b.DistanceKm(tt.a); math.Abs(back-got) > 1e-9 {
+				t.Errorf("distance back is %.3f km, want %.3f", back, got)
+			}
+		})
+	}
+}
+
+func TestLocationString(t *testing.T) {
+	tests := []struct {
+		location Location
+		want     string
+	}{
+		{Location{Country: "DE", City: "Berlin"}, "Berlin, DE"},
+		{Location{Country: "DE"}, "DE"},
+		{Location{City: "Berlin"}, "unknown location"},
+		{Location{}, "unknown location"},
+	}
+	for _, tt := range tests {
+		if got := tt.location.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
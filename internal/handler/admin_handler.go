@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/internal/service"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxImportBytes bounds the size of a user import upload.
+const maxImportBytes = 32 << 20
+
+type AdminHandler struct {
+	Keys    *service.KeyService
+	OAuth   *service.OAuthService
+	Lockout *service.LockoutService
+	Import  *service.UserImportService
+	Logger  *logger.Logger
+}
+
+func NewAdminHandler(
+	keys *service.KeyService,
+	oauth *service.OAuthService,
+	lockout *service.LockoutService,
+	imports *service.UserImportService,
+	log *logger.Logger,
+) *AdminHandler {
+	return &AdminHandler{
+		Keys:    keys,
+		OAuth:   oauth,
+		Lockout: lockout,
+		Import:  imports,
+		Logger:  log,
+	}
+}
+
+func (h *AdminHandler) ListKeys(c *gin.Context) {
+	keys, err := h.Keys.List()
+	if err != nil {
+		h.keyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SigningKeysResponse{
+		Keys: keys,
+	})
+}
+
+func (h *AdminHandler) RotateKeys(c *gin.Context) {
+	var req dto.RotateKeysRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+	}
+
+	key, err := h.Keys.Rotate(req.Compromised)
+	if err != nil {
+		h.keyError(c, err)
+		return
+	}
+
+	h.Logger.Info("Signing keys rotated by admin " + middleware.GetUserID(c))
+	c.JSON(http.StatusOK, dto.SigningKeysResponse{
+		Keys: []models.SigningKey{*key},
+	})
+}
+
+func (h *AdminHandler) keyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrKeyRotationDisabled):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, repository.ErrRotationConflict):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{
+			Error: "keys were rotated concurrently, retry",
+		})
+	default:
+		h.Logger.Error("Signing key operation failed: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "signing key operation failed",
+		})
+	}
+}
+
+func (h *AdminHandler) CreateClient(c *gin.Context) {
+	var req dto.CreateClientRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	client, secret, err := h.OAuth.RegisterClient(service.ClientRegistration{
+		Name:         req.Name,
+		ClientType:   req.ClientType,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		GrantTypes:   req.GrantTypes,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRedirectURI) || errors.Is(err, service.ErrInvalidClientConfig) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+		h.Logger.Error("Failed to register OAuth client: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to register client",
+		})
+		return
+	}
+
+	resp := toClientResponse(client)
+	resp.ClientSecret = secret
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *AdminHandler) RotateClientSecret(c *gin.Context) {
+	clientID := c.Param("id")
+
+	secret, err := h.OAuth.RotateClientSecret(clientID)
+	if err != nil {
+		h.clientError(c, err)
+		return
+	}
+
+	client, err := h.OAuth.GetClient(clientID)
+	if err != nil {
+		h.clientError(c, err)
+		return
+	}
+
+	h.Logger.Info("Client secret for " + clientID + " rotated by admin " + middleware.GetUserID(c))
+	resp := toClientResponse(client)
+	resp.ClientSecret = secret
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AdminHandler) DisableClient(c *gin.Context) {
+	clientID := c.Param("id")
+
+	if err := h.OAuth.DisableClient(clientID); err != nil {
+		h.clientError(c, err)
+		return
+	}
+
+	client, err := h.OAuth.GetClient(clientID)
+	if err != nil {
+		h.clientError(c, err)
+		return
+	}
+
+	h.Logger.Info("Client " + clientID + " disabled by admin " + middleware.GetUserID(c))
+	c.JSON(http.StatusOK, toClientResponse(client))
+}
+
+func (h *AdminHandler) clientError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrUnknownClient) {
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error: "client not found",
+		})
+		return
+	}
+	h.Logger.Error("OAuth client operation failed: " + err.Error())
+	c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+		Error: "client operation failed",
+	})
+}
+
+func (h *AdminHandler) ListClients(c *gin.Context) {
+	clients, err := h.OAuth.ListClients()
+	if err != nil {
+		h.Logger.Error("Failed to list OAuth clients: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to list clients",
+		})
+		return
+	}
+
+	resp := make([]dto.ClientResponse, 0, len(clients))
+	for i := range clients {
+		resp = append(resp, toClientResponse(&clients[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func toClientResponse(client *models.OAuthClient) dto.ClientResponse {
+	return dto.ClientResponse{
+		ClientID:     client.ID,
+		Name:         client.Name,
+		ClientType:   client.ClientType,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		GrantTypes:   client.GrantTypes,
+		DisabledAt:   client.DisabledAt,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// UnlockUser lifts a login lockout before its cooldown ends and resets the
+// user's failed login count.
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	userID := c.Param("id")
+
+	// User IDs are UUIDs; anything else would fail the database cast
+	if uuid.Validate(userID) != nil {
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{
+			Error: "user not found",
+		})
+		return
+	}
+
+	unlocked, err := h.Lockout.Unlock(userID, middleware.GetUserID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownUser) {
+			c.JSON(http.StatusNotFound, dto.ErrorResponse{
+				Error: "user not found",
+			})
+			return
+		}
+		h.Logger.Error("Failed to unlock user: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to unlock user",
+		})
+		return
+	}
+
+	message := "user unlocked"
+	if !unlocked {
+		message = "user was not locked"
+	}
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: message,
+	})
+}
+
+// ImportUsers creates accounts from a JSONL (application/x-ndjson) or CSV
+// (text/csv) export of another identity store. Records that cannot be
+// imported are listed in the response; the rest are imported regardless.
+func (h *AdminHandler) ImportUsers(c *gin.Context) {
+	var format string
+	switch c.ContentType() {
+	case "application/x-ndjson", "application/jsonl", "application/jsonlines":
+		format = service.ImportFormatJSONL
+	case "text/csv":
+		format = service.ImportFormatCSV
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, dto.ErrorResponse{
+			Error: "send application/x-ndjson or text/csv",
+		})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	records, err := service.DecodeUserImport(body, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.Import.Import(records, middleware.GetUserID(c))
+	if err != nil {
+		h.Logger.Error("Failed to import users: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to import users",
+		})
+		return
+	}
+
+	response := dto.ImportUsersResponse{
+		Created: result.Created,
+		Skipped: result.Skipped,
+		Failed:  result.Failed,
+		Errors:  make([]dto.ImportUserError, len(result.Errors)),
+	}
+	for i, e := range result.Errors {
+		response.Errors[i] = dto.ImportUserError{
+			Line:  e.Line,
+			Email: e.Email,
+			Error: e.Error,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -1,26 +1,41 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
 	"github.com/abhay786-20/fraud-auth-service/internal/service"
 	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/password"
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	Service *service.AuthService
-	Logger  *logger.Logger
+	Service      *service.AuthService
+	MFA          *service.MFAService
+	Devices      *service.DeviceService
+	Verification *service.EmailVerificationService
+	Logger       *logger.Logger
 }
 
 func NewAuthHandler(
 	service *service.AuthService,
+	mfa *service.MFAService,
+	devices *service.DeviceService,
+	verification *service.EmailVerificationService,
 	log *logger.Logger,
 ) *AuthHandler {
 	return &AuthHandler{
-		Service: service,
-		Logger:  log,
+		Service:      service,
+		MFA:          mfa,
+		Devices:      devices,
+		Verification: verification,
+		Logger:       log,
 	}
 }
 
@@ -34,7 +49,18 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 		return
 	}
 
-	user, err := h.Service.Signup(req.Email, req.Password)
+	user, err := h.Service.Signup(req.Email, req.Password, loginOrigin(c))
+	var rejected *password.PolicyError
+	if errors.As(err, &rejected) {
+		c.JSON(http.StatusBadRequest, toPasswordRejectedResponse(rejected))
+		return
+	}
+	if errors.Is(err, service.ErrSignupDenied) {
+		c.JSON(http.StatusForbidden, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
 			Error: err.Error(),
@@ -42,9 +68,15 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 		return
 	}
 
+	// The account exists either way; the user can ask for another link
+	if err := h.Verification.Send(user); err != nil {
+		h.Logger.Error("Failed to send verification email: " + err.Error())
+	}
+
 	c.JSON(http.StatusCreated, dto.SignupResponse{
-		ID:    user.ID,
-		Email: user.Email,
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.IsEmailVerified(),
 	})
 }
 
@@ -58,7 +90,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.Service.Login(req.Email, req.Password)
+	origin := loginOrigin(c)
+	if req.DeviceFingerprint != "" {
+		origin.Fingerprint = req.DeviceFingerprint
+	}
+	user, assessment, err := h.Service.Login(req.Email, req.Password, origin)
+	// Denied and unverified logins get the wrong password answer too: the
+	// reason is in the logs, not in the response
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
 			Error: "invalid credentials",
@@ -66,15 +104,307 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.Service.GenerateToken(user)
+	// Users with a second factor get a short-lived mfa_token instead of
+	// tokens, to be redeemed at /mfa/verify with a code or passkey, unless
+	// they trusted this device and the login looks normal. The second
+	// factor also answers a risk challenge; users without one are let
+	// through or denied as configured, and always denied during an attack.
+	riskClaim := service.RiskClaim(assessment)
+	methods, err := h.MFA.Methods(user.ID)
 	if err != nil {
+		h.Logger.Error("Failed to check MFA enrollment: " + err.Error())
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 			Error: "failed to generate token",
 		})
 		return
 	}
+	trusted := false
+	if len(methods) > 0 {
+		trusted, err = h.Devices.SkipsSecondFactor(user.ID, origin, assessment)
+		if err != nil {
+			h.Logger.Error("Failed to check trusted devices: " + err.Error())
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+				Error: "failed to generate token",
+			})
+			return
+		}
+	}
+	if len(methods) > 0 && !trusted {
+		mfaToken, ttl, err := h.MFA.StartChallenge(user.ID, riskClaim)
+		if err != nil {
+			h.Logger.Error("Failed to start MFA challenge: " + err.Error())
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+				Error: "failed to generate token",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			Methods:     methods,
+			ExpiresIn:   int64(ttl.Seconds()),
+		})
+		return
+	}
+	if len(methods) == 0 && assessment != nil && assessment.Decision == risk.DecisionChallenge {
+		if err := h.Service.StepUpUnavailable(user, assessment); err != nil {
+			// Answered like a wrong password, or the attacker would learn
+			// which passwords were right
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Error: "invalid credentials",
+			})
+			return
+		}
+	}
+
+	tokens, err := h.Service.IssueTokens(user, service.IssueOptions{
+		AMR:  []string{models.AMRPassword},
+		Risk: riskClaim,
+	})
+	if err != nil {
+		h.Logger.Error("Failed to issue tokens: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to generate token",
+		})
+		return
+	}
+
+	h.Service.RecordLogin(user, origin, riskClaim)
+	c.JSON(http.StatusOK, toLoginResponse(tokens))
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req dto.RefreshRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	tokens, err := h.Service.Refresh(req.RefreshToken, "")
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Error: "invalid refresh token",
+			})
+			return
+		}
+		h.Logger.Error("Failed to refresh tokens: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to refresh token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, toLoginResponse(tokens))
+}
+
+func (h *AuthHandler) Me(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MeResponse{
+		ID:    claims.UserID,
+		Email: claims.Email,
+	})
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	// Body is optional; an empty body just revokes the access token
+	var req dto.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.Service.Logout(claims, req.RefreshToken); err != nil {
+		h.Logger.Error("Failed to logout: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to logout",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: "logged out",
+	})
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	if err := h.Service.LogoutAll(userID); err != nil {
+		h.Logger.Error("Failed to logout all sessions: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to logout",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: "logged out from all sessions",
+	})
+}
+
+// VerifyEmail redeems the token from a verification link. Tokens issued
+// before verification stay restricted; the client refreshes to lift that.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	if _, err := h.Verification.Verify(req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidVerificationToken) || errors.Is(err, service.ErrVerificationTokenExpired) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+		h.Logger.Error("Failed to verify email: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to verify email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: "email verified",
+	})
+}
+
+// ResendVerification mails a new verification link. The response is the same
+// whether or not the address belongs to an account, and throttled requests
+// are dropped silently for the same reason.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req dto.ResendVerificationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	err := h.Verification.Resend(req.Email)
+	if errors.Is(err, service.ErrVerificationThrottled) {
+		h.Logger.Warn("Verification email throttled for " + req.Email)
+	} else if err != nil {
+		h.Logger.Error("Failed to resend verification email: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to send verification email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.MessageResponse{
+		Message: "if the address belongs to an unverified account, a verification email is on its way",
+	})
+}
+
+// Verify is a forward-auth endpoint for API gateways (Traefik ForwardAuth,
+// nginx auth_request, Envoy ext_authz over HTTP). It runs behind JWTAuth, so
+// reaching it means the token is valid; the identity is returned as headers
+// for the gateway to copy onto the upstream request.
+func (h *AuthHandler) Verify(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	// Restricted tokens never reach upstream services
+	if claims.Restricted {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	for name, value := range middleware.IdentityHeaders(claims) {
+		c.Header(name, value)
+	}
+	c.Status(http.StatusOK)
+}
+
+func toLoginResponse(tokens *service.TokenPair) dto.LoginResponse {
+	return dto.LoginResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}
+}
+
+// deviceFingerprintHeader carries the client's device fingerprint. Login
+// and MFA requests may send it in the body instead.
+const deviceFingerprintHeader = "X-Device-Fingerprint"
+
+// deviceTrustCookie holds the token of a trusted device. It is HttpOnly so
+// scripts on the page cannot read it and replay it elsewhere.
+const deviceTrustCookie = "device_trust"
+
+// loginOrigin describes the client of a login or signup request for risk
+// scoring, fraud rules and device recognition.
+func loginOrigin(c *gin.Context) risk.Request {
+	return risk.Request{
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Fingerprint:    c.GetHeader(deviceFingerprintHeader),
+		DeviceToken:    deviceToken(c),
+		Time:           time.Now(),
+	}
+}
+
+// deviceToken returns the trust token the client's device cookie carries.
+func deviceToken(c *gin.Context) string {
+	token, err := c.Cookie(deviceTrustCookie)
+	if err != nil {
+		return ""
+	}
+	return token
+}
 
-	c.JSON(http.StatusOK, dto.LoginResponse{
-		Token: token,
+// setDeviceTrustCookie hands a trusted device its token until it expires.
+func setDeviceTrustCookie(c *gin.Context, token string, expires time.Time) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     deviceTrustCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(time.Until(expires).Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
 	})
 }
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/service"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceHandler struct {
+	Service *service.DeviceService
+	Logger  *logger.Logger
+}
+
+func NewDeviceHandler(
+	service *service.DeviceService,
+	log *logger.Logger,
+) *DeviceHandler {
+	return &DeviceHandler{
+		Service: service,
+		Logger:  log,
+	}
+}
+
+// ListDevices returns the devices the user has logged in from. The one
+// making the request is marked current.
+func (h *DeviceHandler) ListDevices(c *gin.Context) {
+	devices, err := h.Service.List(middleware.GetUserID(c))
+	if err != nil {
+		h.deviceError(c, err)
+		return
+	}
+
+	current := service.DeviceID(loginOrigin(c))
+	now := time.Now()
+	resp := make([]dto.DeviceResponse, len(devices))
+	for i := range devices {
+		resp[i] = toDeviceResponse(&devices[i], now)
+		resp[i].Current = devices[i].DeviceHash == current
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteDevice forgets a device. Its next login counts as new and needs the
+// second factor again.
+func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
+	if err := h.Service.Delete(middleware.GetUserID(c), c.Param("id")); err != nil {
+		h.deviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: "device removed",
+	})
+}
+
+func (h *DeviceHandler) deviceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: err.Error()})
+	default:
+		h.Logger.Error("Device request failed: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal error"})
+	}
+}
+
+// toDeviceResponse leaves out trust that has expired.
+func toDeviceResponse(d *models.UserDevice, now time.Time) dto.DeviceResponse {
+	resp := dto.DeviceResponse{
+		ID:          d.ID,
+		Name:        d.Name,
+		LastIP:      d.LastIP,
+		FirstSeenAt: d.FirstSeenAt,
+		LastSeenAt:  d.LastSeenAt,
+	}
+	if d.IsTrusted(now) {
+		resp.TrustedUntil = d.TrustedUntil
+	}
+	return resp
+}
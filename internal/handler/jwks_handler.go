@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	Keys utils.KeySet
+}
+
+func NewJWKSHandler(keys utils.KeySet) *JWKSHandler {
+	return &JWKSHandler{
+		Keys: keys,
+	}
+}
+
+// JWKS publishes the public verification keys. HMAC secrets are never exposed,
+// so an HS256 deployment serves an empty key set.
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	// Verifiers cache this; keep it short so new keys propagate quickly
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.BuildJWKS(h.Keys.PublicKeys()))
+}
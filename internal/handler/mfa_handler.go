@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/internal/service"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	Service *service.MFAService
+	Auth    *service.AuthService
+	Devices *service.DeviceService
+	Logger  *logger.Logger
+}
+
+func NewMFAHandler(
+	service *service.MFAService,
+	auth *service.AuthService,
+	devices *service.DeviceService,
+	log *logger.Logger,
+) *MFAHandler {
+	return &MFAHandler{
+		Service: service,
+		Auth:    auth,
+		Devices: devices,
+		Logger:  log,
+	}
+}
+
+// Verify completes a two-step login: it exchanges the mfa_token returned by
+// /login and a TOTP or recovery code for the real token pair.
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var req dto.MFAVerifyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	user, amr, riskClaim, err := h.Service.CompleteChallenge(req.MFAToken, service.MFAProof{
+		Code:               req.Code,
+		WebAuthnSession:    req.WebAuthnSession,
+		WebAuthnCredential: req.Credential,
+	})
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	tokens, err := h.Auth.IssueTokens(user, service.IssueOptions{AMR: amr, Risk: riskClaim})
+	if err != nil {
+		h.Logger.Error("Failed to issue tokens: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to generate token",
+		})
+		return
+	}
+
+	origin := loginOrigin(c)
+	if req.DeviceFingerprint != "" {
+		origin.Fingerprint = req.DeviceFingerprint
+	}
+	h.Auth.RecordLogin(user, origin, riskClaim)
+
+	// The tokens are issued either way; trust can be granted at the next login
+	if req.TrustDevice {
+		token, expires, err := h.Devices.Trust(user, origin)
+		if err != nil {
+			h.Logger.Error("Failed to trust device: " + err.Error())
+		} else if token != "" {
+			setDeviceTrustCookie(c, token, expires)
+		}
+	}
+
+	c.JSON(http.StatusOK, toLoginResponse(tokens))
+}
+
+// BeginWebAuthn starts a passkey assertion as the second step of a login.
+func (h *MFAHandler) BeginWebAuthn(c *gin.Context) {
+	var req dto.MFATokenRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	assertion, sessionToken, err := h.Service.BeginWebAuthn(req.MFAToken)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.WebAuthnBeginResponse{
+		SessionToken: sessionToken,
+		Options:      assertion,
+	})
+}
+
+func (h *MFAHandler) Status(c *gin.Context) {
+	status, err := h.Service.Status(middleware.GetUserID(c))
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MFAStatusResponse{
+		TOTPEnabled:            status.TOTPEnabled,
+		RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+		Passkeys:               status.Passkeys,
+	})
+}
+
+// EnrollTOTP starts TOTP enrollment. The secret is not enforced until
+// ConfirmTOTP succeeds.
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	user, err := h.Auth.GetUser(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	enrollment, err := h.Service.EnrollTOTP(user)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.TOTPEnrollResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+	})
+}
+
+// ConfirmTOTP enables TOTP and returns the recovery codes, once.
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	var req dto.MFACodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.Service.ConfirmTOTP(middleware.GetUserID(c), req.Code)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	var req dto.MFACodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	if err := h.Service.DisableTOTP(middleware.GetUserID(c), req.Code); err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: "two-factor authentication disabled",
+	})
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.MFACodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.Service.RegenerateRecoveryCodes(middleware.GetUserID(c), req.Code)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+func (h *MFAHandler) mfaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode),
+		errors.Is(err, service.ErrInvalidMFAToken),
+		errors.Is(err, service.ErrInvalidPasskey),
+		errors.Is(err, service.ErrPasskeyCloned),
+		errors.Is(err, service.ErrWebAuthnSession):
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrMFAProofRequired):
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrMFAAlreadyActive),
+		errors.Is(err, service.ErrMFANotEnrolled),
+		errors.Is(err, service.ErrNoPasskeys):
+		c.JSON(http.StatusConflict, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrMFAUnavailable):
+		c.JSON(http.StatusNotImplemented, dto.ErrorResponse{Error: err.Error()})
+	default:
+		h.Logger.Error("MFA request failed: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal error"})
+	}
+}
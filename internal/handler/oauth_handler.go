@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/service"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var authorizeTemplate = template.Must(template.ParseFS(templateFS, "templates/authorize.html"))
+
+type OAuthHandler struct {
+	Service *service.OAuthService
+	Logger  *logger.Logger
+}
+
+func NewOAuthHandler(
+	service *service.OAuthService,
+	log *logger.Logger,
+) *OAuthHandler {
+	return &OAuthHandler{
+		Service: service,
+		Logger:  log,
+	}
+}
+
+type authorizePage struct {
+	ClientName string
+	Request    *service.AuthorizeRequest
+	Email      string
+	MFAToken   string // Set when the password was accepted and a code is needed
+	Error      string
+}
+
+// Authorize (GET) validates the authorization request and renders the login page.
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var form dto.AuthorizeRequest
+	if err := c.ShouldBindQuery(&form); err != nil {
+		h.renderError(c, http.StatusBadRequest, "malformed authorization request")
+		return
+	}
+
+	req := toAuthorizeRequest(&form)
+	client, err := h.Service.ValidateAuthorizeRequest(req)
+	if err != nil {
+		h.authorizeError(c, req, err)
+		return
+	}
+
+	h.renderLogin(c, http.StatusOK, authorizePage{
+		ClientName: client.Name,
+		Request:    req,
+	})
+}
+
+// AuthorizeSubmit (POST) handles the login form and redirects back to the client with a code.
+func (h *OAuthHandler) AuthorizeSubmit(c *gin.Context) {
+	var form dto.AuthorizeRequest
+	if err := c.ShouldBind(&form); err != nil {
+		h.renderError(c, http.StatusBadRequest, "malformed authorization request")
+		return
+	}
+
+	req := toAuthorizeRequest(&form)
+	if form.MFAToken != "" {
+		h.authorizeMFA(c, req, &form)
+		return
+	}
+
+	redirect, mfaToken, err := h.Service.Authorize(req, form.Email, form.Password, loginOrigin(c))
+	// Denied and unverified logins are answered like a wrong password, so
+	// the page does not tell an attacker the password was right
+	if errors.Is(err, service.ErrInvalidCredentials) || errors.Is(err, service.ErrLoginDenied) ||
+		errors.Is(err, service.ErrEmailNotVerified) {
+		client, _ := h.Service.ValidateAuthorizeRequest(req)
+		h.renderLogin(c, http.StatusUnauthorized, authorizePage{
+			ClientName: client.Name,
+			Request:    req,
+			Email:      form.Email,
+			Error:      "Invalid email or password.",
+		})
+		return
+	}
+	if err != nil {
+		h.authorizeError(c, req, err)
+		return
+	}
+
+	if mfaToken != "" {
+		client, _ := h.Service.ValidateAuthorizeRequest(req)
+		h.renderLogin(c, http.StatusOK, authorizePage{
+			ClientName: client.Name,
+			Request:    req,
+			MFAToken:   mfaToken,
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// authorizeMFA handles the verification code form shown after the password step.
+func (h *OAuthHandler) authorizeMFA(c *gin.Context, req *service.AuthorizeRequest, form *dto.AuthorizeRequest) {
+	redirect, err := h.Service.AuthorizeMFA(req, form.MFAToken, service.MFAProof{
+		Code:               form.Code,
+		WebAuthnSession:    form.WebAuthnSession,
+		WebAuthnCredential: []byte(form.WebAuthnCredential),
+	}, loginOrigin(c))
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode),
+		errors.Is(err, service.ErrMFAProofRequired),
+		errors.Is(err, service.ErrInvalidPasskey),
+		errors.Is(err, service.ErrPasskeyCloned),
+		errors.Is(err, service.ErrWebAuthnSession):
+		client, _ := h.Service.ValidateAuthorizeRequest(req)
+		h.renderLogin(c, http.StatusUnauthorized, authorizePage{
+			ClientName: client.Name,
+			Request:    req,
+			MFAToken:   form.MFAToken,
+			Error:      "Verification failed. Please try again.",
+		})
+		return
+	case errors.Is(err, service.ErrInvalidMFAToken):
+		// Expired or too many attempts; start over from the password
+		client, _ := h.Service.ValidateAuthorizeRequest(req)
+		h.renderLogin(c, http.StatusUnauthorized, authorizePage{
+			ClientName: client.Name,
+			Request:    req,
+			Error:      "Your sign-in attempt expired. Please sign in again.",
+		})
+		return
+	case err != nil:
+		h.authorizeError(c, req, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// Token implements POST /oauth2/token for the authorization_code,
+// refresh_token and client_credentials grants.
+func (h *OAuthHandler) Token(c *gin.Context) {
+	// Token responses must never be cached (RFC 6749 section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req dto.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.OAuthErrorResponse{
+			Error:            "invalid_request",
+			ErrorDescription: "grant_type is required",
+		})
+		return
+	}
+
+	creds, usedBasic := clientCredentials(c, req.ClientID, req.ClientSecret)
+
+	var (
+		tokens *service.TokenPair
+		err    error
+	)
+	switch req.GrantType {
+	case models.GrantAuthorizationCode:
+		tokens, err = h.Service.ExchangeCode(creds, req.Code, req.RedirectURI, req.CodeVerifier)
+	case models.GrantRefreshToken:
+		tokens, err = h.Service.RefreshGrant(creds, req.RefreshToken)
+	case models.GrantClientCredentials:
+		tokens, err = h.Service.ClientCredentialsGrant(creds, req.Scope)
+	default:
+		c.JSON(http.StatusBadRequest, dto.OAuthErrorResponse{
+			Error: "unsupported_grant_type",
+		})
+		return
+	}
+
+	if err != nil {
+		h.oauthError(c, err, usedBasic)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		Scope:        tokens.Scope,
+	})
+}
+
+// Introspect implements RFC 7662 token introspection for resource servers.
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	var req dto.IntrospectRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.OAuthErrorResponse{
+			Error:            "invalid_request",
+			ErrorDescription: "token is required",
+		})
+		return
+	}
+
+	creds, usedBasic := clientCredentials(c, req.ClientID, req.ClientSecret)
+	result, err := h.Service.Introspect(creds, req.Token, req.TokenTypeHint)
+	if err != nil {
+		h.oauthError(c, err, usedBasic)
+		return
+	}
+
+	if !result.Active {
+		c.JSON(http.StatusOK, dto.IntrospectResponse{Active: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.IntrospectResponse{
+		Active:    true,
+		TokenType: result.TokenType,
+		Sub:       result.Subject,
+		ClientID:  result.ClientID,
+		Username:  result.Username,
+		Scope:     result.Scope,
+		TokenUse:  result.TokenUse,
+		Iss:       result.Issuer,
+		Aud:       result.Audience,
+		Jti:       result.JTI,
+		Exp:       result.ExpiresAt.Unix(),
+		Iat:       result.IssuedAt.Unix(),
+	})
+}
+
+// oauthError writes an RFC 6749 error response. invalid_client is a 401,
+// with a Basic challenge if the client tried HTTP Basic authentication.
+func (h *OAuthHandler) oauthError(c *gin.Context, err error, usedBasic bool) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		h.Logger.Error("OAuth endpoint failed: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.OAuthErrorResponse{
+			Error: "server_error",
+		})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+		if usedBasic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth2"`)
+		}
+	}
+	c.JSON(status, dto.OAuthErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
+
+// authorizeError reports a failed authorization request. Errors about the
+// client or redirect URI are shown to the user; anything else is sent back
+// to the (now verified) redirect URI.
+func (h *OAuthHandler) authorizeError(c *gin.Context, req *service.AuthorizeRequest, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownClient):
+		h.renderError(c, http.StatusBadRequest, "Unknown client.")
+		return
+	case errors.Is(err, service.ErrInvalidRedirectURI):
+		h.renderError(c, http.StatusBadRequest, "The redirect URI is not registered for this client.")
+		return
+	}
+
+	params := url.Values{"state": {req.State}}
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		params.Set("error", oauthErr.Code)
+		params.Set("error_description", oauthErr.Description)
+	} else {
+		h.Logger.Error("Authorization request failed: " + err.Error())
+		params.Set("error", "server_error")
+	}
+
+	c.Redirect(http.StatusFound, h.Service.RedirectURL(req.RedirectURI, params))
+}
+
+func (h *OAuthHandler) renderLogin(c *gin.Context, status int, page authorizePage) {
+	// The login page must not be framed (clickjacking) or cached
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "frame-ancestors 'none'")
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+
+	if err := authorizeTemplate.Execute(c.Writer, page); err != nil {
+		h.Logger.Error("Failed to render login page: " + err.Error())
+	}
+}
+
+func (h *OAuthHandler) renderError(c *gin.Context, status int, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.String(status, "Authorization failed: "+message)
+}
+
+// clientCredentials reads client authentication from HTTP Basic
+// (client_secret_basic) or the form body (client_secret_post / public clients).
+func clientCredentials(c *gin.Context, formID, formSecret string) (service.ClientCredentials, bool) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		// RFC 6749 section 2.3.1: both parts are form-urlencoded
+		if decoded, err := url.QueryUnescape(id); err == nil {
+			id = decoded
+		}
+		if decoded, err := url.QueryUnescape(secret); err == nil {
+			secret = decoded
+		}
+		return service.ClientCredentials{ID: id, Secret: secret}, true
+	}
+
+	return service.ClientCredentials{ID: formID, Secret: formSecret}, false
+}
+
+func toAuthorizeRequest(form *dto.AuthorizeRequest) *service.AuthorizeRequest {
+	return &service.AuthorizeRequest{
+		ResponseType:        form.ResponseType,
+		ClientID:            form.ClientID,
+		RedirectURI:         form.RedirectURI,
+		Scope:               form.Scope,
+		State:               form.State,
+		Nonce:               form.Nonce,
+		CodeChallenge:       form.CodeChallenge,
+		CodeChallengeMethod: form.CodeChallengeMethod,
+	}
+}
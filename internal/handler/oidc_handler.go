@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/internal/service"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	Service *service.AuthService
+	Keys    utils.KeySet
+	Issuer  string
+	Logger  *logger.Logger
+}
+
+func NewOIDCHandler(
+	service *service.AuthService,
+	keys utils.KeySet,
+	issuer string,
+	log *logger.Logger,
+) *OIDCHandler {
+	return &OIDCHandler{
+		Service: service,
+		Keys:    keys,
+		Issuer:  issuer,
+		Logger:  log,
+	}
+}
+
+// Discovery serves /.well-known/openid-configuration.
+func (h *OIDCHandler) Discovery(c *gin.Context) {
+	var algs []string
+	if key, err := h.Keys.SigningKey(); err == nil {
+		algs = append(algs, key.Method.Alg())
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, dto.OpenIDConfiguration{
+		Issuer:                           h.Issuer,
+		AuthorizationEndpoint:            h.Issuer + "/oauth2/authorize",
+		TokenEndpoint:                    h.Issuer + "/oauth2/token",
+		UserInfoEndpoint:                 h.Issuer + "/oauth2/userinfo",
+		IntrospectionEndpoint:            h.Issuer + "/oauth2/introspect",
+		JWKSURI:                          h.Issuer + "/.well-known/jwks.json",
+		ScopesSupported:                  []string{"openid", "email"},
+		ResponseTypesSupported:           []string{"code"},
+		GrantTypesSupported:              []string{"authorization_code", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: algs,
+		ClaimsSupported:                  []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified", "amr"},
+		CodeChallengeMethodsSupported:    []string{utils.PKCEMethodS256},
+		TokenEndpointAuthMethods:         []string{"none", "client_secret_basic", "client_secret_post"},
+	})
+}
+
+// UserInfo returns the claims about the user behind the access token,
+// read from the user store rather than the (possibly stale) token.
+func (h *OIDCHandler) UserInfo(c *gin.Context) {
+	user, err := h.Service.GetUser(middleware.GetUserID(c))
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "invalid token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UserInfoResponse{
+		Sub:           user.ID,
+		Email:         user.Email,
+		EmailVerified: user.IsEmailVerified(),
+	})
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/internal/service"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/password"
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordHandler struct {
+	Service *service.PasswordService
+	Logger  *logger.Logger
+}
+
+func NewPasswordHandler(
+	service *service.PasswordService,
+	log *logger.Logger,
+) *PasswordHandler {
+	return &PasswordHandler{
+		Service: service,
+		Logger:  log,
+	}
+}
+
+// Change sets a new password for the signed-in user. Other sessions are
+// signed out; the response carries a fresh token pair for this one.
+func (h *PasswordHandler) Change(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	tokens, err := h.Service.ChangePassword(claims, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		var rejected *password.PolicyError
+		switch {
+		case errors.Is(err, service.ErrWrongPassword):
+			c.JSON(http.StatusForbidden, dto.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, service.ErrPasswordUnchanged):
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+		case errors.As(err, &rejected):
+			c.JSON(http.StatusBadRequest, toPasswordRejectedResponse(rejected))
+		default:
+			h.Logger.Error("Failed to change password: " + err.Error())
+			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+				Error: "failed to change password",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toLoginResponse(tokens))
+}
+
+// Forgot mails a password reset link. The response is the same whether or
+// not the address belongs to an account, and throttled requests are dropped
+// silently for the same reason.
+func (h *PasswordHandler) Forgot(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	err := h.Service.ForgotPassword(req.Email)
+	if errors.Is(err, service.ErrResetThrottled) {
+		h.Logger.Warn("Password reset throttled for " + req.Email)
+	} else if err != nil {
+		h.Logger.Error("Failed to start password reset: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to send password reset email",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dto.MessageResponse{
+		Message: "if the address belongs to an account, a password reset email is on its way",
+	})
+}
+
+// Reset sets a new password with the token from a reset link. Every session
+// of the account is signed out.
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	if err := h.Service.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error: err.Error(),
+			})
+			return
+		}
+		var rejected *password.PolicyError
+		if errors.As(err, &rejected) {
+			c.JSON(http.StatusBadRequest, toPasswordRejectedResponse(rejected))
+			return
+		}
+		h.Logger.Error("Failed to reset password: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to reset password",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: "password has been reset",
+	})
+}
+
+// toPasswordRejectedResponse lists every rule the password broke, so a
+// client can point all of them out at once.
+func toPasswordRejectedResponse(err *password.PolicyError) dto.PasswordRejectedResponse {
+	reasons := make([]dto.PasswordViolation, len(err.Violations))
+	for i, v := range err.Violations {
+		reasons[i] = dto.PasswordViolation{
+			Reason:  v.Reason,
+			Message: v.Message,
+		}
+	}
+	return dto.PasswordRejectedResponse{
+		Error:   "password does not meet the password policy",
+		Reasons: reasons,
+	}
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/service"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type WebAuthnHandler struct {
+	Service *service.WebAuthnService
+	Auth    *service.AuthService
+	Logger  *logger.Logger
+}
+
+func NewWebAuthnHandler(
+	service *service.WebAuthnService,
+	auth *service.AuthService,
+	log *logger.Logger,
+) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		Service: service,
+		Auth:    auth,
+		Logger:  log,
+	}
+}
+
+// BeginRegistration returns the options for navigator.credentials.create().
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	user, err := h.Auth.GetUser(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	creation, sessionToken, err := h.Service.BeginRegistration(user)
+	if err != nil {
+		h.webauthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.WebAuthnBeginResponse{
+		SessionToken: sessionToken,
+		Options:      creation,
+	})
+}
+
+// FinishRegistration verifies the new credential and stores it.
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	var req dto.WebAuthnRegisterRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	user, err := h.Auth.GetUser(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	credential, err := h.Service.FinishRegistration(user, req.SessionToken, req.Name, req.Credential)
+	if err != nil {
+		h.webauthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toWebAuthnCredentialResponse(credential))
+}
+
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	credentials, err := h.Service.ListCredentials(middleware.GetUserID(c))
+	if err != nil {
+		h.webauthnError(c, err)
+		return
+	}
+
+	resp := make([]dto.WebAuthnCredentialResponse, len(credentials))
+	for i := range credentials {
+		resp[i] = toWebAuthnCredentialResponse(&credentials[i])
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	if err := h.Service.DeleteCredential(middleware.GetUserID(c), c.Param("id")); err != nil {
+		h.webauthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.MessageResponse{
+		Message: "passkey removed",
+	})
+}
+
+// BeginLogin starts a passwordless login with a discoverable passkey.
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	assertion, sessionToken, err := h.Service.BeginPasswordlessLogin()
+	if err != nil {
+		h.webauthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.WebAuthnBeginResponse{
+		SessionToken: sessionToken,
+		Options:      assertion,
+	})
+}
+
+// FinishLogin verifies a passwordless assertion and issues tokens.
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var req dto.WebAuthnLoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	user, amr, err := h.Service.FinishLogin(req.SessionToken, req.Credential)
+	if err != nil {
+		h.webauthnError(c, err)
+		return
+	}
+
+	tokens, err := h.Auth.IssueTokens(user, service.IssueOptions{AMR: amr})
+	if err != nil {
+		h.Logger.Error("Failed to issue tokens: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
+			Error: "failed to generate token",
+		})
+		return
+	}
+
+	// Passkey logins are not risk-scored, but they vouch for the device
+	// and network in later assessments
+	h.Auth.RecordLogin(user, loginOrigin(c), nil)
+	c.JSON(http.StatusOK, toLoginResponse(tokens))
+}
+
+func (h *WebAuthnHandler) webauthnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPasskey),
+		errors.Is(err, service.ErrPasskeyCloned),
+		errors.Is(err, service.ErrWebAuthnSession):
+		c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrPasskeyNotFound):
+		c.JSON(http.StatusNotFound, dto.ErrorResponse{Error: err.Error()})
+	default:
+		h.Logger.Error("WebAuthn request failed: " + err.Error())
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal error"})
+	}
+}
+
+func toWebAuthnCredentialResponse(c *models.WebAuthnCredential) dto.WebAuthnCredentialResponse {
+	return dto.WebAuthnCredentialResponse{
+		ID:              c.ID,
+		Name:            c.Name,
+		Attachment:      c.Attachment,
+		Synced:          c.BackupState,
+		CloneDetectedAt: c.CloneDetectedAt,
+		LastUsedAt:      c.LastUsedAt,
+		CreatedAt:       c.CreatedAt,
+	}
+}
@@ -1 +1,122 @@
 package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+// Context keys under which the authenticated identity is stored.
+const (
+	ContextKeyClaims = "auth.claims"
+	ContextKeyToken  = "auth.token"
+)
+
+const bearerPrefix = "Bearer "
+
+// TokenValidator validates a raw access token and returns its claims.
+// AuthService implements it; the interface keeps the middleware free of
+// any knowledge about secrets or storage.
+type TokenValidator interface {
+	ValidateToken(token string) (*utils.Claims, error)
+}
+
+// JWTAuth returns a gin middleware that requires a valid Bearer token.
+// On success the parsed claims are available through GetClaims and friends.
+func JWTAuth(validator TokenValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := ExtractBearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Error: "missing or malformed authorization header",
+			})
+			return
+		}
+
+		claims, err := validator.ValidateToken(token)
+		if err != nil {
+			message := "invalid token"
+			switch {
+			case errors.Is(err, utils.ErrExpiredToken):
+				message = "token has expired"
+			case errors.Is(err, utils.ErrRevokedToken):
+				message = "token has been revoked"
+			}
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Error: message,
+			})
+			return
+		}
+
+		c.Set(ContextKeyClaims, claims)
+		c.Set(ContextKeyToken, token)
+		c.Next()
+	}
+}
+
+// ExtractBearerToken returns the token from an "Authorization: Bearer <token>" header value.
+func ExtractBearerToken(header string) (string, bool) {
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[len(bearerPrefix):])
+	if token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// GetClaims returns the claims stored by JWTAuth.
+func GetClaims(c *gin.Context) (*utils.Claims, bool) {
+	val, ok := c.Get(ContextKeyClaims)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := val.(*utils.Claims)
+	return claims, ok
+}
+
+// GetUserID returns the authenticated user's ID, or "" if the request is unauthenticated.
+func GetUserID(c *gin.Context) string {
+	if claims, ok := GetClaims(c); ok {
+		return claims.UserID
+	}
+	return ""
+}
+
+// GetUserEmail returns the authenticated user's email, or "" if the request is unauthenticated.
+func GetUserEmail(c *gin.Context) string {
+	if claims, ok := GetClaims(c); ok {
+		return claims.Email
+	}
+	return ""
+}
+
+// GetToken returns the raw bearer token that was validated for this request.
+func GetToken(c *gin.Context) string {
+	return c.GetString(ContextKeyToken)
+}
+
+// IdentityHeaders are the headers a gateway forwards to upstream services.
+func IdentityHeaders(claims *utils.Claims) map[string]string {
+	headers := map[string]string{
+		"X-User-Id":    claims.UserID,
+		"X-User-Email": claims.Email,
+		"X-User-Role":  claims.Role,
+		"X-Client-Id":  claims.ClientID,
+		"X-Scope":      claims.Scope,
+	}
+	for name, value := range headers {
+		if value == "" {
+			delete(headers, name)
+		}
+	}
+	return headers
+}
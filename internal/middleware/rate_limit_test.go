@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/ratelimit"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+)
+
+// fixedLimiter answers every request with the same result.
+type fixedLimiter struct {
+	result ratelimit.Result
+	err    error
+}
+
+func (l fixedLimiter) Allow(context.Context, ratelimit.Rule, string) (ratelimit.Result, error) {
+	return l.result, l.err
+}
+
+// attacks reports an attack on every IP in it.
+type attacks map[string]string
+
+func (a attacks) UnderAttack(ip string) string {
+	return a[ip]
+}
+
+var testRule = ratelimit.Rule{Name: "ip", Algorithm: ratelimit.TokenBucket, Limit: 2, Window: time.Minute}
+
+func newRouter(limit gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/login", limit, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return engine
+}
+
+func post(engine *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit(t *testing.T) {
+	engine := newRouter(RateLimit(ratelimit.NewMemoryLimiter(), testRule, KeyByIP, logger.New()))
+
+	tests := []struct {
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{http.StatusOK, "1", ""},
+		{http.StatusOK, "0", ""},
+		{http.StatusTooManyRequests, "0", "30"},
+	}
+	for i, tt := range tests {
+		rec := post(engine, `{}`)
+
+		if rec.Code != tt.status {
+			t.Errorf("request %d: got status %d, want %d", i, rec.Code, tt.status)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: got RateLimit-Limit %q, want 2", i, got)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != tt.remaining {
+			t.Errorf("request %d: got RateLimit-Remaining %q, want %q", i, got, tt.remaining)
+		}
+		if got := rec.Header().Get("RateLimit-Reset"); got == "" {
+			t.Errorf("request %d: no RateLimit-Reset header", i)
+		}
+		if got := rec.Header().Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("request %d: got Retry-After %q, want %q", i, got, tt.retryAfter)
+		}
+	}
+
+	rec := post(engine, `{}`)
+	if !strings.Contains(rec.Body.String(), "too many requests") {
+		t.Errorf("got body %s, want a too many requests error", rec.Body.String())
+	}
+}
+
+func TestRateLimitRoundsHeadersUp(t *testing.T) {
+	limiter := fixedLimiter{result: ratelimit.Result{
+		Limit:      5,
+		RetryAfter: 1500 * time.Millisecond,
+		Reset:      59100 * time.Millisecond,
+	}}
+	rec := post(newRouter(RateLimit(limiter, testRule, KeyByIP, logger.New())), `{}`)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("got Retry-After %q, want 2", got)
+	}
+	if got := rec.Header().Get("RateLimit-Reset"); got != "60" {
+		t.Errorf("got RateLimit-Reset %q, want 60", got)
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	limiter := fixedLimiter{err: errors.New("connection refused")}
+	rec := post(newRouter(RateLimit(limiter, testRule, KeyByIP, logger.New())), `{}`)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d, want 200 when the limiter fails", rec.Code)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "" {
+		t.Errorf("got RateLimit-Limit %q, want none", got)
+	}
+}
+
+func TestRateLimitByEmail(t *testing.T) {
+	rule := testRule
+	rule.Limit = 1
+	engine := newRouter(RateLimit(ratelimit.NewMemoryLimiter(), rule, KeyByEmail, logger.New()))
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"email": "Alice@example.com"}`, http.StatusOK},
+		{`{"email": " alice@example.com "}`, http.StatusTooManyRequests},
+		{`{"email": "bob@example.com"}`, http.StatusOK},
+		{`{}`, http.StatusOK}, // No email, not limited by this rule
+		{`{}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := post(engine, tt.body); rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.body, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestRateLimitUnderAttack(t *testing.T) {
+	rule := testRule
+	rule.Limit = 1
+	limiter := ratelimit.NewMemoryLimiter()
+
+	calm := newRouter(RateLimitUnderAttack(attacks{}, limiter, rule, KeyByIP, logger.New()))
+	for i := 0; i < 3; i++ {
+		if rec := post(calm, `{}`); rec.Code != http.StatusOK {
+			t.Fatalf("request %d without an attack: got status %d, want 200", i, rec.Code)
+		}
+	}
+
+	attacked := newRouter(RateLimitUnderAttack(attacks{"192.0.2.1": "192.0.2.0/24"}, limiter, rule, KeyByIP, logger.New()))
+	if rec := post(attacked, `{}`); rec.Code != http.StatusOK {
+		t.Errorf("first request during an attack: got status %d, want 200", rec.Code)
+	}
+	if rec := post(attacked, `{}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second request during an attack: got status %d, want 429", rec.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/dto"
+)
+
+// RequireRole returns a gin middleware that only lets through tokens whose
+// role claim is one of roles. It must run after JWTAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Error: "unauthorized",
+			})
+			return
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{
+			Error: "forbidden",
+		})
+	}
+}
+
+// RequireUser rejects service (client_credentials) tokens on routes that act
+// on behalf of a human user. It must run after JWTAuth.
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Error: "unauthorized",
+			})
+			return
+		}
+
+		if claims.IsServiceToken() || claims.UserID == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{
+				Error: "user token required",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail rejects restricted tokens, which are issued to users
+// who have not verified their email address yet. It must run after JWTAuth.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{
+				Error: "unauthorized",
+			})
+			return
+		}
+
+		if claims.Restricted {
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{
+				Error: "email verification required",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
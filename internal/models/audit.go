@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Audit event types
+const (
+	AuditAccountLocked   = "account.locked"   // Too many failed logins
+	AuditAccountUnlocked = "account.unlocked" // An admin lifted a lockout
+	AuditUsersImported   = "users.imported"   // Accounts were imported from another system
+	AuditLoginRisk       = "login.risk"       // A password login was risk-scored
+	AuditLoginDenied     = "login.denied"     // A login with the right password was refused
+	AuditAttackDetected  = "attack.detected"  // Failed logins across accounts crossed a credential stuffing threshold
+	AuditSignupScreened  = "signup.screened"  // A signup matched a fraud rule
+)
+
+// AuditEvent is a security-relevant event kept for later investigation.
+type AuditEvent struct {
+	ID        string    `db:"id"`
+	Event     string    `db:"event"`
+	UserID    *string   `db:"user_id"`  // Account concerned, if any
+	ActorID   *string   `db:"actor_id"` // Who caused it, when not the user
+	Detail    string    `db:"detail"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// UserDevice is a browser or app a user has logged in from.
+type UserDevice struct {
+	ID             string     `db:"id"`
+	UserID         string     `db:"user_id"`
+	DeviceHash     string     `db:"device_hash"` // device.ID of the client
+	Name           string     `db:"name"`        // e.g. "Firefox on Windows"
+	UserAgent      string     `db:"user_agent"`  // As last seen
+	LastIP         string     `db:"last_ip"`
+	FirstSeenAt    time.Time  `db:"first_seen_at"`
+	LastSeenAt     time.Time  `db:"last_seen_at"`
+	TrustedUntil   *time.Time `db:"trusted_until"`    // nil unless the user chose to trust the device
+	TrustTokenHash *string    `db:"trust_token_hash"` // SHA-256 of the device trust cookie; nil if never trusted
+}
+
+// IsTrusted reports whether trust granted to the device is in force at
+// time t. Only a login presenting the trust token skips the second factor.
+func (d *UserDevice) IsTrusted(t time.Time) bool {
+	return d.TrustedUntil != nil && t.Before(*d.TrustedUntil)
+}
+
+// IsTrustedWith reports whether the device is trusted at time t and
+// tokenHash is the hash of the trust token it was issued.
+func (d *UserDevice) IsTrustedWith(tokenHash string, t time.Time) bool {
+	return d.IsTrusted(t) && d.TrustTokenHash != nil && *d.TrustTokenHash == tokenHash
+}
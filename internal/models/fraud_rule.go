@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FraudRule is an analyst-written rule evaluated on logins or signups (see
+// package rules). It comes from the fraud_rules table or a YAML file.
+type FraudRule struct {
+	ID          string         `db:"id"`
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Event       string         `db:"event"`      // "login" or "signup"
+	Expression  string         `db:"expression"` // CEL, evaluating to a bool
+	Action      string         `db:"action"`     // "allow", "challenge", "deny" or "tag"
+	Tags        pq.StringArray `db:"tags"`       // Added to the outcome when the action is "tag"
+	Shadow      bool           `db:"shadow"`     // Only log what the rule would have done
+	Enabled     bool           `db:"enabled"`
+	Priority    int            `db:"priority"` // Lower runs first
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
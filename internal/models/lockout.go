@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// LoginFailures tracks consecutive failed logins of one account.
+type LoginFailures struct {
+	UserID       string     `db:"user_id"`
+	FailedCount  int        `db:"failed_count"`
+	LastFailedAt time.Time  `db:"last_failed_at"`
+	LockedUntil  *time.Time `db:"locked_until"`
+}
+
+// LoginAttempt is an attempt reserved against an account's failures, to be
+// taken back if it turns out not to be a failure.
+type LoginAttempt struct {
+	UserID     string     `db:"user_id"`
+	ReservedAt time.Time  `db:"last_failed_at"`     // The failure time the reservation stamped
+	PreviousAt *time.Time `db:"previous_failed_at"` // The failure time it replaced, nil if none
+}
+
+// IsLocked reports whether the account is locked at time t.
+func (f *LoginFailures) IsLocked(t time.Time) bool {
+	return f.LockedUntil != nil && t.Before(*f.LockedUntil)
+}
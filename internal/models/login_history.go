@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// LoginRecord is one successful interactive login, kept so that later logins
+// can be compared against the user's habits.
+type LoginRecord struct {
+	ID         string    `db:"id"`
+	UserID     string    `db:"user_id"`
+	IP         string    `db:"ip"`
+	UserAgent  string    `db:"user_agent"`
+	RiskScore  *int      `db:"risk_score"` // nil when the login was not scored, e.g. a passkey login
+	Country    string    `db:"country"`    // ISO code; "" when the IP could not be located
+	City       string    `db:"city"`
+	Latitude   *float64  `db:"latitude"` // nil when the IP could not be located
+	Longitude  *float64  `db:"longitude"`
+	AccuracyKm int       `db:"accuracy_km"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// IsLocated reports whether the login's IP was found in the GeoIP database.
+func (r *LoginRecord) IsLocated() bool {
+	return r.Latitude != nil && r.Longitude != nil
+}
+
+// LoginRisk is the risk assessment of the login a grant started with,
+// carried along so that every token of the grant can report it.
+type LoginRisk struct {
+	RiskScore   *int           `db:"risk_score"` // nil when the login was not scored
+	RiskReasons pq.StringArray `db:"risk_reasons"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Authentication method references (RFC 8176), recorded in the "amr" claim.
+const (
+	AMRPassword = "pwd" // Password
+	AMROTP      = "otp" // One-time password: TOTP or a recovery code
+	AMRMFA      = "mfa" // More than one factor was used
+)
+
+// UserTOTP is a user's TOTP enrollment. Secret is encrypted at rest.
+type UserTOTP struct {
+	UserID       string     `db:"user_id"`
+	Secret       []byte     `db:"secret"`
+	EnabledAt    *time.Time `db:"enabled_at"` // nil until enrollment is confirmed
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// IsEnabled reports whether the enrollment was confirmed and is enforced at login.
+func (t *UserTOTP) IsEnabled() bool {
+	return t.EnabledAt != nil
+}
+
+// MFAChallenge is a pending second-factor step of a password login.
+type MFAChallenge struct {
+	ID         string     `db:"id"`
+	UserID     string     `db:"user_id"`
+	TokenHash  string     `db:"token_hash"`
+	Attempts   int        `db:"attempts"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	ConsumedAt *time.Time `db:"consumed_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+
+	LoginRisk
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OAuth client types (RFC 6749 section 2.1)
+const (
+	ClientTypePublic       = "public"
+	ClientTypeConfidential = "confidential"
+)
+
+// OAuth grant types
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+type OAuthClient struct {
+	ID              string         `db:"id"`
+	Name            string         `db:"name"`
+	ClientType      string         `db:"client_type"`
+	SecretHash      *string        `db:"secret_hash"` // nil for public clients
+	SecretRotatedAt *time.Time     `db:"secret_rotated_at"`
+	RedirectURIs    pq.StringArray `db:"redirect_uris"`
+	Scopes          pq.StringArray `db:"scopes"`
+	GrantTypes      pq.StringArray `db:"grant_types"`
+	DisabledAt      *time.Time     `db:"disabled_at"`
+	CreatedAt       time.Time      `db:"created_at"`
+	UpdatedAt       time.Time      `db:"updated_at"`
+}
+
+// IsConfidential reports whether the client authenticates with a secret.
+func (c *OAuthClient) IsConfidential() bool {
+	return c.ClientType == ClientTypeConfidential
+}
+
+// AllowsGrant reports whether the client may use the given grant type.
+func (c *OAuthClient) AllowsGrant(grant string) bool {
+	for _, g := range c.GrantTypes {
+		if g == grant {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRedirectURI reports whether uri exactly matches a registered redirect URI.
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScope reports whether the client may request scope.
+func (c *OAuthClient) AllowsScope(scope string) bool {
+	for _, allowed := range c.Scopes {
+		if allowed == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type AuthorizationCode struct {
+	CodeHash      string         `db:"code_hash"`
+	ClientID      string         `db:"client_id"`
+	UserID        string         `db:"user_id"`
+	RedirectURI   string         `db:"redirect_uri"`
+	Scope         string         `db:"scope"`
+	Nonce         string         `db:"nonce"`
+	CodeChallenge string         `db:"code_challenge"`
+	AMR           pq.StringArray `db:"amr"`
+	FamilyID      *string        `db:"family_id"`
+	ExpiresAt     time.Time      `db:"expires_at"`
+	UsedAt        *time.Time     `db:"used_at"`
+	CreatedAt     time.Time      `db:"created_at"`
+
+	LoginRisk
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type RefreshToken struct {
+	ID        string         `db:"id"`
+	UserID    string         `db:"user_id"`
+	FamilyID  string         `db:"family_id"`
+	ParentID  *string        `db:"parent_id"`
+	ClientID  *string        `db:"client_id"` // nil for first-party logins
+	Scope     string         `db:"scope"`
+	AMR       pq.StringArray `db:"amr"`       // Authentication methods of the original login
+	AuthTime  time.Time      `db:"auth_time"` // When the original login happened
+	TokenHash string         `db:"token_hash"`
+	ExpiresAt time.Time      `db:"expires_at"`
+	UsedAt    *time.Time     `db:"used_at"`
+	RevokedAt *time.Time     `db:"revoked_at"`
+	CreatedAt time.Time      `db:"created_at"`
+
+	LoginRisk
+}
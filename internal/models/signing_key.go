@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SigningKey is the lifecycle metadata of a managed token signing key.
+type SigningKey struct {
+	KID         string     `db:"kid" json:"kid"`
+	Algorithm   string     `db:"algorithm" json:"algorithm"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	ActivatedAt *time.Time `db:"activated_at" json:"activated_at,omitempty"`
+	RetiredAt   *time.Time `db:"retired_at" json:"retired_at,omitempty"`
+	VerifyUntil *time.Time `db:"verify_until" json:"verify_until,omitempty"`
+
+	// Sealed PEM private key; nil when the key lives in AUTH_JWT_KEYS_DIR
+	PrivateKey []byte `db:"private_key" json:"-"`
+}
+
+// IsActive reports whether this key currently signs new tokens.
+func (k *SigningKey) IsActive() bool {
+	return k.ActivatedAt != nil && k.RetiredAt == nil
+}
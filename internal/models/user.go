@@ -2,10 +2,45 @@ package models
 
 import "time"
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID        string    `db:"id"`
-	Email     string    `db:"email"`
-	Password  string    `db:"password"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID              string     `db:"id"`
+	Email           string     `db:"email"`
+	Password        string     `db:"password"`
+	Role            string     `db:"role"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at"` // nil until the user follows the verification link
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+}
+
+// IsEmailVerified reports whether the user proved ownership of their email address.
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// EmailVerificationToken records a verification link that was sent, so it
+// can be redeemed only once. ID is the jti of the signed token.
+type EmailVerificationToken struct {
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	Email     string     `db:"email"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"` // Also set when a newer link supersedes this one
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// PasswordResetToken is an outstanding forgot-password link. Only the
+// SHA-256 hash of the emailed token is stored.
+type PasswordResetToken struct {
+	ID        string     `db:"id"`
+	UserID    string     `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"` // Also set when a newer link supersedes this one
+	CreatedAt time.Time  `db:"created_at"`
 }
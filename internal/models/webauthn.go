@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebAuthn ceremonies
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+)
+
+// AMRHardwareKey records proof of possession of a hardware-bound key (RFC 8176).
+const AMRHardwareKey = "hwk"
+
+// WebAuthnCredential is a registered security key or passkey.
+type WebAuthnCredential struct {
+	ID              string         `db:"id"`
+	UserID          string         `db:"user_id"`
+	CredentialID    []byte         `db:"credential_id"`
+	Name            string         `db:"name"`
+	PublicKey       []byte         `db:"public_key"`
+	AttestationType string         `db:"attestation_type"`
+	Transports      pq.StringArray `db:"transports"`
+	AAGUID          []byte         `db:"aaguid"`
+	Attachment      string         `db:"attachment"`
+	SignCount       int64          `db:"sign_count"`
+	UserVerified    bool           `db:"user_verified"`
+	BackupEligible  bool           `db:"backup_eligible"` // Synced passkey
+	BackupState     bool           `db:"backup_state"`
+	CloneDetectedAt *time.Time     `db:"clone_detected_at"`
+	LastUsedAt      *time.Time     `db:"last_used_at"`
+	CreatedAt       time.Time      `db:"created_at"`
+}
+
+// WebAuthnSession is the server side of an in-flight ceremony.
+type WebAuthnSession struct {
+	ID        string    `db:"id"`
+	TokenHash string    `db:"token_hash"`
+	UserID    *string   `db:"user_id"` // nil for passwordless logins
+	Ceremony  string    `db:"ceremony"`
+	Data      []byte    `db:"data"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
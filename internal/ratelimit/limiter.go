@@ -0,0 +1,123 @@
+// Package ratelimit decides whether a request may proceed under a rate
+// limit. Limits are enforced in-process (MemoryLimiter, for a single
+// instance) or in a Redis-compatible server shared by every replica
+// (RedisLimiter). Both implement the same two algorithms with the same math,
+// so switching backends does not change behaviour.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Algorithm selects how a Rule is enforced.
+type Algorithm string
+
+const (
+	// TokenBucket allows bursts of up to Limit requests and refills at
+	// Limit per Window.
+	TokenBucket Algorithm = "token_bucket"
+
+	// SlidingWindow allows Limit requests in any Window-long period. It
+	// weights the previous fixed window by how much of it still overlaps,
+	// which approximates a true sliding log in constant memory.
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+// ParseAlgorithm validates an algorithm name from configuration.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch Algorithm(name) {
+	case TokenBucket, SlidingWindow:
+		return Algorithm(name), nil
+	default:
+		return "", fmt.Errorf("unknown rate limit algorithm %q", name)
+	}
+}
+
+// Rule is one rate limit. Name namespaces its counters, so different rules
+// never share state even for the same key.
+type Rule struct {
+	Name      string
+	Algorithm Algorithm
+	Limit     int
+	Window    time.Duration
+}
+
+// Result is the outcome of one Allow call.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // Wait before the next request can succeed; zero when allowed
+	Reset      time.Duration // Time until the full quota is available again
+}
+
+// Limiter consumes one unit of a rule's quota for key, if there is one left.
+type Limiter interface {
+	Allow(ctx context.Context, rule Rule, key string) (Result, error)
+}
+
+// bucketResult builds the result of a token bucket holding tokens after the
+// request was (or was not) admitted.
+func bucketResult(rule Rule, tokens float64, allowed bool) Result {
+	perToken := rule.Window / time.Duration(rule.Limit)
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(rule.Limit) - tokens) * float64(perToken)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	return result
+}
+
+// refill returns the tokens in a bucket that held tokens elapsed ago.
+func refill(rule Rule, tokens float64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return tokens
+	}
+	added := float64(elapsed) / float64(rule.Window) * float64(rule.Limit)
+	return math.Min(float64(rule.Limit), tokens+added)
+}
+
+// slidingEstimate is the weighted request count of the sliding window that
+// ends elapsed into the current fixed window.
+func slidingEstimate(rule Rule, elapsed time.Duration, current, previous int) float64 {
+	overlap := float64(rule.Window-elapsed) / float64(rule.Window)
+	return float64(previous)*overlap + float64(current)
+}
+
+// slidingResult builds the result of a sliding window check. current
+// includes the request when it was admitted.
+func slidingResult(rule Rule, elapsed time.Duration, current, previous int, allowed bool) Result {
+	estimate := slidingEstimate(rule, elapsed, current, previous)
+	untilNextWindow := rule.Window - elapsed
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: max(rule.Limit-int(math.Ceil(estimate)), 0),
+		Reset:     untilNextWindow + rule.Window,
+	}
+	if current == 0 && previous == 0 {
+		result.Reset = 0
+	}
+	if allowed {
+		return result
+	}
+
+	// The previous window's weight shrinks as time passes; find when the
+	// estimate leaves room for one more request. If the current window alone
+	// is full, nothing frees up before the next window starts.
+	result.RetryAfter = untilNextWindow
+	if previous > 0 && current < rule.Limit {
+		needed := float64(rule.Window) * (1 - float64(rule.Limit-current-1)/float64(previous))
+		result.RetryAfter = min(max(time.Duration(needed)-elapsed, time.Millisecond), untilNextWindow)
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// epoch is on a minute boundary, so sliding windows start with it.
+var epoch = time.Unix(1_700_000_040, 0)
+
+// step is one request made at epoch+at and what the limiter should answer.
+type step struct {
+	at         time.Duration
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration
+}
+
+// clock is a settable time source for the limiters.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newMemory(t *testing.T, c *clock) Limiter {
+	l := NewMemoryLimiter()
+	l.now = c.now
+	return l
+}
+
+func newRedis(t *testing.T, c *clock) Limiter {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	l := NewRedisLimiter(client)
+	l.now = c.now
+	return l
+}
+
+func TestLimiters(t *testing.T) {
+	backends := []struct {
+		name string
+		new  func(*testing.T, *clock) Limiter
+	}{
+		{"memory", newMemory},
+		{"redis", newRedis},
+	}
+
+	tests := []struct {
+		name  string
+		rule  Rule
+		steps []step
+	}{
+		{
+			name: "token bucket bursts then refills one token per Window/Limit",
+			rule: Rule{Name: "tb", Algorithm: TokenBucket, Limit: 3, Window: time.Minute},
+			steps: []step{
+				{at: 0, allowed: true, remaining: 2},
+				{at: 0, allowed: true, remaining: 1},
+				{at: 0, allowed: true, remaining: 0},
+				{at: 0, allowed: false, remaining: 0, retryAfter: 20 * time.Second},
+				{at: 10 * time.Second, allowed: false, remaining: 0, retryAfter: 10 * time.Second},
+				{at: 20 * time.Second, allowed: true, remaining: 0},
+				{at: 3 * time.Minute, allowed: true, remaining: 2},
+			},
+		},
+		{
+			name: "sliding window weighs the previous window by its overlap",
+			rule: Rule{Name: "sw", Algorithm: SlidingWindow, Limit: 3, Window: time.Minute},
+			steps: []step{
+				{at: 0, allowed: true, remaining: 2},
+				{at: 10 * time.Second, allowed: true, remaining: 1},
+				{at: 20 * time.Second, allowed: true, remaining: 0},
+				// The current window alone is full: wait for the next one
+				{at: 30 * time.Second, allowed: false, remaining: 0, retryAfter: 30 * time.Second},
+				// All three still count fully at the start of the next window,
+				// and one of them has dropped out a third of the way in
+				{at: time.Minute, allowed: false, remaining: 0, retryAfter: 20 * time.Second},
+				{at: time.Minute + 20*time.Second, allowed: true, remaining: 0},
+				{at: 5 * time.Minute, allowed: true, remaining: 2},
+			},
+		},
+	}
+
+	for _, backend := range backends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				c := &clock{}
+				limiter := backend.new(t, c)
+
+				for i, s := range tt.steps {
+					c.t = epoch.Add(s.at)
+					result, err := limiter.Allow(context.Background(), tt.rule, "ip:192.0.2.1")
+					if err != nil {
+						t.Fatalf("step %d: %v", i, err)
+					}
+					if result.Allowed != s.allowed || result.Remaining != s.remaining || result.Limit != tt.rule.Limit {
+						t.Errorf("step %d: got allowed=%v remaining=%d limit=%d, want allowed=%v remaining=%d limit=%d",
+							i, result.Allowed, result.Remaining, result.Limit, s.allowed, s.remaining, tt.rule.Limit)
+					}
+					if result.RetryAfter != s.retryAfter {
+						t.Errorf("step %d: got retry after %s, want %s", i, result.RetryAfter, s.retryAfter)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLimitersKeepKeysAndRulesApart(t *testing.T) {
+	for name, newLimiter := range map[string]func(*testing.T, *clock) Limiter{"memory": newMemory, "redis": newRedis} {
+		t.Run(name, func(t *testing.T) {
+			c := &clock{t: epoch}
+			limiter := newLimiter(t, c)
+			ctx := context.Background()
+
+			perIP := Rule{Name: "ip", Algorithm: TokenBucket, Limit: 1, Window: time.Minute}
+			perEmail := Rule{Name: "email", Algorithm: TokenBucket, Limit: 1, Window: time.Minute}
+
+			for _, call := range []struct {
+				rule    Rule
+				key     string
+				allowed bool
+			}{
+				{perIP, "a", true},
+				{perIP, "a", false},
+				{perIP, "b", true},
+				{perEmail, "a", true},
+			} {
+				result, err := limiter.Allow(ctx, call.rule, call.key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Allowed != call.allowed {
+					t.Errorf("%s %s: got allowed=%v, want %v", call.rule.Name, call.key, result.Allowed, call.allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	for _, name := range []string{"token_bucket", "sliding_window"} {
+		if _, err := ParseAlgorithm(name); err != nil {
+			t.Errorf("ParseAlgorithm(%q): %v", name, err)
+		}
+	}
+	if _, err := ParseAlgorithm("leaky_bucket"); err == nil {
+		t.Error("ParseAlgorithm accepted an unknown algorithm")
+	}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle counters are dropped from memory.
+const sweepInterval = time.Minute
+
+type bucketState struct {
+	tokens float64
+	last   time.Time
+}
+
+type windowState struct {
+	index    int64 // Fixed window number: unix time / Window
+	current  int
+	previous int
+}
+
+type memoryEntry struct {
+	bucket  bucketState
+	window  windowState
+	expires time.Time // Idle until then, the state could still matter
+}
+
+// MemoryLimiter keeps counters in process memory. Each replica enforces
+// its own limits, so use RedisLimiter when running more than one.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, rule Rule, key string) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	id := rule.Name + ":" + key
+	entry, ok := l.entries[id]
+	if !ok {
+		entry = &memoryEntry{bucket: bucketState{tokens: float64(rule.Limit), last: now}}
+		l.entries[id] = entry
+	}
+	entry.expires = now.Add(2 * rule.Window)
+
+	if rule.Algorithm == TokenBucket {
+		return l.allowBucket(rule, &entry.bucket, now), nil
+	}
+	return l.allowWindow(rule, &entry.window, now), nil
+}
+
+func (l *MemoryLimiter) allowBucket(rule Rule, state *bucketState, now time.Time) Result {
+	state.tokens = refill(rule, state.tokens, now.Sub(state.last))
+	state.last = now
+
+	allowed := state.tokens >= 1
+	if allowed {
+		state.tokens--
+	}
+	return bucketResult(rule, state.tokens, allowed)
+}
+
+func (l *MemoryLimiter) allowWindow(rule Rule, state *windowState, now time.Time) Result {
+	index := now.UnixNano() / int64(rule.Window)
+	elapsed := time.Duration(now.UnixNano() - index*int64(rule.Window))
+
+	switch index - state.index {
+	case 0:
+	case 1:
+		state.previous, state.current = state.current, 0
+	default:
+		state.previous, state.current = 0, 0
+	}
+	state.index = index
+
+	allowed := slidingEstimate(rule, elapsed, state.current, state.previous)+1 <= float64(rule.Limit)
+	if allowed {
+		state.current++
+	}
+	return slidingResult(rule, elapsed, state.current, state.previous, allowed)
+}
+
+// sweep drops counters that have been idle long enough to be back at a full
+// quota, so memory stays bounded by the number of recently active keys.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for id, entry := range l.entries {
+		if now.After(entry.expires) {
+			delete(l.entries, id)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces every counter this package writes.
+const keyPrefix = "ratelimit:"
+
+// bucketScript refills and takes from a token bucket stored as a hash.
+// Tokens are returned as a string because Lua numbers are truncated to
+// integers on the way back to the client.
+var bucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) / window * capacity)
+	ts = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {allowed, tostring(tokens)}
+`)
+
+// windowScript checks the weighted count of the current and previous fixed
+// windows and counts the request if it fits.
+var windowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+if previous * (window - elapsed) / window + current + 1 > limit then
+	return {0, current, previous}
+end
+
+current = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {1, current, previous}
+`)
+
+// RedisLimiter keeps counters in a Redis-compatible server so every replica
+// shares the same limits. Each check is a single Lua script, which keeps it
+// atomic under concurrency. Keys use a hash tag, so the keys of one counter
+// land in the same Redis Cluster slot.
+type RedisLimiter struct {
+	client redis.Scripter
+	now    func() time.Time
+}
+
+func NewRedisLimiter(client redis.Scripter) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, rule Rule, key string) (Result, error) {
+	tag := "{" + rule.Name + ":" + key + "}"
+	now := l.now()
+
+	if rule.Algorithm == TokenBucket {
+		return l.allowBucket(ctx, rule, keyPrefix+"tb:"+tag, now)
+	}
+	return l.allowWindow(ctx, rule, keyPrefix+"sw:"+tag, now)
+}
+
+func (l *RedisLimiter) allowBucket(ctx context.Context, rule Rule, key string, now time.Time) (Result, error) {
+	reply, err := bucketScript.Run(ctx, l.client, []string{key},
+		rule.Limit, rule.Window.Milliseconds(), now.UnixMilli(),
+	).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected token bucket reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	raw, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected token bucket reply %v", reply)
+	}
+
+	return bucketResult(rule, tokens, allowed == 1), nil
+}
+
+func (l *RedisLimiter) allowWindow(ctx context.Context, rule Rule, key string, now time.Time) (Result, error) {
+	window := rule.Window.Milliseconds()
+	index := now.UnixMilli() / window
+	elapsed := now.UnixMilli() - index*window
+
+	current := key + ":" + strconv.FormatInt(index, 10)
+	previous := key + ":" + strconv.FormatInt(index-1, 10)
+
+	reply, err := windowScript.Run(ctx, l.client, []string{current, previous},
+		rule.Limit, window, elapsed,
+	).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 3 {
+		return Result{}, fmt.Errorf("unexpected sliding window reply %v", reply)
+	}
+
+	elapsedDuration := time.Duration(elapsed) * time.Millisecond
+	return slidingResult(rule, elapsedDuration, int(reply[1]), int(reply[2]), reply[0] == 1), nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditRepository is an append-only log of security events.
+type AuditRepository interface {
+	// Record stores an event. ID and CreatedAt are populated on success.
+	Record(event *models.AuditEvent) error
+}
+
+type PostgresAuditRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresAuditRepository(db *sqlx.DB, log *logger.Logger) AuditRepository {
+	return &PostgresAuditRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresAuditRepository) Record(event *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (event, user_id, actor_id, detail)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		event.Event,
+		event.UserID,
+		event.ActorID,
+		event.Detail,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		r.log.Error("Failed to record audit event " + event.Event + ": " + err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// DeviceRepository keeps the devices users log in from.
+type DeviceRepository interface {
+	// Get finds one of the user's devices by its hash.
+	// Returns sql.ErrNoRows if the user never logged in from it.
+	Get(userID, deviceHash string) (*models.UserDevice, error)
+
+	// Count returns how many devices the user has.
+	Count(userID string) (int, error)
+
+	// Touch records a login from a device, creating it on first sight, and
+	// fills in the stored fields. It returns true if the device is new.
+	Touch(device *models.UserDevice) (bool, error)
+
+	// GetTrusted finds the user's device that was issued the trust token
+	// with the given hash. Returns sql.ErrNoRows if there is none.
+	GetTrusted(userID, tokenHash string) (*models.UserDevice, error)
+
+	// Trust lets a device skip the second factor until the given time when
+	// a login presents the token with the given hash. It replaces any token
+	// the device had. It returns false if the user has no such device.
+	Trust(userID, deviceHash, tokenHash string, until time.Time) (bool, error)
+
+	// ListByUser returns the user's devices, most recently seen first.
+	ListByUser(userID string) ([]models.UserDevice, error)
+
+	// Delete forgets one of the user's devices.
+	// It returns false if the user has no device with that ID.
+	Delete(userID, id string) (bool, error)
+}
+
+type PostgresDeviceRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresDeviceRepository(db *sqlx.DB, log *logger.Logger) DeviceRepository {
+	return &PostgresDeviceRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+const deviceColumns = `id, user_id, device_hash, name, user_agent, last_ip, first_seen_at, last_seen_at, trusted_until, trust_token_hash`
+
+func (r *PostgresDeviceRepository) Get(userID, deviceHash string) (*models.UserDevice, error) {
+	var device models.UserDevice
+
+	query := `SELECT ` + deviceColumns + ` FROM user_devices WHERE user_id = $1 AND device_hash = $2`
+
+	if err := r.db.Get(&device, query, userID, deviceHash); err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+func (r *PostgresDeviceRepository) GetTrusted(userID, tokenHash string) (*models.UserDevice, error) {
+	var device models.UserDevice
+
+	query := `SELECT ` + deviceColumns + ` FROM user_devices WHERE user_id = $1 AND trust_token_hash = $2`
+
+	if err := r.db.Get(&device, query, userID, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+func (r *PostgresDeviceRepository) Count(userID string) (int, error) {
+	var count int
+
+	if err := r.db.Get(&count, `SELECT COUNT(*) FROM user_devices WHERE user_id = $1`, userID); err != nil {
+		r.log.Error("Failed to count devices: " + err.Error())
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *PostgresDeviceRepository) Touch(device *models.UserDevice) (bool, error) {
+	var created bool
+
+	// xmax is 0 only for a freshly inserted row
+	query := `
+		INSERT INTO user_devices (user_id, device_hash, name, user_agent, last_ip)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, device_hash) DO UPDATE SET
+			name = EXCLUDED.name,
+			user_agent = EXCLUDED.user_agent,
+			last_ip = EXCLUDED.last_ip,
+			last_seen_at = NOW()
+		RETURNING id, first_seen_at, last_seen_at, trusted_until, trust_token_hash, (xmax = 0) AS created
+	`
+
+	err := r.db.QueryRow(
+		query,
+		device.UserID,
+		device.DeviceHash,
+		device.Name,
+		device.UserAgent,
+		device.LastIP,
+	).Scan(&device.ID, &device.FirstSeenAt, &device.LastSeenAt, &device.TrustedUntil, &device.TrustTokenHash, &created)
+	if err != nil {
+		r.log.Error("Failed to record device: " + err.Error())
+		return false, err
+	}
+
+	return created, nil
+}
+
+func (r *PostgresDeviceRepository) Trust(userID, deviceHash, tokenHash string, until time.Time) (bool, error) {
+	query := `
+		UPDATE user_devices
+		SET trusted_until = $4, trust_token_hash = $3
+		WHERE user_id = $1 AND device_hash = $2
+	`
+
+	result, err := r.db.Exec(query, userID, deviceHash, tokenHash, until)
+	if err != nil {
+		r.log.Error("Failed to trust device: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *PostgresDeviceRepository) ListByUser(userID string) ([]models.UserDevice, error) {
+	devices := []models.UserDevice{}
+
+	query := `SELECT ` + deviceColumns + ` FROM user_devices WHERE user_id = $1 ORDER BY last_seen_at DESC`
+
+	if err := r.db.Select(&devices, query, userID); err != nil {
+		r.log.Error("Failed to list devices: " + err.Error())
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+func (r *PostgresDeviceRepository) Delete(userID, id string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM user_devices WHERE user_id = $1 AND id::text = $2`, userID, id)
+	if err != nil {
+		r.log.Error("Failed to delete device: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// EmailVerificationRepository tracks the verification links sent to users.
+type EmailVerificationRepository interface {
+	// Create records a newly sent link and supersedes (marks used) every
+	// earlier link of the same user, so only the latest one works.
+	// CreatedAt is populated on success.
+	Create(token *models.EmailVerificationToken) error
+
+	// Use marks a link as redeemed. It returns false if the link does not
+	// exist, belongs to another user or was already used or superseded.
+	Use(id, userID string) (bool, error)
+
+	// CountSince returns how many links were sent to a user after since.
+	CountSince(userID string, since time.Time) (int, error)
+}
+
+type PostgresEmailVerificationRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresEmailVerificationRepository(db *sqlx.DB, log *logger.Logger) EmailVerificationRepository {
+	return &PostgresEmailVerificationRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresEmailVerificationRepository) Create(token *models.EmailVerificationToken) error {
+	query := `
+		WITH superseded AS (
+			UPDATE email_verification_tokens
+			SET used_at = NOW()
+			WHERE user_id = $2 AND used_at IS NULL
+		)
+		INSERT INTO email_verification_tokens (id, user_id, email, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		token.ID,
+		token.UserID,
+		token.Email,
+		token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+	if err != nil {
+		r.log.Error("Failed to store email verification token: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresEmailVerificationRepository) Use(id, userID string) (bool, error) {
+	query := `
+		UPDATE email_verification_tokens
+		SET used_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		r.log.Error("Failed to use email verification token: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *PostgresEmailVerificationRepository) CountSince(userID string, since time.Time) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(*)
+		FROM email_verification_tokens
+		WHERE user_id = $1 AND created_at > $2
+	`
+
+	if err := r.db.Get(&count, query, userID, since); err != nil {
+		r.log.Error("Failed to count email verification tokens: " + err.Error())
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// FraudRuleRepository reads the fraud rules analysts maintain in the
+// fraud_rules table. It is a rules.Source.
+type FraudRuleRepository interface {
+	// List returns the enabled rules in the order they run.
+	List() ([]models.FraudRule, error)
+
+	// Version returns a digest of the whole table, which changes whenever a
+	// rule is added, edited or deleted.
+	Version() (string, error)
+}
+
+type PostgresFraudRuleRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresFraudRuleRepository(db *sqlx.DB, log *logger.Logger) FraudRuleRepository {
+	return &PostgresFraudRuleRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresFraudRuleRepository) List() ([]models.FraudRule, error) {
+	rules := []models.FraudRule{}
+
+	query := `
+		SELECT id, name, description, event, expression, action, tags, shadow,
+			enabled, priority, created_at, updated_at
+		FROM fraud_rules
+		WHERE enabled
+		ORDER BY priority, name
+	`
+
+	if err := r.db.Select(&rules, query); err != nil {
+		r.log.Error("Failed to list fraud rules: " + err.Error())
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (r *PostgresFraudRuleRepository) Version() (string, error) {
+	var version string
+
+	// Rows are hashed whole, so edits count even if updated_at is left alone
+	query := `
+		SELECT md5(COALESCE(string_agg(r::text, ',' ORDER BY r.id), ''))
+		FROM fraud_rules r
+	`
+
+	if err := r.db.Get(&version, query); err != nil {
+		r.log.Error("Failed to read fraud rules version: " + err.Error())
+		return "", err
+	}
+
+	return version, nil
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// LoginFailureRepository counts consecutive failed logins per account.
+type LoginFailureRepository interface {
+	// Get returns the user's outstanding failures.
+	// Returns sql.ErrNoRows if the user has none.
+	Get(userID string) (*models.LoginFailures, error)
+
+	// ReserveAttempt claims a login attempt, counted as a failure until
+	// ReleaseAttempt takes it back. The claim
+	// and the checks it passes are one statement, so concurrent attempts
+	// cannot all slip through. It returns false, and counts nothing, while
+	// the account is locked or the last attempt was less than the backoff
+	// ago: none below free failures, then base doubling with each further
+	// failure up to max. The count starts over when the previous failure
+	// happened before resetBefore or when an earlier lock has expired.
+	ReserveAttempt(userID string, resetBefore time.Time, free int, base, max time.Duration) (*models.LoginAttempt, bool, error)
+
+	// ReleaseAttempt takes back a reserved attempt that turned out not to
+	// be a failure, and the failure time it stamped unless a later attempt
+	// has stamped its own since.
+	ReleaseAttempt(attempt *models.LoginAttempt) error
+
+	// Lock locks the account until the given time. It returns false if the
+	// account was already locked, so concurrent failures lock it only once.
+	Lock(userID string, until time.Time) (bool, error)
+
+	// Clear forgets the user's failures and lifts any lock.
+	// It returns false if there was nothing to clear.
+	Clear(userID string) (bool, error)
+}
+
+type PostgresLoginFailureRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresLoginFailureRepository(db *sqlx.DB, log *logger.Logger) LoginFailureRepository {
+	return &PostgresLoginFailureRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresLoginFailureRepository) Get(userID string) (*models.LoginFailures, error) {
+	var failures models.LoginFailures
+
+	query := `
+		SELECT user_id, failed_count, last_failed_at, locked_until
+		FROM login_failures
+		WHERE user_id = $1
+	`
+
+	if err := r.db.Get(&failures, query, userID); err != nil {
+		return nil, err
+	}
+
+	return &failures, nil
+}
+
+func (r *PostgresLoginFailureRepository) ReserveAttempt(userID string, resetBefore time.Time, free int, base, max time.Duration) (*models.LoginAttempt, bool, error) {
+	var attempt models.LoginAttempt
+
+	// The WHERE clause of the upsert is evaluated on the locked row, so of
+	// several concurrent attempts only those the backoff allows are counted.
+	// The exponent is capped to keep power() in range. previous reads the
+	// row as it was before the upsert, for ReleaseAttempt to restore.
+	query := `
+		WITH previous AS (
+			SELECT last_failed_at FROM login_failures WHERE user_id = $1
+		), reserved AS (
+			INSERT INTO login_failures (user_id, failed_count, last_failed_at)
+			VALUES ($1, 1, NOW())
+			ON CONFLICT (user_id) DO UPDATE SET
+				failed_count = CASE
+					WHEN login_failures.last_failed_at < $2 OR login_failures.locked_until <= NOW() THEN 1
+					ELSE login_failures.failed_count + 1
+				END,
+				locked_until = CASE
+					WHEN login_failures.locked_until <= NOW() THEN NULL
+					ELSE login_failures.locked_until
+				END,
+				last_failed_at = NOW()
+			WHERE (login_failures.locked_until IS NULL OR login_failures.locked_until <= NOW())
+			  AND (login_failures.last_failed_at < $2
+				OR login_failures.locked_until <= NOW()
+				OR login_failures.failed_count < $3
+				OR login_failures.last_failed_at + make_interval(secs =>
+					LEAST($4 * power(2, LEAST(login_failures.failed_count - $3, 30)), $5)) <= NOW())
+			RETURNING user_id, last_failed_at
+		)
+		SELECT reserved.user_id, reserved.last_failed_at, (SELECT last_failed_at FROM previous) AS previous_failed_at
+		FROM reserved
+	`
+
+	err := r.db.Get(&attempt, query, userID, resetBefore, free, base.Seconds(), max.Seconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		r.log.Error("Failed to reserve login attempt: " + err.Error())
+		return nil, false, err
+	}
+
+	return &attempt, true, nil
+}
+
+func (r *PostgresLoginFailureRepository) ReleaseAttempt(attempt *models.LoginAttempt) error {
+	// The row goes away with its last failure, unless the account is locked.
+	// Otherwise the backoff runs from the failure before the attempt again.
+	query := `
+		WITH released AS (
+			DELETE FROM login_failures
+			WHERE user_id = $1 AND failed_count <= 1 AND locked_until IS NULL
+		)
+		UPDATE login_failures
+		SET failed_count = failed_count - 1,
+			last_failed_at = CASE
+				WHEN last_failed_at = $2 THEN COALESCE($3, last_failed_at)
+				ELSE last_failed_at
+			END
+		WHERE user_id = $1 AND failed_count > 1
+	`
+
+	if _, err := r.db.Exec(query, attempt.UserID, attempt.ReservedAt, attempt.PreviousAt); err != nil {
+		r.log.Error("Failed to release login attempt: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresLoginFailureRepository) Lock(userID string, until time.Time) (bool, error) {
+	query := `
+		UPDATE login_failures
+		SET locked_until = $2
+		WHERE user_id = $1 AND (locked_until IS NULL OR locked_until <= NOW())
+	`
+
+	result, err := r.db.Exec(query, userID, until)
+	if err != nil {
+		r.log.Error("Failed to lock account: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *PostgresLoginFailureRepository) Clear(userID string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM login_failures WHERE user_id = $1`, userID)
+	if err != nil {
+		r.log.Error("Failed to clear login failures: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
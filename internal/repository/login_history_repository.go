@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// LoginHistoryRepository keeps the successful logins of each user.
+type LoginHistoryRepository interface {
+	// Record stores a login. ID and CreatedAt are populated on success.
+	Record(record *models.LoginRecord) error
+
+	// Recent returns up to limit of the user's latest logins, newest first.
+	Recent(userID string, limit int) ([]models.LoginRecord, error)
+}
+
+type PostgresLoginHistoryRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresLoginHistoryRepository(db *sqlx.DB, log *logger.Logger) LoginHistoryRepository {
+	return &PostgresLoginHistoryRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresLoginHistoryRepository) Record(record *models.LoginRecord) error {
+	query := `
+		INSERT INTO login_history
+			(user_id, ip, user_agent, risk_score, country, city, latitude, longitude, accuracy_km)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		record.UserID,
+		record.IP,
+		record.UserAgent,
+		record.RiskScore,
+		record.Country,
+		record.City,
+		record.Latitude,
+		record.Longitude,
+		record.AccuracyKm,
+	).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		r.log.Error("Failed to record login: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresLoginHistoryRepository) Recent(userID string, limit int) ([]models.LoginRecord, error) {
+	records := []models.LoginRecord{}
+
+	query := `
+		SELECT id, user_id, ip, user_agent, risk_score, country, city, latitude, longitude, accuracy_km, created_at
+		FROM login_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	if err := r.db.Select(&records, query, userID, limit); err != nil {
+		r.log.Error("Failed to list login history: " + err.Error())
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// MFARepository persists TOTP enrollments, recovery codes and pending
+// login challenges. Recovery codes and challenge tokens are stored by hash.
+type MFARepository interface {
+	// GetTOTP returns the user's TOTP enrollment, confirmed or not.
+	// Returns sql.ErrNoRows if the user never started enrollment.
+	GetTOTP(userID string) (*models.UserTOTP, error)
+
+	// SaveTOTP starts (or restarts) an unconfirmed enrollment with a new secret.
+	// A confirmed enrollment is left untouched; returns false in that case.
+	SaveTOTP(userID string, secret []byte) (bool, error)
+
+	// EnableTOTP confirms the enrollment and replaces the recovery codes
+	// in a single transaction.
+	EnableTOTP(userID string, step int64, recoveryCodeHashes []string) error
+
+	// DeleteTOTP removes the enrollment and all recovery codes.
+	DeleteTOTP(userID string) error
+
+	// UseTOTPStep records step as consumed. Returns false if an equal or
+	// later step was already used, i.e. the code is being replayed.
+	UseTOTPStep(userID string, step int64) (bool, error)
+
+	// ReplaceRecoveryCodes discards the user's recovery codes and stores new ones.
+	ReplaceRecoveryCodes(userID string, codeHashes []string) error
+
+	// UseRecoveryCode atomically consumes an unused recovery code.
+	// Returns false if no unused code matches.
+	UseRecoveryCode(userID, codeHash string) (bool, error)
+
+	// CountRecoveryCodes returns how many unused recovery codes remain.
+	CountRecoveryCodes(userID string) (int, error)
+
+	// CreateChallenge stores a pending second-factor challenge.
+	CreateChallenge(challenge *models.MFAChallenge) error
+
+	// GetChallenge finds a challenge by the hash of its token.
+	// Returns sql.ErrNoRows if no challenge matches.
+	GetChallenge(tokenHash string) (*models.MFAChallenge, error)
+
+	// ClaimChallengeAttempt counts an attempt at answering a challenge and
+	// returns the new total. The attempt is claimed before the proof is
+	// checked, in the same statement that enforces the limit, so concurrent
+	// guesses cannot exceed maxAttempts. Returns false if the challenge is
+	// used up, consumed or expired.
+	ClaimChallengeAttempt(id string, maxAttempts int) (int, bool, error)
+
+	// ConsumeChallenge atomically marks a challenge used.
+	// Returns false if it was already consumed.
+	ConsumeChallenge(id string) (bool, error)
+}
+
+type PostgresMFARepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresMFARepository(db *sqlx.DB, log *logger.Logger) MFARepository {
+	return &PostgresMFARepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresMFARepository) GetTOTP(userID string) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+
+	query := `
+		SELECT user_id, secret, enabled_at, last_used_step, created_at
+		FROM user_totp
+		WHERE user_id=$1
+	`
+
+	if err := r.db.Get(&totp, query, userID); err != nil {
+		return nil, err
+	}
+
+	return &totp, nil
+}
+
+func (r *PostgresMFARepository) SaveTOTP(userID string, secret []byte) (bool, error) {
+	query := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_totp.enabled_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, secret)
+	if err != nil {
+		r.log.Error("Failed to save TOTP enrollment: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *PostgresMFARepository) EnableTOTP(userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_totp
+		SET enabled_at = NOW(), last_used_step = $2
+		WHERE user_id=$1
+	`
+	if _, err := tx.Exec(query, userID, step); err != nil {
+		r.log.Error("Failed to enable TOTP: " + err.Error())
+		return err
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		r.log.Error("Failed to store recovery codes: " + err.Error())
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresMFARepository) DeleteTOTP(userID string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id=$1`, userID); err != nil {
+		r.log.Error("Failed to delete TOTP enrollment: " + err.Error())
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		r.log.Error("Failed to delete recovery codes: " + err.Error())
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresMFARepository) UseTOTPStep(userID string, step int64) (bool, error) {
+	// Compare-and-swap on last_used_step: two requests with the same code
+	// cannot both succeed.
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id=$1 AND last_used_step < $2
+	`
+
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		r.log.Error("Failed to record TOTP step: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *PostgresMFARepository) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		r.log.Error("Failed to replace recovery codes: " + err.Error())
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresMFARepository) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		r.log.Error("Failed to use recovery code: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *PostgresMFARepository) CountRecoveryCodes(userID string) (int, error) {
+	var count int
+
+	query := `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id=$1 AND used_at IS NULL`
+
+	if err := r.db.Get(&count, query, userID); err != nil {
+		r.log.Error("Failed to count recovery codes: " + err.Error())
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *PostgresMFARepository) CreateChallenge(challenge *models.MFAChallenge) error {
+	query := `
+		INSERT INTO mfa_challenges (user_id, token_hash, expires_at, risk_score, risk_reasons)
+		VALUES ($1, $2, $3, $4, COALESCE($5::text[], '{}'))
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		challenge.UserID,
+		challenge.TokenHash,
+		challenge.ExpiresAt,
+		challenge.RiskScore,
+		challenge.RiskReasons,
+	).Scan(&challenge.ID, &challenge.CreatedAt)
+	if err != nil {
+		r.log.Error("Failed to create MFA challenge: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresMFARepository) GetChallenge(tokenHash string) (*models.MFAChallenge, error) {
+	var challenge models.MFAChallenge
+
+	query := `
+		SELECT id, user_id, token_hash, attempts, expires_at, consumed_at, created_at, risk_score, risk_reasons
+		FROM mfa_challenges
+		WHERE token_hash=$1
+	`
+
+	if err := r.db.Get(&challenge, query, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+func (r *PostgresMFARepository) ClaimChallengeAttempt(id string, maxAttempts int) (int, bool, error) {
+	var attempts int
+
+	query := `
+		UPDATE mfa_challenges
+		SET attempts = attempts + 1
+		WHERE id=$1 AND attempts < $2 AND consumed_at IS NULL AND expires_at > NOW()
+		RETURNING attempts
+	`
+
+	err := r.db.Get(&attempts, query, id, maxAttempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		r.log.Error("Failed to record MFA attempt: " + err.Error())
+		return 0, false, err
+	}
+
+	return attempts, true, nil
+}
+
+func (r *PostgresMFARepository) ConsumeChallenge(id string) (bool, error) {
+	query := `
+		UPDATE mfa_challenges
+		SET consumed_at = NOW()
+		WHERE id=$1 AND consumed_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		r.log.Error("Failed to consume MFA challenge: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func replaceRecoveryCodes(tx *sqlx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(
+			`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, hash,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// OAuthClientRepository stores registered OAuth clients.
+type OAuthClientRepository interface {
+	// Create registers a client. CreatedAt and UpdatedAt are populated on success.
+	Create(client *models.OAuthClient) error
+
+	// GetByID finds a client by client_id.
+	// Returns sql.ErrNoRows if the client is not registered.
+	GetByID(id string) (*models.OAuthClient, error)
+
+	// List returns every registered client.
+	List() ([]models.OAuthClient, error)
+
+	// UpdateSecret replaces a confidential client's secret hash.
+	// Returns sql.ErrNoRows if the client does not exist.
+	UpdateSecret(id, secretHash string) error
+
+	// Disable stops a client from obtaining new tokens.
+	// Returns sql.ErrNoRows if the client does not exist.
+	Disable(id string) error
+}
+
+type PostgresOAuthClientRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresOAuthClientRepository(db *sqlx.DB, log *logger.Logger) OAuthClientRepository {
+	return &PostgresOAuthClientRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+const oauthClientColumns = `id, name, client_type, secret_hash, secret_rotated_at, redirect_uris,
+	scopes, grant_types, disabled_at, created_at, updated_at`
+
+func (r *PostgresOAuthClientRepository) Create(client *models.OAuthClient) error {
+	query := `
+		INSERT INTO oauth_clients (id, name, client_type, secret_hash, redirect_uris, scopes, grant_types)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		client.ID,
+		client.Name,
+		client.ClientType,
+		client.SecretHash,
+		client.RedirectURIs,
+		client.Scopes,
+		client.GrantTypes,
+	).Scan(&client.CreatedAt, &client.UpdatedAt)
+	if err != nil {
+		r.log.Error("Failed to create OAuth client: " + err.Error())
+		return err
+	}
+
+	r.log.Info("OAuth client registered: " + client.ID)
+	return nil
+}
+
+func (r *PostgresOAuthClientRepository) GetByID(id string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE id=$1`
+
+	if err := r.db.Get(&client, query, id); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func (r *PostgresOAuthClientRepository) List() ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients ORDER BY created_at`
+
+	if err := r.db.Select(&clients, query); err != nil {
+		r.log.Error("Failed to list OAuth clients: " + err.Error())
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (r *PostgresOAuthClientRepository) UpdateSecret(id, secretHash string) error {
+	query := `
+		UPDATE oauth_clients
+		SET secret_hash = $2, secret_rotated_at = NOW(), updated_at = NOW()
+		WHERE id=$1 AND client_type = 'confidential'
+	`
+
+	return r.execOne(query, id, secretHash)
+}
+
+func (r *PostgresOAuthClientRepository) Disable(id string) error {
+	query := `
+		UPDATE oauth_clients
+		SET disabled_at = COALESCE(disabled_at, NOW()), updated_at = NOW()
+		WHERE id=$1
+	`
+
+	return r.execOne(query, id)
+}
+
+// execOne runs an update that must touch exactly one client row.
+func (r *PostgresOAuthClientRepository) execOne(query string, args ...interface{}) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		r.log.Error("Failed to update OAuth client: " + err.Error())
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// PasswordResetRepository stores forgot-password links by token hash.
+type PasswordResetRepository interface {
+	// Create stores a new link and supersedes (marks used) every earlier
+	// link of the same user. ID and CreatedAt are populated on success.
+	Create(token *models.PasswordResetToken) error
+
+	// Get returns an unused, unexpired link without using it up.
+	// Returns sql.ErrNoRows if no such link exists.
+	Get(tokenHash string) (*models.PasswordResetToken, error)
+
+	// Consume atomically marks an unused, unexpired link as used and returns
+	// it, so each link resets a password at most once.
+	// Returns sql.ErrNoRows if no such link exists.
+	Consume(tokenHash string) (*models.PasswordResetToken, error)
+
+	// CountSince returns how many links were requested for a user after since.
+	CountSince(userID string, since time.Time) (int, error)
+}
+
+type PostgresPasswordResetRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresPasswordResetRepository(db *sqlx.DB, log *logger.Logger) PasswordResetRepository {
+	return &PostgresPasswordResetRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresPasswordResetRepository) Create(token *models.PasswordResetToken) error {
+	query := `
+		WITH superseded AS (
+			UPDATE password_reset_tokens
+			SET used_at = NOW()
+			WHERE user_id = $1 AND used_at IS NULL
+		)
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		r.log.Error("Failed to store password reset token: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresPasswordResetRepository) Get(tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	if err := r.db.Get(&token, query, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *PostgresPasswordResetRepository) Consume(tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, token_hash, expires_at, used_at, created_at
+	`
+
+	if err := r.db.Get(&token, query, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *PostgresPasswordResetRepository) CountSince(userID string, since time.Time) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(*)
+		FROM password_reset_tokens
+		WHERE user_id = $1 AND created_at > $2
+	`
+
+	if err := r.db.Get(&count, query, userID, since); err != nil {
+		r.log.Error("Failed to count password reset tokens: " + err.Error())
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// RefreshTokenRepository persists refresh tokens and their rotation state.
+// Tokens are looked up by hash only; the raw value never reaches the database.
+type RefreshTokenRepository interface {
+	// Create inserts a refresh token. An empty FamilyID starts a new family.
+	// ID, FamilyID and CreatedAt are populated on success.
+	Create(token *models.RefreshToken) error
+
+	// GetByHash finds a refresh token by the hash of its raw value.
+	// Returns sql.ErrNoRows if no token matches.
+	GetByHash(tokenHash string) (*models.RefreshToken, error)
+
+	// MarkUsed atomically flags a token as consumed.
+	// Returns false if the token was already used or revoked.
+	MarkUsed(id string) (bool, error)
+
+	// RevokeFamily revokes every token that descends from the same login.
+	RevokeFamily(familyID string) error
+
+	// RevokeAllForUser revokes every outstanding refresh token of a user.
+	RevokeAllForUser(userID string) error
+}
+
+type PostgresRefreshTokenRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresRefreshTokenRepository(db *sqlx.DB, log *logger.Logger) RefreshTokenRepository {
+	return &PostgresRefreshTokenRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresRefreshTokenRepository) Create(token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens
+			(user_id, family_id, parent_id, client_id, scope, amr, token_hash, expires_at, risk_score, risk_reasons,
+			auth_time)
+		VALUES ($1, COALESCE(NULLIF($2, '')::uuid, gen_random_uuid()), $3, $4, $5, COALESCE($6::text[], '{}'), $7, $8,
+			$9, COALESCE($10::text[], '{}'), $11)
+		RETURNING id, family_id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		token.UserID,
+		token.FamilyID,
+		token.ParentID,
+		token.ClientID,
+		token.Scope,
+		token.AMR,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.RiskScore,
+		token.RiskReasons,
+		token.AuthTime,
+	).Scan(&token.ID, &token.FamilyID, &token.CreatedAt)
+	if err != nil {
+		r.log.Error("Failed to create refresh token: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresRefreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+
+	query := `
+		SELECT id, user_id, family_id, parent_id, client_id, scope, amr, token_hash, expires_at, used_at, revoked_at, created_at,
+			risk_score, risk_reasons, auth_time
+		FROM refresh_tokens
+		WHERE token_hash=$1
+	`
+
+	if err := r.db.Get(&token, query, tokenHash); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *PostgresRefreshTokenRepository) MarkUsed(id string) (bool, error) {
+	// The WHERE clause makes this a compare-and-swap: two concurrent refreshes
+	// with the same token cannot both succeed.
+	query := `
+		UPDATE refresh_tokens
+		SET used_at = NOW()
+		WHERE id=$1 AND used_at IS NULL AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		r.log.Error("Failed to mark refresh token used: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *PostgresRefreshTokenRepository) RevokeFamily(familyID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id=$1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, familyID); err != nil {
+		r.log.Error("Failed to revoke refresh token family " + familyID + ": " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresRefreshTokenRepository) RevokeAllForUser(userID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id=$1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, userID); err != nil {
+		r.log.Error("Failed to revoke refresh tokens for user " + userID + ": " + err.Error())
+		return err
+	}
+
+	return nil
+}
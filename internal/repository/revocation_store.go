@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// RevocationStore records access tokens that must be rejected before they expire.
+//
+// Two mechanisms are supported:
+//   - single tokens, identified by their jti claim (logout)
+//   - a per-user "tokens valid after" cutoff (logout everywhere)
+type RevocationStore interface {
+	// Revoke marks a single token as revoked until it would have expired anyway.
+	Revoke(jti, userID string, expiresAt time.Time) error
+
+	// IsRevoked reports whether the token with this jti has been revoked.
+	IsRevoked(jti string) (bool, error)
+
+	// SetTokensValidAfter rejects every token for the user issued before t.
+	SetTokensValidAfter(userID string, t time.Time) error
+
+	// TokensValidAfter returns the user's cutoff, or the zero time if none is set.
+	TokensValidAfter(userID string) (time.Time, error)
+}
+
+// =============================================================================
+// Postgres implementation
+// =============================================================================
+
+type PostgresRevocationStore struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresRevocationStore(db *sqlx.DB, log *logger.Logger) RevocationStore {
+	return &PostgresRevocationStore{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresRevocationStore) Revoke(jti, userID string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(query, jti, userID, expiresAt); err != nil {
+		r.log.Error("Failed to revoke token: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresRevocationStore) IsRevoked(jti string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti=$1)`
+
+	if err := r.db.Get(&exists, query, jti); err != nil {
+		r.log.Error("Failed to check token revocation: " + err.Error())
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *PostgresRevocationStore) SetTokensValidAfter(userID string, t time.Time) error {
+	query := `
+		INSERT INTO user_token_cutoffs (user_id, valid_after)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET valid_after = EXCLUDED.valid_after
+	`
+
+	if _, err := r.db.Exec(query, userID, t); err != nil {
+		r.log.Error("Failed to set token cutoff for user " + userID + ": " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresRevocationStore) TokensValidAfter(userID string) (time.Time, error) {
+	var validAfter time.Time
+
+	query := `SELECT valid_after FROM user_token_cutoffs WHERE user_id=$1`
+
+	err := r.db.Get(&validAfter, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		r.log.Error("Failed to fetch token cutoff for user " + userID + ": " + err.Error())
+		return time.Time{}, err
+	}
+
+	return validAfter, nil
+}
+
+// =============================================================================
+// In-memory implementation
+// =============================================================================
+
+// MemoryRevocationStore keeps revocations in process memory.
+// Suitable for local development and single-instance deployments only:
+// revocations are lost on restart and not shared between replicas.
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time // jti -> token expiry
+	cutoffs map[string]time.Time // user ID -> valid after
+}
+
+func NewMemoryRevocationStore() RevocationStore {
+	return &MemoryRevocationStore{
+		revoked: make(map[string]time.Time),
+		cutoffs: make(map[string]time.Time),
+	}
+}
+
+func (m *MemoryRevocationStore) Revoke(jti, userID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop entries for tokens that have expired on their own
+	now := time.Now()
+	for id, exp := range m.revoked {
+		if now.After(exp) {
+			delete(m.revoked, id)
+		}
+	}
+
+	m.revoked[jti] = expiresAt
+	return nil
+}
+
+func (m *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.revoked[jti]
+	return ok, nil
+}
+
+func (m *MemoryRevocationStore) SetTokensValidAfter(userID string, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cutoffs[userID] = t
+	return nil
+}
+
+func (m *MemoryRevocationStore) TokensValidAfter(userID string) (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cutoffs[userID], nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrRotationConflict is returned when another instance rotated the keys first.
+var ErrRotationConflict = errors.New("signing key was rotated concurrently")
+
+// SigningKeyRepository persists signing key lifecycle metadata.
+type SigningKeyRepository interface {
+	// List returns every known key, newest first.
+	List() ([]models.SigningKey, error)
+
+	// ListVerifiable returns keys that may still verify tokens at the given
+	// time, including their sealed private keys.
+	ListVerifiable(at time.Time) ([]models.SigningKey, error)
+
+	// Rotate retires the active key and activates newKey in one transaction.
+	// expectedActiveKID guards against two instances rotating at once: if the
+	// active key is no longer expectedActiveKID, ErrRotationConflict is returned.
+	// An empty expectedActiveKID means no key is expected to be active yet.
+	// newKey's PrivateKey, if any, is stored with it.
+	// The retired key keeps verifying until retiredVerifyUntil.
+	Rotate(newKey *models.SigningKey, expectedActiveKID string, retiredVerifyUntil time.Time) error
+}
+
+type PostgresSigningKeyRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresSigningKeyRepository(db *sqlx.DB, log *logger.Logger) SigningKeyRepository {
+	return &PostgresSigningKeyRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresSigningKeyRepository) List() ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+
+	query := `
+		SELECT kid, algorithm, created_at, activated_at, retired_at, verify_until
+		FROM signing_keys
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.Select(&keys, query); err != nil {
+		r.log.Error("Failed to list signing keys: " + err.Error())
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *PostgresSigningKeyRepository) ListVerifiable(at time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+
+	query := `
+		SELECT kid, algorithm, created_at, activated_at, retired_at, verify_until, private_key
+		FROM signing_keys
+		WHERE activated_at IS NOT NULL
+		  AND (verify_until IS NULL OR verify_until > $1)
+		ORDER BY activated_at DESC
+	`
+
+	if err := r.db.Select(&keys, query, at); err != nil {
+		r.log.Error("Failed to list verifiable signing keys: " + err.Error())
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *PostgresSigningKeyRepository) Rotate(newKey *models.SigningKey, expectedActiveKID string, retiredVerifyUntil time.Time) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the active row (if any) so concurrent rotations serialize here
+	var activeKID string
+	err = tx.Get(&activeKID, `
+		SELECT kid FROM signing_keys
+		WHERE activated_at IS NOT NULL AND retired_at IS NULL
+		FOR UPDATE
+	`)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if activeKID != expectedActiveKID {
+		return ErrRotationConflict
+	}
+
+	if activeKID != "" {
+		_, err = tx.Exec(`
+			UPDATE signing_keys
+			SET retired_at = NOW(), verify_until = $2
+			WHERE kid=$1
+		`, activeKID, retiredVerifyUntil)
+		if err != nil {
+			r.log.Error("Failed to retire signing key " + activeKID + ": " + err.Error())
+			return err
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO signing_keys (kid, algorithm, activated_at, private_key)
+		VALUES ($1, $2, NOW(), $3)
+		RETURNING created_at, activated_at
+	`, newKey.KID, newKey.Algorithm, newKey.PrivateKey).Scan(&newKey.CreatedAt, &newKey.ActivatedAt)
+	if err != nil {
+		// With no active row there was nothing to lock, so two first rotations
+		// both get here; the partial unique index rejects the second one
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrRotationConflict
+		}
+		r.log.Error("Failed to activate signing key " + newKey.KID + ": " + err.Error())
+		return err
+	}
+
+	return tx.Commit()
+}
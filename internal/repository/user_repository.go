@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
+
 	"github.com/abhay786-20/fraud-auth-service/internal/models"
 	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
 	"github.com/jmoiron/sqlx"
@@ -30,6 +33,22 @@ type UserRepository interface {
 	// GetByEmail finds a user by their email address
 	// Returns (*User, nil) if found, (nil, error) if not found or error occurs
 	GetByEmail(email string) (*models.User, error)
+
+	// GetByID finds a user by primary key
+	// Returns (*User, nil) if found, (nil, error) if not found or error occurs
+	GetByID(id string) (*models.User, error)
+
+	// MarkEmailVerified records that the user proved ownership of their email.
+	// Verifying an already verified user keeps the original timestamp.
+	MarkEmailVerified(id string) error
+
+	// UpdatePassword replaces the user's password hash and bumps updated_at
+	UpdatePassword(id, passwordHash string) error
+
+	// Import inserts a user brought over from another system with the given
+	// password hash, role and verification time. It returns false, and
+	// changes nothing, if the email is already taken.
+	Import(user *models.User) (bool, error)
 }
 
 // =============================================================================
@@ -96,7 +115,7 @@ func NewPostgresUserRepository(db *sqlx.DB, log *logger.Logger) UserRepository {
 // - error: nil if success, error if failed
 //
 // SIDE EFFECT:
-// - user.ID, user.CreatedAt, user.UpdatedAt are populated after successful insert
+// - user.ID, user.Role, user.CreatedAt, user.UpdatedAt are populated after successful insert
 //   (because we use RETURNING clause and Scan into the same user object)
 //
 // SQL EXPLANATION:
@@ -111,7 +130,7 @@ func (r *PostgresUserRepository) Create(user *models.User) error {
 	query := `
 		INSERT INTO users (email, password)
 		VALUES ($1, $2)
-		RETURNING id, created_at, updated_at
+		RETURNING id, role, created_at, updated_at
 	`
 
 	// Execute query and scan returned values into user struct
@@ -122,7 +141,7 @@ func (r *PostgresUserRepository) Create(user *models.User) error {
 		query,
 		user.Email,    // $1 - first placeholder
 		user.Password, // $2 - second placeholder
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	// Error handling - always check and log errors
 	if err != nil {
@@ -161,7 +180,7 @@ func (r *PostgresUserRepository) GetByEmail(email string) (*models.User, error)
 
 	// SQL query - $1 is placeholder for email parameter
 	query := `
-		SELECT id, email, password, created_at
+		SELECT id, email, password, role, email_verified_at, created_at
 		FROM users
 		WHERE email=$1
 	`
@@ -178,3 +197,113 @@ func (r *PostgresUserRepository) GetByEmail(email string) (*models.User, error)
 	r.log.Info("User fetched successfully with ID: " + user.ID)
 	return &user, nil // Return pointer to user and nil error
 }
+
+// =============================================================================
+// METHOD - Get User By ID
+// =============================================================================
+// GetByID finds a user by their ID.
+//
+// Used when a request carries a user ID rather than credentials
+// (e.g. refresh tokens), so the current user record can be reloaded.
+func (r *PostgresUserRepository) GetByID(id string) (*models.User, error) {
+	var user models.User
+
+	query := `
+		SELECT id, email, password, role, email_verified_at, created_at, updated_at
+		FROM users
+		WHERE id=$1
+	`
+
+	err := r.db.Get(&user, query, id)
+	if err != nil {
+		r.log.Error("Failed to fetch user by id: " + err.Error())
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// =============================================================================
+// METHOD - Mark Email Verified
+// =============================================================================
+// MarkEmailVerified sets email_verified_at once the user has followed the
+// verification link mailed to them.
+func (r *PostgresUserRepository) MarkEmailVerified(id string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id); err != nil {
+		r.log.Error("Failed to mark email verified: " + err.Error())
+		return err
+	}
+
+	r.log.Info("Email verified for user " + id)
+	return nil
+}
+
+// =============================================================================
+// METHOD - Update Password
+// =============================================================================
+// UpdatePassword stores a new password hash for the user.
+//
+// The caller hashes the password; this layer never sees plaintext.
+func (r *PostgresUserRepository) UpdatePassword(id, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET password = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(query, id, passwordHash)
+	if err != nil {
+		r.log.Error("Failed to update password: " + err.Error())
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	r.log.Info("Password updated for user " + id)
+	return nil
+}
+
+// =============================================================================
+// METHOD - Import User
+// =============================================================================
+// Import inserts a user migrated from another identity store.
+//
+// Unlike Create, the role and email_verified_at come from the caller, and
+// an existing account with the same email is left untouched.
+func (r *PostgresUserRepository) Import(user *models.User) (bool, error) {
+	query := `
+		INSERT INTO users (email, password, role, email_verified_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		user.Email,
+		user.Password,
+		user.Role,
+		user.EmailVerifiedAt,
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.log.Error("Failed to import user: " + err.Error())
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// WebAuthnRepository stores WebAuthn credentials by user and the state of
+// in-flight ceremonies.
+type WebAuthnRepository interface {
+	// CreateCredential stores a newly registered credential.
+	// ID and CreatedAt are populated on success.
+	CreateCredential(credential *models.WebAuthnCredential) error
+
+	// ListByUser returns every credential of a user, oldest first.
+	ListByUser(userID string) ([]models.WebAuthnCredential, error)
+
+	// CountUsable returns how many credentials of a user can still sign in,
+	// i.e. were not flagged as cloned.
+	CountUsable(userID string) (int, error)
+
+	// RecordUse stores the sign counter and flags reported by a successful assertion.
+	RecordUse(credential *models.WebAuthnCredential) error
+
+	// FlagCloned marks a credential as possibly cloned.
+	FlagCloned(id string) error
+
+	// DeleteCredential removes one of the user's credentials.
+	// Returns false if the user has no credential with that ID.
+	DeleteCredential(userID, id string) (bool, error)
+
+	// CreateSession stores the state of a ceremony.
+	CreateSession(session *models.WebAuthnSession) error
+
+	// TakeSession atomically deletes and returns a ceremony, so each
+	// challenge can be answered once. Returns sql.ErrNoRows if none matches.
+	TakeSession(tokenHash, ceremony string) (*models.WebAuthnSession, error)
+}
+
+type PostgresWebAuthnRepository struct {
+	db  *sqlx.DB
+	log *logger.Logger
+}
+
+func NewPostgresWebAuthnRepository(db *sqlx.DB, log *logger.Logger) WebAuthnRepository {
+	return &PostgresWebAuthnRepository{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *PostgresWebAuthnRepository) CreateCredential(credential *models.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials
+			(user_id, credential_id, name, public_key, attestation_type, transports, aaguid,
+			 attachment, sign_count, user_verified, backup_eligible, backup_state)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6::text[], '{}'), $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		credential.UserID,
+		credential.CredentialID,
+		credential.Name,
+		credential.PublicKey,
+		credential.AttestationType,
+		credential.Transports,
+		credential.AAGUID,
+		credential.Attachment,
+		credential.SignCount,
+		credential.UserVerified,
+		credential.BackupEligible,
+		credential.BackupState,
+	).Scan(&credential.ID, &credential.CreatedAt)
+	if err != nil {
+		r.log.Error("Failed to store WebAuthn credential: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresWebAuthnRepository) ListByUser(userID string) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+
+	query := `
+		SELECT id, user_id, credential_id, name, public_key, attestation_type, transports, aaguid,
+		       attachment, sign_count, user_verified, backup_eligible, backup_state,
+		       clone_detected_at, last_used_at, created_at
+		FROM webauthn_credentials
+		WHERE user_id=$1
+		ORDER BY created_at
+	`
+
+	if err := r.db.Select(&credentials, query, userID); err != nil {
+		r.log.Error("Failed to list WebAuthn credentials: " + err.Error())
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+func (r *PostgresWebAuthnRepository) CountUsable(userID string) (int, error) {
+	var count int
+
+	query := `SELECT COUNT(*) FROM webauthn_credentials WHERE user_id=$1 AND clone_detected_at IS NULL`
+
+	if err := r.db.Get(&count, query, userID); err != nil {
+		r.log.Error("Failed to count WebAuthn credentials: " + err.Error())
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *PostgresWebAuthnRepository) RecordUse(credential *models.WebAuthnCredential) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, user_verified = $3, backup_state = $4, last_used_at = NOW()
+		WHERE id=$1
+	`
+
+	_, err := r.db.Exec(query, credential.ID, credential.SignCount, credential.UserVerified, credential.BackupState)
+	if err != nil {
+		r.log.Error("Failed to record WebAuthn credential use: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresWebAuthnRepository) FlagCloned(id string) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET clone_detected_at = COALESCE(clone_detected_at, NOW())
+		WHERE id=$1
+	`
+
+	if _, err := r.db.Exec(query, id); err != nil {
+		r.log.Error("Failed to flag WebAuthn credential " + id + ": " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresWebAuthnRepository) DeleteCredential(userID, id string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM webauthn_credentials WHERE id::text=$1 AND user_id=$2`, id, userID)
+	if err != nil {
+		r.log.Error("Failed to delete WebAuthn credential: " + err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *PostgresWebAuthnRepository) CreateSession(session *models.WebAuthnSession) error {
+	query := `
+		INSERT INTO webauthn_sessions (token_hash, user_id, ceremony, data, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(
+		query,
+		session.TokenHash,
+		session.UserID,
+		session.Ceremony,
+		session.Data,
+		session.ExpiresAt,
+	).Scan(&session.ID, &session.CreatedAt)
+	if err != nil {
+		r.log.Error("Failed to store WebAuthn session: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresWebAuthnRepository) TakeSession(tokenHash, ceremony string) (*models.WebAuthnSession, error) {
+	var session models.WebAuthnSession
+
+	query := `
+		DELETE FROM webauthn_sessions
+		WHERE token_hash=$1 AND ceremony=$2
+		RETURNING id, token_hash, user_id, ceremony, data, expires_at, created_at
+	`
+
+	if err := r.db.Get(&session, query, tokenHash, ceremony); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,168 @@
+// Package risk scores password logins for signs of account takeover. Each
+// Signal looks at one kind of evidence (an unknown device, a burst of failed
+// attempts, ...) and contributes points; the Engine adds them up to a score
+// between 0 and 100 and turns it into a decision.
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/geoip"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+)
+
+// Decisions
+const (
+	DecisionAllow     = "allow"     // Proceed as usual
+	DecisionChallenge = "challenge" // Require a second factor before issuing tokens
+	DecisionDeny      = "deny"      // Refuse the login
+)
+
+// MaxScore is the highest risk score.
+const MaxScore = 100
+
+// Request describes the client attempting a login.
+type Request struct {
+	IP             string
+	UserAgent      string
+	AcceptLanguage string
+	Fingerprint    string // Device fingerprint sent by the client, if any
+	DeviceToken    string // Trust token of the device from its cookie, if any
+	Time           time.Time
+}
+
+// Login is what signals look at. It is only assessed once the password has
+// been verified, so it always belongs to a real account.
+type Login struct {
+	Request
+	UserID         string
+	AccountCreated time.Time
+	Location       *geoip.Location      // Where the IP is; nil if it could not be located
+	Device         *models.UserDevice   // The user's known device the login comes from; nil if new
+	KnownDevices   int                  // How many devices the user has logged in from
+	Failures       int                  // Consecutive failed attempts before this one
+	LastFailure    time.Time            // Zero when Failures is 0
+	History        []models.LoginRecord // Earlier successful logins, newest first
+}
+
+// Reason is one signal's contribution to a score.
+type Reason struct {
+	Signal string // Reason code, e.g. "new_device"
+	Score  int
+	Detail string
+}
+
+// Signal evaluates one kind of evidence. Evaluate returns nil when the login
+// looks normal in that respect.
+type Signal interface {
+	Evaluate(login *Login) *Reason
+}
+
+// Assessment is the outcome of scoring a login.
+type Assessment struct {
+	Score    int
+	Decision string
+	Reasons  []Reason
+	Attack   string // Scope of a credential stuffing attack covering the client; "" if none
+}
+
+// Codes returns the reason codes, highest contribution first.
+func (a *Assessment) Codes() []string {
+	codes := make([]string, len(a.Reasons))
+	for i, reason := range a.Reasons {
+		codes[i] = reason.Signal
+	}
+	return codes
+}
+
+// String summarises the assessment for logs and audit events, e.g.
+// "challenge, score 55: new_device +25 (...), new_network +20 (...)".
+func (a *Assessment) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, score %d", a.Decision, a.Score)
+	for i, reason := range a.Reasons {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(reason.Signal)
+		if reason.Score > 0 {
+			fmt.Fprintf(&b, " +%d", reason.Score)
+		}
+		if reason.Detail != "" {
+			b.WriteString(" (" + reason.Detail + ")")
+		}
+	}
+	return b.String()
+}
+
+// Challenge asks for a second factor whatever the score, for a reason that
+// lies outside the login itself, such as an attack in progress. A denied
+// login stays denied.
+func (a *Assessment) Challenge(reason Reason) {
+	a.Reasons = append(a.Reasons, reason)
+	if a.Decision == DecisionAllow {
+		a.Decision = DecisionChallenge
+	}
+}
+
+// Decide sets the decision whatever the score, as a fraud rule does.
+func (a *Assessment) Decide(decision string, reason Reason) {
+	a.Reasons = append(a.Reasons, reason)
+	a.Decision = decision
+}
+
+// Engine scores logins with a set of signals. A score at or above the
+// challenge threshold asks for a second factor, one at or above the deny
+// threshold refuses the login; a threshold of 0 disables that decision.
+type Engine struct {
+	signals     []Signal
+	challengeAt int
+	denyAt      int
+}
+
+func NewEngine(challengeAt, denyAt int, signals ...Signal) (*Engine, error) {
+	if challengeAt < 0 || challengeAt > MaxScore || denyAt < 0 || denyAt > MaxScore {
+		return nil, errors.New("risk thresholds must be between 0 and 100")
+	}
+	if challengeAt > 0 && denyAt > 0 && denyAt < challengeAt {
+		return nil, errors.New("the risk deny threshold must not be below the challenge threshold")
+	}
+	return &Engine{
+		signals:     signals,
+		challengeAt: challengeAt,
+		denyAt:      denyAt,
+	}, nil
+}
+
+// Assess scores a login. Reasons are ordered by contribution, highest first.
+func (e *Engine) Assess(login *Login) *Assessment {
+	assessment := &Assessment{Decision: DecisionAllow}
+
+	for _, signal := range e.signals {
+		reason := signal.Evaluate(login)
+		if reason == nil || reason.Score <= 0 {
+			continue
+		}
+		assessment.Score += reason.Score
+		assessment.Reasons = append(assessment.Reasons, *reason)
+	}
+	assessment.Score = min(assessment.Score, MaxScore)
+
+	sort.SliceStable(assessment.Reasons, func(i, j int) bool {
+		return assessment.Reasons[i].Score > assessment.Reasons[j].Score
+	})
+
+	switch {
+	case e.denyAt > 0 && assessment.Score >= e.denyAt:
+		assessment.Decision = DecisionDeny
+	case e.challengeAt > 0 && assessment.Score >= e.challengeAt:
+		assessment.Decision = DecisionChallenge
+	}
+	return assessment
+}
@@ -0,0 +1,119 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+)
+
+// fixed is a Signal that always contributes the same score.
+type fixed struct {
+	name  string
+	score int
+}
+
+func (s fixed) Evaluate(*Login) *Reason {
+	if s.score == 0 {
+		return nil
+	}
+	return &Reason{Signal: s.name, Score: s.score}
+}
+
+func TestNewEngineValidatesThresholds(t *testing.T) {
+	tests := []struct {
+		challengeAt, denyAt int
+		ok                  bool
+	}{
+		{50, 80, true},
+		{50, 50, true},
+		{0, 80, true},
+		{50, 0, true},
+		{0, 0, true},
+		{80, 50, false},
+		{-1, 80, false},
+		{50, 101, false},
+	}
+	for _, tt := range tests {
+		_, err := NewEngine(tt.challengeAt, tt.denyAt)
+		if (err == nil) != tt.ok {
+			t.Errorf("NewEngine(%d, %d) = %v, want ok %v", tt.challengeAt, tt.denyAt, err, tt.ok)
+		}
+	}
+}
+
+func TestAssessThresholds(t *testing.T) {
+	tests := []struct {
+		name                string
+		challengeAt, denyAt int
+		scores              []int
+		wantScore           int
+		wantDecision        string
+	}{
+		{"nothing fires", 50, 80, nil, 0, DecisionAllow},
+		{"below the challenge threshold", 50, 80, []int{20, 29}, 49, DecisionAllow},
+		{"at the challenge threshold", 50, 80, []int{25, 25}, 50, DecisionChallenge},
+		{"at the deny threshold", 50, 80, []int{50, 30}, 80, DecisionDeny},
+		{"capped at the maximum", 50, 80, []int{60, 70}, MaxScore, DecisionDeny},
+		{"challenging disabled", 0, 80, []int{60}, 60, DecisionAllow},
+		{"denying disabled", 50, 0, []int{100}, 100, DecisionChallenge},
+		{"negative scores ignored", 50, 80, []int{50, -30}, 50, DecisionChallenge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signals []Signal
+			for i, score := range tt.scores {
+				signals = append(signals, fixed{name: "signal" + string(rune('a'+i)), score: score})
+			}
+			engine, err := NewEngine(tt.challengeAt, tt.denyAt, signals...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a := engine.Assess(&Login{})
+			if a.Score != tt.wantScore || a.Decision != tt.wantDecision {
+				t.Errorf("got %s, want %s with score %d", a, tt.wantDecision, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestAssessOrdersReasons(t *testing.T) {
+	engine, err := NewEngine(50, 80,
+		fixed{name: "small", score: 5},
+		fixed{name: "quiet", score: 0},
+		fixed{name: "large", score: 30},
+		fixed{name: "medium", score: 20},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := engine.Assess(&Login{})
+	if got := strings.Join(a.Codes(), ","); got != "large,medium,small" {
+		t.Errorf("got reasons %s, want large,medium,small", got)
+	}
+	if got := a.String(); got != "challenge, score 55: large +30, medium +20, small +5" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestAssessmentOverrides(t *testing.T) {
+	attack := Reason{Signal: "credential_stuffing"}
+
+	allowed := &Assessment{Decision: DecisionAllow}
+	allowed.Challenge(attack)
+	if allowed.Decision != DecisionChallenge || len(allowed.Reasons) != 1 {
+		t.Errorf("Challenge on an allowed login: got %s", allowed)
+	}
+
+	denied := &Assessment{Decision: DecisionDeny}
+	denied.Challenge(attack)
+	if denied.Decision != DecisionDeny {
+		t.Errorf("Challenge relaxed a denial to %s", denied.Decision)
+	}
+
+	// Rules decide either way
+	denied.Decide(DecisionAllow, Reason{Signal: "rule:office"})
+	if denied.Decision != DecisionAllow || len(denied.Reasons) != 2 {
+		t.Errorf("Decide: got %s", denied)
+	}
+}
@@ -0,0 +1,203 @@
+package risk
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/device"
+	"github.com/abhay786-20/fraud-auth-service/internal/geoip"
+)
+
+// DefaultSignals returns every built-in signal with its default weight.
+// Impossible travel alone is enough for the default challenge threshold.
+func DefaultSignals() []Signal {
+	return []Signal{
+		ImpossibleTravel{Weight: 50, MaxSpeedKmh: 1000},
+		NewDevice{Weight: 25},
+		NewNetwork{Weight: 20},
+		FailureVelocity{Weight: 30, Saturation: 5},
+		UnusualHour{Weight: 10, MinHistory: 5, Tolerance: 2},
+		AccountAge{Weight: 15, MinAge: 7 * 24 * time.Hour},
+	}
+}
+
+// NewDevice fires when the login comes from a device (see package device)
+// the user has not completed a login from. Users without any known device
+// are left to AccountAge: everything would look new to them.
+type NewDevice struct {
+	Weight int
+}
+
+func (s NewDevice) Evaluate(login *Login) *Reason {
+	if login.KnownDevices == 0 || login.Device != nil {
+		return nil
+	}
+	name := device.Parse(login.UserAgent, login.AcceptLanguage).Name()
+	return &Reason{Signal: "new_device", Score: s.Weight, Detail: name + " not seen before"}
+}
+
+// NewNetwork fires when the login comes from an IP address the user has not
+// logged in from before. The full weight applies when the whole network
+// (the /24 for IPv4, the /48 for IPv6) is new, a quarter of it when only the
+// address within a known network is.
+type NewNetwork struct {
+	Weight int
+}
+
+func (s NewNetwork) Evaluate(login *Login) *Reason {
+	if len(login.History) == 0 {
+		return nil
+	}
+
+	network, ok := networkOf(login.IP)
+	if !ok {
+		return nil
+	}
+
+	knownNetwork := false
+	for _, record := range login.History {
+		if record.IP == login.IP {
+			return nil
+		}
+		if prefix, ok := networkOf(record.IP); ok && prefix == network {
+			knownNetwork = true
+		}
+	}
+
+	if knownNetwork {
+		return &Reason{Signal: "new_ip", Score: s.Weight / 4, Detail: "new address in known network " + network.String()}
+	}
+	return &Reason{Signal: "new_network", Score: s.Weight, Detail: "network " + network.String() + " not seen before"}
+}
+
+// networkOf returns the network an address belongs to for NewNetwork.
+func networkOf(ip string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	return prefix, err == nil
+}
+
+// FailureVelocity fires when the correct password follows failed attempts,
+// as when a guessing attack finally succeeds. The score grows with the
+// number of failures and reaches the full weight at Saturation.
+type FailureVelocity struct {
+	Weight     int
+	Saturation int
+}
+
+func (s FailureVelocity) Evaluate(login *Login) *Reason {
+	if login.Failures <= 0 {
+		return nil
+	}
+
+	failures := min(login.Failures, s.Saturation)
+	return &Reason{
+		Signal: "failed_attempts",
+		Score:  s.Weight * failures / max(s.Saturation, 1),
+		Detail: fmt.Sprintf("%d failed attempts, the last %s ago",
+			login.Failures, login.Time.Sub(login.LastFailure).Round(time.Second)),
+	}
+}
+
+// UnusualHour fires when the user has not logged in within Tolerance hours
+// of the current time of day (UTC) before. Users with fewer than MinHistory
+// logins have no habits to compare against.
+type UnusualHour struct {
+	Weight     int
+	MinHistory int
+	Tolerance  int // Hours either side
+}
+
+func (s UnusualHour) Evaluate(login *Login) *Reason {
+	if len(login.History) < s.MinHistory {
+		return nil
+	}
+
+	hour := login.Time.UTC().Hour()
+	for _, record := range login.History {
+		distance := (record.CreatedAt.UTC().Hour() - hour + 24) % 24
+		if distance <= s.Tolerance || 24-distance <= s.Tolerance {
+			return nil
+		}
+	}
+	return &Reason{Signal: "unusual_hour", Score: s.Weight, Detail: fmt.Sprintf("no earlier logins around %02d:00 UTC", hour)}
+}
+
+// AccountAge fires for accounts younger than MinAge, which are more often
+// created for abuse. Accounts under a day old get the full weight, older
+// ones half.
+type AccountAge struct {
+	Weight int
+	MinAge time.Duration
+}
+
+func (s AccountAge) Evaluate(login *Login) *Reason {
+	age := login.Time.Sub(login.AccountCreated)
+	if age >= s.MinAge {
+		return nil
+	}
+
+	score := s.Weight
+	if age >= 24*time.Hour {
+		score /= 2
+	}
+	return &Reason{Signal: "new_account", Score: score, Detail: "account created " + age.Round(time.Minute).String() + " ago"}
+}
+
+// ImpossibleTravel fires when the user would have had to travel faster than
+// MaxSpeedKmh since their last located login to be here now. The accuracy
+// radius of both locations is taken off the distance, so imprecise
+// database entries do not count as travel.
+type ImpossibleTravel struct {
+	Weight      int
+	MaxSpeedKmh float64
+}
+
+func (s ImpossibleTravel) Evaluate(login *Login) *Reason {
+	if login.Location == nil {
+		return nil
+	}
+
+	for _, record := range login.History {
+		if !record.IsLocated() {
+			continue
+		}
+
+		previous := &geoip.Location{
+			Country:   record.Country,
+			City:      record.City,
+			Latitude:  *record.Latitude,
+			Longitude: *record.Longitude,
+			RadiusKm:  record.AccuracyKm,
+		}
+		distance := login.Location.DistanceKm(previous) - float64(login.Location.RadiusKm+previous.RadiusKm)
+		if distance <= 0 {
+			return nil
+		}
+
+		// Logins in the same minute would otherwise divide by (almost) zero
+		elapsed := max(login.Time.Sub(record.CreatedAt), time.Minute)
+		speed := distance / elapsed.Hours()
+		if speed <= s.MaxSpeedKmh {
+			return nil
+		}
+
+		return &Reason{
+			Signal: "impossible_travel",
+			Score:  s.Weight,
+			Detail: fmt.Sprintf("%s to %s: %.0f km in %s, %.0f km/h",
+				previous, login.Location, distance, elapsed.Round(time.Minute), speed),
+		}
+	}
+	return nil
+}
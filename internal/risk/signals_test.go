@@ -0,0 +1,252 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/geoip"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+)
+
+var now = time.Date(2024, 6, 3, 14, 30, 0, 0, time.UTC)
+
+const firefox = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0"
+
+// history returns earlier logins from ip, one a day at the given UTC hour.
+func history(ip string, hour, n int) []models.LoginRecord {
+	records := make([]models.LoginRecord, n)
+	for i := range records {
+		day := now.AddDate(0, 0, -(i + 1))
+		records[i] = models.LoginRecord{
+			IP:        ip,
+			CreatedAt: time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, time.UTC),
+		}
+	}
+	return records
+}
+
+// score evaluates signal and returns its contribution and reason code.
+func score(signal Signal, login *Login) (int, string) {
+	reason := signal.Evaluate(login)
+	if reason == nil {
+		return 0, ""
+	}
+	return reason.Score, reason.Signal
+}
+
+func TestNewDevice(t *testing.T) {
+	signal := NewDevice{Weight: 25}
+
+	tests := []struct {
+		name   string
+		login  Login
+		want   int
+		reason string
+	}{
+		{"known device", Login{KnownDevices: 2, Device: &models.UserDevice{}}, 0, ""},
+		{"new device", Login{KnownDevices: 2}, 25, "new_device"},
+		{"first device ever", Login{KnownDevices: 0}, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.login.UserAgent = firefox
+			got, reason := score(signal, &tt.login)
+			if got != tt.want || reason != tt.reason {
+				t.Errorf("got %d %q, want %d %q", got, reason, tt.want, tt.reason)
+			}
+		})
+	}
+
+	r := signal.Evaluate(&Login{Request: Request{UserAgent: firefox}, KnownDevices: 1})
+	if r.Detail != "Firefox on Windows not seen before" {
+		t.Errorf("detail %q names the wrong device", r.Detail)
+	}
+}
+
+func TestNewNetwork(t *testing.T) {
+	signal := NewNetwork{Weight: 20}
+	known := append(history("192.0.2.10", 9, 2), history("2001:db8:1:2::1", 9, 1)...)
+
+	tests := []struct {
+		name    string
+		ip      string
+		history []models.LoginRecord
+		want    int
+		reason  string
+	}{
+		{"known address", "192.0.2.10", known, 0, ""},
+		{"new address in a known /24", "192.0.2.77", known, 5, "new_ip"},
+		{"new network", "198.51.100.1", known, 20, "new_network"},
+		{"IPv4-mapped known address", "::ffff:192.0.2.77", known, 5, "new_ip"},
+		{"new address in a known /48", "2001:db8:1:ffff::9", known, 5, "new_ip"},
+		{"new /48", "2001:db8:2::1", known, 20, "new_network"},
+		{"no history", "198.51.100.1", nil, 0, ""},
+		{"unparseable address", "not an ip", known, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := score(signal, &Login{Request: Request{IP: tt.ip}, History: tt.history})
+			if got != tt.want || reason != tt.reason {
+				t.Errorf("got %d %q, want %d %q", got, reason, tt.want, tt.reason)
+			}
+		})
+	}
+}
+
+func TestFailureVelocity(t *testing.T) {
+	signal := FailureVelocity{Weight: 30, Saturation: 5}
+
+	for failures, want := range map[int]int{0: 0, 1: 6, 3: 18, 5: 30, 50: 30} {
+		login := &Login{Request: Request{Time: now}, Failures: failures, LastFailure: now.Add(-time.Minute)}
+		if got, _ := score(signal, login); got != want {
+			t.Errorf("%d failures: got %d, want %d", failures, got, want)
+		}
+	}
+
+	// A zero saturation must not divide by zero
+	if got, _ := score(FailureVelocity{Weight: 30}, &Login{Failures: 2}); got != 0 {
+		t.Errorf("zero saturation: got %d, want 0", got)
+	}
+}
+
+func TestUnusualHour(t *testing.T) {
+	signal := UnusualHour{Weight: 10, MinHistory: 5, Tolerance: 2}
+
+	tests := []struct {
+		name    string
+		at      int // UTC hour of the login
+		history []models.LoginRecord
+		want    int
+	}{
+		{"usual hour", 14, history("192.0.2.10", 14, 5), 0},
+		{"within tolerance", 16, history("192.0.2.10", 14, 5), 0},
+		{"outside tolerance", 17, history("192.0.2.10", 14, 5), 10},
+		{"across midnight", 1, history("192.0.2.10", 23, 5), 0},
+		{"across midnight backwards", 22, history("192.0.2.10", 0, 5), 0},
+		{"night after day logins", 3, history("192.0.2.10", 14, 5), 10},
+		{"too little history", 3, history("192.0.2.10", 14, 4), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := time.Date(2024, 6, 3, tt.at, 30, 0, 0, time.UTC)
+			if got, _ := score(signal, &Login{Request: Request{Time: at}, History: tt.history}); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// Hours compare in UTC whatever the zone the time is in
+	local := time.Date(2024, 6, 3, 16, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	if got, _ := score(signal, &Login{Request: Request{Time: local}, History: history("192.0.2.10", 14, 5)}); got != 0 {
+		t.Errorf("16:30 CEST after logins at 14:00 UTC: got %d, want 0", got)
+	}
+}
+
+func TestAccountAge(t *testing.T) {
+	signal := AccountAge{Weight: 15, MinAge: 7 * 24 * time.Hour}
+
+	for age, want := range map[time.Duration]int{
+		time.Hour:           15,
+		23 * time.Hour:      15,
+		24 * time.Hour:      7,
+		6 * 24 * time.Hour:  7,
+		7 * 24 * time.Hour:  0,
+		90 * 24 * time.Hour: 0,
+	} {
+		login := &Login{Request: Request{Time: now}, AccountCreated: now.Add(-age)}
+		if got, _ := score(signal, login); got != want {
+			t.Errorf("account %s old: got %d, want %d", age, got, want)
+		}
+	}
+}
+
+// located returns an earlier login from loc, ago before now.
+func located(loc *geoip.Location, ago time.Duration) models.LoginRecord {
+	lat, lon := loc.Latitude, loc.Longitude
+	return models.LoginRecord{
+		Country:    loc.Country,
+		City:       loc.City,
+		Latitude:   &lat,
+		Longitude:  &lon,
+		AccuracyKm: loc.RadiusKm,
+		CreatedAt:  now.Add(-ago),
+	}
+}
+
+func TestImpossibleTravel(t *testing.T) {
+	signal := ImpossibleTravel{Weight: 50, MaxSpeedKmh: 1000}
+
+	var (
+		berlin     = &geoip.Location{Country: "DE", City: "Berlin", Latitude: 52.5200, Longitude: 13.4050, RadiusKm: 10}
+		potsdam    = &geoip.Location{Country: "DE", City: "Potsdam", Latitude: 52.3906, Longitude: 13.0645, RadiusKm: 10}
+		paris      = &geoip.Location{Country: "FR", City: "Paris", Latitude: 48.8566, Longitude: 2.3522, RadiusKm: 10}
+		vagueFR    = &geoip.Location{Country: "FR", Latitude: 46.0, Longitude: 2.0, RadiusKm: 500}
+		vagueDE    = &geoip.Location{Country: "DE", Latitude: 51.0, Longitude: 9.0, RadiusKm: 500}
+		unlocated  = models.LoginRecord{IP: "192.0.2.1", CreatedAt: now.Add(-time.Minute)}
+		berlinPast = func(ago time.Duration) []models.LoginRecord {
+			return []models.LoginRecord{located(berlin, ago)}
+		}
+	)
+
+	// Berlin to Paris is about 878 km, 857 km without the accuracy radii
+	tests := []struct {
+		name     string
+		location *geoip.Location
+		history  []models.LoginRecord
+		want     int
+	}{
+		{"Paris 30 minutes after Berlin", paris, berlinPast(30 * time.Minute), 50},
+		{"Paris 50 minutes after Berlin", paris, berlinPast(50 * time.Minute), 50},
+		{"Paris 55 minutes after Berlin", paris, berlinPast(55 * time.Minute), 0},
+		{"Paris a day after Berlin", paris, berlinPast(24 * time.Hour), 0},
+		{"Paris seconds after Berlin", paris, berlinPast(time.Second), 50},
+		{"next town seconds later", potsdam, berlinPast(time.Second), 0},
+		{"same place", berlin, berlinPast(time.Second), 0},
+		{"overlapping accuracy radii", vagueFR, []models.LoginRecord{located(vagueDE, time.Minute)}, 0},
+		{"unlocated logins are skipped", paris, append([]models.LoginRecord{unlocated}, located(berlin, 30*time.Minute)), 50},
+		// Only the latest located login counts; Berlin to Paris was scored when it happened
+		{"latest located login decides", paris, []models.LoginRecord{located(paris, 10*time.Minute), located(berlin, 30*time.Minute)}, 0},
+		{"login not located", nil, berlinPast(30 * time.Minute), 0},
+		{"no located history", paris, []models.LoginRecord{unlocated}, 0},
+		{"no history", paris, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login := &Login{Request: Request{Time: now}, Location: tt.location, History: tt.history}
+			if got, _ := score(signal, login); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	r := signal.Evaluate(&Login{Request: Request{Time: now}, Location: paris, History: berlinPast(30 * time.Minute)})
+	if r == nil || !strings.HasPrefix(r.Detail, "Berlin, DE to Paris, FR: 857 km in 30m0s, 1715 km/h") {
+		t.Errorf("got %+v, want the route, distance and speed", r)
+	}
+}
+
+func TestDefaultSignalsChallengeANewDeviceOnANewNetwork(t *testing.T) {
+	engine, err := NewEngine(40, 80, DefaultSignals()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := &Login{
+		Request:        Request{IP: "198.51.100.1", UserAgent: firefox, Time: now},
+		AccountCreated: now.AddDate(-1, 0, 0),
+		KnownDevices:   1,
+		History:        history("192.0.2.10", 14, 5),
+	}
+	a := engine.Assess(login)
+	if a.Decision != DecisionChallenge || a.Score != 45 {
+		t.Errorf("got %s, want a challenge with score 45", a)
+	}
+
+	// The same login from the usual device and network is not scored
+	login.IP = "192.0.2.10"
+	login.Device = &models.UserDevice{}
+	if a := engine.Assess(login); a.Score != 0 || a.Decision != DecisionAllow {
+		t.Errorf("got %s for a usual login, want allow with score 0", a)
+	}
+}
@@ -1,11 +1,15 @@
 package router
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/abhay786-20/fraud-auth-service/internal/config"
 	"github.com/abhay786-20/fraud-auth-service/internal/handler"
 	"github.com/abhay786-20/fraud-auth-service/internal/middleware"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/ratelimit"
 	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
 )
 
@@ -16,8 +20,19 @@ type Router struct {
 func NewRouter(
 	log *logger.Logger,
 	cfg *config.Config,
+	limiter ratelimit.Limiter,
+	attackLimiter ratelimit.Limiter,
+	attacks middleware.AttackChecker,
 	authHandler *handler.AuthHandler,
 	healthHandler *handler.HealthHandler,
+	jwksHandler *handler.JWKSHandler,
+	adminHandler *handler.AdminHandler,
+	oidcHandler *handler.OIDCHandler,
+	oauthHandler *handler.OAuthHandler,
+	mfaHandler *handler.MFAHandler,
+	webauthnHandler *handler.WebAuthnHandler,
+	passwordHandler *handler.PasswordHandler,
+	deviceHandler *handler.DeviceHandler,
 ) *Router {
 
 	gin.SetMode(cfg.Server.GinMode)
@@ -26,14 +41,105 @@ func NewRouter(
 	engine.Use(gin.Recovery())
 	engine.Use(middleware.Logger(log))
 
+	// Only listed proxies may set the client IP via X-Forwarded-For; with none
+	// listed the connection's remote address is used.
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Error("Invalid AUTH_TRUSTED_PROXIES, trusting no proxies: " + err.Error())
+		_ = engine.SetTrustedProxies(nil)
+	}
+
+	// Rate limits: per IP on public credential endpoints, per submitted email
+	// on endpoints that take one, and per user on authenticated endpoints
+	perIP := rateLimit(limiter, cfg.RateLimit, "ip", cfg.RateLimit.IPPerMin, middleware.KeyByIP, log)
+	perEmail := rateLimit(limiter, cfg.RateLimit, "email", cfg.RateLimit.EmailPerMin, middleware.KeyByEmail, log)
+	perUser := rateLimit(limiter, cfg.RateLimit, "user", cfg.RateLimit.UserPerMin, middleware.KeyByUser, log)
+
+	// Tighter limits on password logins while a credential stuffing attack
+	// covers the client, enforced even when rate limiting is off
+	attackIP := attackRateLimit(attackLimiter, attacks, cfg.RateLimit, "attack-ip", cfg.Stuffing.IPPerMin, middleware.KeyByIP, log)
+	attackEmail := attackRateLimit(attackLimiter, attacks, cfg.RateLimit, "attack-email", cfg.Stuffing.EmailPerMin, middleware.KeyByEmail, log)
+
 	// Health check
 	engine.GET("/health", healthHandler.Check)
 
+	// Public verification keys for the gateway and downstream services
+	engine.GET("/.well-known/jwks.json", jwksHandler.JWKS)
+
+	// OpenID Connect
+	engine.GET("/.well-known/openid-configuration", oidcHandler.Discovery)
+	oauth2 := engine.Group("/oauth2")
+	{
+		userinfo := []gin.HandlerFunc{middleware.JWTAuth(authHandler.Service), middleware.RequireUser(), oidcHandler.UserInfo}
+		oauth2.GET("/userinfo", userinfo...)
+		oauth2.POST("/userinfo", userinfo...)
+
+		oauth2.GET("/authorize", oauthHandler.Authorize)
+		oauth2.POST("/authorize", perIP, perEmail, attackIP, attackEmail, oauthHandler.AuthorizeSubmit)
+		oauth2.POST("/token", perIP, oauthHandler.Token)
+		oauth2.POST("/introspect", oauthHandler.Introspect)
+	}
+
 	// Auth routes
 	auth := engine.Group("/api/v1/auth")
 	{
-		auth.POST("/signup", authHandler.Signup)
-		auth.POST("/login", authHandler.Login)
+		auth.POST("/signup", perIP, perEmail, authHandler.Signup)
+		auth.POST("/login", perIP, perEmail, attackIP, attackEmail, authHandler.Login)
+		auth.POST("/refresh", perIP, authHandler.Refresh)
+		auth.POST("/verify-email", perIP, authHandler.VerifyEmail)
+		auth.POST("/verify-email/resend", perIP, perEmail, authHandler.ResendVerification)
+		auth.POST("/password/forgot", perIP, perEmail, passwordHandler.Forgot)
+		auth.POST("/password/reset", perIP, passwordHandler.Reset)
+		auth.POST("/mfa/verify", perIP, mfaHandler.Verify)
+		auth.POST("/mfa/webauthn/begin", perIP, mfaHandler.BeginWebAuthn)
+		auth.POST("/webauthn/login/begin", perIP, webauthnHandler.BeginLogin)
+		auth.POST("/webauthn/login/finish", perIP, webauthnHandler.FinishLogin)
+	}
+
+	// Forward-auth for API gateways. Gateways replay the original request
+	// method, so every method is accepted.
+	auth.Any("/verify", middleware.JWTAuth(authHandler.Service), authHandler.Verify)
+
+	// Authenticated auth routes. Users who have not verified their email yet
+	// hold restricted tokens, which are good for these routes only.
+	protected := auth.Group("")
+	protected.Use(middleware.JWTAuth(authHandler.Service), middleware.RequireUser(), perUser)
+	{
+		protected.GET("/me", authHandler.Me)
+		protected.POST("/logout", authHandler.Logout)
+		protected.POST("/logout-all", authHandler.LogoutAll)
+		protected.PUT("/password", passwordHandler.Change)
+		protected.GET("/devices", deviceHandler.ListDevices)
+		protected.DELETE("/devices/:id", deviceHandler.DeleteDevice)
+	}
+
+	// Everything else requires a verified email
+	verified := protected.Group("")
+	verified.Use(middleware.RequireVerifiedEmail())
+	{
+		verified.GET("/mfa", mfaHandler.Status)
+		verified.POST("/mfa/totp/enroll", mfaHandler.EnrollTOTP)
+		verified.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+		verified.POST("/mfa/totp/disable", mfaHandler.DisableTOTP)
+		verified.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+
+		verified.POST("/webauthn/register/begin", webauthnHandler.BeginRegistration)
+		verified.POST("/webauthn/register/finish", webauthnHandler.FinishRegistration)
+		verified.GET("/webauthn/credentials", webauthnHandler.ListCredentials)
+		verified.DELETE("/webauthn/credentials/:id", webauthnHandler.DeleteCredential)
+	}
+
+	// Admin routes
+	admin := engine.Group("/api/v1/admin")
+	admin.Use(middleware.JWTAuth(authHandler.Service), perUser, middleware.RequireVerifiedEmail(), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/keys", adminHandler.ListKeys)
+		admin.POST("/keys/rotate", adminHandler.RotateKeys)
+		admin.GET("/clients", adminHandler.ListClients)
+		admin.POST("/clients", adminHandler.CreateClient)
+		admin.POST("/clients/:id/rotate-secret", adminHandler.RotateClientSecret)
+		admin.POST("/clients/:id/disable", adminHandler.DisableClient)
+		admin.POST("/users/:id/unlock", adminHandler.UnlockUser)
+		admin.POST("/users/import", adminHandler.ImportUsers)
 	}
 
 	log.Info("Router initialized")
@@ -42,3 +148,33 @@ func NewRouter(
 		Engine: engine,
 	}
 }
+
+// rateLimit builds the middleware for one per-minute limit. It passes every
+// request through when rate limiting is off or the limit is not positive.
+func rateLimit(limiter ratelimit.Limiter, cfg config.RateLimitConfig, name string, perMin int, key middleware.KeyFunc, log *logger.Logger) gin.HandlerFunc {
+	if limiter == nil || perMin <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	rule := ratelimit.Rule{
+		Name:      name,
+		Algorithm: ratelimit.Algorithm(cfg.Algorithm),
+		Limit:     perMin,
+		Window:    time.Minute,
+	}
+	return middleware.RateLimit(limiter, rule, key, log)
+}
+
+// attackRateLimit is like rateLimit, for requests covered by a credential
+// stuffing attack only. limiter is nil when detection is off.
+func attackRateLimit(limiter ratelimit.Limiter, attacks middleware.AttackChecker, cfg config.RateLimitConfig, name string, perMin int, key middleware.KeyFunc, log *logger.Logger) gin.HandlerFunc {
+	if limiter == nil || perMin <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	rule := ratelimit.Rule{
+		Name:      name,
+		Algorithm: ratelimit.Algorithm(cfg.Algorithm),
+		Limit:     perMin,
+		Window:    time.Minute,
+	}
+	return middleware.RateLimitUnderAttack(attacks, limiter, rule, key, log)
+}
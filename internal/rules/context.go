@@ -0,0 +1,75 @@
+package rules
+
+import "time"
+
+// Context is what rule expressions see. Each field is a top-level variable,
+// e.g. user.email or geo.country; the cel tags give the names.
+type Context struct {
+	User     User
+	Request  Request
+	Device   Device
+	Geo      Geo
+	Velocity Velocity
+	Risk     Risk
+}
+
+// User is the account logging in, or the one being created at signup.
+type User struct {
+	ID            string    `cel:"id"`             // "" at signup
+	Email         string    `cel:"email"`          // Lower case
+	EmailDomain   string    `cel:"email_domain"`   // Part after the @
+	EmailVerified bool      `cel:"email_verified"` // Always false at signup
+	Role          string    `cel:"role"`           // "user" or "admin"; "" at signup
+	CreatedAt     time.Time `cel:"created_at"`     // Zero at signup
+}
+
+// Request is the client making the request.
+type Request struct {
+	IP             string    `cel:"ip"`
+	UserAgent      string    `cel:"user_agent"`
+	AcceptLanguage string    `cel:"accept_language"`
+	Time           time.Time `cel:"time"`
+	Hour           int       `cel:"hour"` // 0-23, UTC
+}
+
+// Device is the device the request comes from (see package device).
+type Device struct {
+	ID            string `cel:"id"`            // Hex SHA-256 identifying the device
+	Name          string `cel:"name"`          // e.g. "Firefox on Windows"
+	Browser       string `cel:"browser"`       // "" if not recognised
+	OS            string `cel:"os"`            // "" if not recognised
+	Language      string `cel:"language"`      // Primary Accept-Language tag, e.g. "en-gb"
+	Fingerprinted bool   `cel:"fingerprinted"` // The client sent a fingerprint
+	Known         bool   `cel:"known"`         // The user has logged in from it before
+	Trusted       bool   `cel:"trusted"`       // The login presents its trust cookie, so it skips the second factor
+	KnownDevices  int    `cel:"known_devices"` // How many devices the user has logged in from
+}
+
+// Geo is where the IP address is, from the GeoIP database.
+type Geo struct {
+	Located    bool    `cel:"located"` // False when there is no database or the IP is not in it
+	Country    string  `cel:"country"` // ISO 3166-1 alpha-2, e.g. "DE"
+	City       string  `cel:"city"`
+	Latitude   float64 `cel:"latitude"`
+	Longitude  float64 `cel:"longitude"`
+	AccuracyKm int     `cel:"accuracy_km"`
+}
+
+// Velocity holds counters over recent activity. Apart from the attack
+// fields they are 0 at signup.
+type Velocity struct {
+	Failures     int    `cel:"failures"`      // Failed attempts on the account before this login
+	Logins1h     int    `cel:"logins_1h"`     // The user's completed logins in the last hour
+	Logins24h    int    `cel:"logins_24h"`    // ... and in the last 24 hours
+	Countries24h int    `cel:"countries_24h"` // Distinct countries those came from
+	UnderAttack  bool   `cel:"under_attack"`  // A credential stuffing attack covers the client
+	AttackScope  string `cel:"attack_scope"`  // The network under attack, "global" or ""
+}
+
+// Risk is the risk engine's assessment of the login, before the rules
+// run. It is empty at signup and when scoring is disabled.
+type Risk struct {
+	Score    int      `cel:"score"`
+	Decision string   `cel:"decision"` // "allow", "challenge" or "deny"
+	Reasons  []string `cel:"reasons"`  // Reason codes, e.g. "new_device"
+}
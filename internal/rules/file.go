@@ -0,0 +1,83 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+)
+
+// FileSource reads rules from a YAML file:
+//
+//	rules:
+//	  - name: deny-sanctioned-countries
+//	    event: login
+//	    when: geo.country in ["KP", "IR"]
+//	    action: deny
+//	    shadow: true
+//
+// Rules run in the order they are listed. enabled defaults to true.
+type FileSource struct {
+	path string
+}
+
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+// fileRule is a rule as written in the file.
+type fileRule struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Event       string   `yaml:"event"`
+	When        string   `yaml:"when"`
+	Action      string   `yaml:"action"`
+	Tags        []string `yaml:"tags"`
+	Shadow      bool     `yaml:"shadow"`
+	Enabled     *bool    `yaml:"enabled"`
+}
+
+func (s *FileSource) List() ([]models.FraudRule, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Rules []fileRule `yaml:"rules"`
+	}
+	if err := yaml.UnmarshalWithOptions(data, &file, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+
+	defs := make([]models.FraudRule, 0, len(file.Rules))
+	for i, rule := range file.Rules {
+		if rule.Enabled != nil && !*rule.Enabled {
+			continue
+		}
+		defs = append(defs, models.FraudRule{
+			Name:        rule.Name,
+			Description: rule.Description,
+			Event:       rule.Event,
+			Expression:  rule.When,
+			Action:      rule.Action,
+			Tags:        rule.Tags,
+			Shadow:      rule.Shadow,
+			Enabled:     true,
+			Priority:    i,
+		})
+	}
+	return defs, nil
+}
+
+// Version is the file's modification time and size.
+func (s *FileSource) Version() (string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(info.ModTime().UnixNano(), 10) + "/" + strconv.FormatInt(info.Size(), 10), nil
+}
@@ -0,0 +1,77 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeRules(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeRules(t, path, `
+rules:
+  - name: deny-sanctioned-countries
+    event: login
+    when: geo.country in ["KP", "IR"]
+    action: deny
+    shadow: true
+  - name: retired
+    event: login
+    when: "true"
+    action: deny
+    enabled: false
+  - name: tag-disposable
+    event: signup
+    when: user.email_domain == "mailinator.com"
+    action: tag
+    tags: [disposable_email]
+`)
+	source := NewFileSource(path)
+
+	defs, err := source.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("got %d rules, want the 2 enabled ones", len(defs))
+	}
+	if d := defs[0]; d.Name != "deny-sanctioned-countries" || !d.Shadow || !d.Enabled || d.Expression != `geo.country in ["KP", "IR"]` {
+		t.Errorf("first rule %+v", d)
+	}
+	if d := defs[1]; d.Name != "tag-disposable" || d.Priority <= defs[0].Priority || len(d.Tags) != 1 {
+		t.Errorf("second rule %+v, want it after the first with its tag", d)
+	}
+	if _, err := Compile(defs, false); err != nil {
+		t.Errorf("rules from the file do not compile: %v", err)
+	}
+
+	version, err := source.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRules(t, path, "rules: []\n")
+	if changed, err := source.Version(); err != nil || changed == version {
+		t.Errorf("Version = %q, %v after a change; was %q", changed, err, version)
+	}
+}
+
+func TestFileSourceRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeRules(t, path, `
+rules:
+  - name: typo
+    event: login
+    condition: "true"
+    action: deny
+`)
+	if defs, err := NewFileSource(path).List(); err == nil {
+		t.Errorf("got %+v, want an error for the unknown key", defs)
+	}
+}
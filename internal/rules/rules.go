@@ -0,0 +1,262 @@
+// Package rules evaluates fraud rules written by analysts. A rule is a CEL
+// expression (https://cel.dev) over a Context, e.g.
+//
+//	geo.country in ["KP", "IR"] && !device.known
+//
+// with an action to take when it is true. Rules are compiled and type
+// checked when they are loaded, so a typo is reported before a rule runs.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+)
+
+// Events rules run on
+const (
+	EventLogin  = "login"  // A password login, after the password was verified and scored
+	EventSignup = "signup" // An account about to be created
+)
+
+// Actions
+const (
+	ActionAllow     = "allow"     // Let the login through, overriding the risk score
+	ActionChallenge = "challenge" // Require a second factor (logins only)
+	ActionDeny      = "deny"      // Refuse the login or signup
+	ActionTag       = "tag"       // Only add the rule's tags
+)
+
+// Source provides the rules to load: a YAML file (FileSource) or the
+// fraud_rules table.
+type Source interface {
+	// List returns the enabled rules in the order they run.
+	List() ([]models.FraudRule, error)
+
+	// Version changes whenever the rules do, so reloads can be skipped
+	// when nothing changed.
+	Version() (string, error)
+}
+
+// Rule is a compiled rule.
+type Rule struct {
+	models.FraudRule
+	program cel.Program
+}
+
+// Set is a compiled set of rules. It is immutable and safe for concurrent use.
+type Set struct {
+	rules []Rule
+}
+
+// Match is a rule that matched.
+type Match struct {
+	Rule   string
+	Action string
+	Tags   []string
+	Shadow bool // Only logged, not applied
+}
+
+// Outcome is the result of running the rules on an event.
+type Outcome struct {
+	Decision string   // Action of the deciding rule; "" if no allow, challenge or deny rule matched
+	Rule     string   // Name of the deciding rule
+	Tags     []string // Tags of the matching tag rules, without duplicates
+	Matches  []Match  // Every rule that matched, shadow rules included, in order
+}
+
+// Shadowed returns the matches of shadow rules.
+func (o *Outcome) Shadowed() []Match {
+	var shadowed []Match
+	for _, match := range o.Matches {
+		if match.Shadow {
+			shadowed = append(shadowed, match)
+		}
+	}
+	return shadowed
+}
+
+// String summarises the applied part of the outcome for logs and audit
+// events, e.g. "deny by block-tor; tags disposable_email".
+func (o *Outcome) String() string {
+	var parts []string
+	if o.Decision != "" {
+		parts = append(parts, o.Decision+" by "+o.Rule)
+	}
+	if len(o.Tags) > 0 {
+		parts = append(parts, "tags "+strings.Join(o.Tags, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Compile checks and compiles rules, keeping their order. With shadowAll
+// every rule runs in shadow mode. Every invalid rule is reported, not just
+// the first.
+func Compile(defs []models.FraudRule, shadowAll bool) (*Set, error) {
+	env, err := newEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	set := &Set{}
+	var problems []error
+	seen := make(map[string]bool)
+	for _, def := range defs {
+		if err := validate(def); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if seen[def.Name] {
+			problems = append(problems, fmt.Errorf("rule %q: defined twice", def.Name))
+			continue
+		}
+		seen[def.Name] = true
+
+		ast, issues := env.Compile(def.Expression)
+		if issues.Err() != nil {
+			problems = append(problems, fmt.Errorf("rule %q: %w", def.Name, issues.Err()))
+			continue
+		}
+		if ast.OutputType() != cel.BoolType {
+			problems = append(problems, fmt.Errorf("rule %q: expression is %s, want bool", def.Name, ast.OutputType()))
+			continue
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("rule %q: %w", def.Name, err))
+			continue
+		}
+
+		def.Shadow = def.Shadow || shadowAll
+		set.rules = append(set.rules, Rule{FraudRule: def, program: program})
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return set, nil
+}
+
+// Len is the number of rules in the set.
+func (s *Set) Len() int {
+	return len(s.rules)
+}
+
+// Has reports whether any rule runs on event.
+func (s *Set) Has(event string) bool {
+	return slices.ContainsFunc(s.rules, func(r Rule) bool { return r.Event == event })
+}
+
+// Evaluate runs the rules for event in order. The first allow, challenge or
+// deny rule that matches decides; tag rules apply whether they come before
+// or after it, and shadow rules only report their match. A rule that fails
+// at run time (e.g. on integer overflow) counts as not matching; the
+// errors are returned along with the outcome.
+func (s *Set) Evaluate(event string, ctx *Context) (*Outcome, []error) {
+	vars := map[string]any{
+		"user":     &ctx.User,
+		"request":  &ctx.Request,
+		"device":   &ctx.Device,
+		"geo":      &ctx.Geo,
+		"velocity": &ctx.Velocity,
+		"risk":     &ctx.Risk,
+	}
+
+	outcome := &Outcome{}
+	var errs []error
+	for _, rule := range s.rules {
+		if rule.Event != event {
+			continue
+		}
+		// Once decided, only tag and shadow rules have anything left to say
+		if outcome.Decision != "" && rule.Action != ActionTag && !rule.Shadow {
+			continue
+		}
+
+		value, _, err := rule.program.Eval(vars)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %q: %w", rule.Name, err))
+			continue
+		}
+		if matched, _ := value.Value().(bool); !matched {
+			continue
+		}
+
+		outcome.Matches = append(outcome.Matches, Match{
+			Rule:   rule.Name,
+			Action: rule.Action,
+			Tags:   rule.Tags,
+			Shadow: rule.Shadow,
+		})
+		switch {
+		case rule.Shadow:
+		case rule.Action == ActionTag:
+			for _, tag := range rule.Tags {
+				if !slices.Contains(outcome.Tags, tag) {
+					outcome.Tags = append(outcome.Tags, tag)
+				}
+			}
+		default:
+			outcome.Decision = rule.Action
+			outcome.Rule = rule.Name
+		}
+	}
+	return outcome, errs
+}
+
+// newEnv declares the Context variables. The standard CEL string
+// extensions (lowerAscii, split, ...) are available too.
+func newEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		ext.NativeTypes(
+			reflect.TypeOf(User{}), reflect.TypeOf(Request{}), reflect.TypeOf(Device{}),
+			reflect.TypeOf(Geo{}), reflect.TypeOf(Velocity{}), reflect.TypeOf(Risk{}),
+			ext.ParseStructTags(true),
+		),
+		ext.Strings(),
+		cel.Variable("user", cel.ObjectType("rules.User")),
+		cel.Variable("request", cel.ObjectType("rules.Request")),
+		cel.Variable("device", cel.ObjectType("rules.Device")),
+		cel.Variable("geo", cel.ObjectType("rules.Geo")),
+		cel.Variable("velocity", cel.ObjectType("rules.Velocity")),
+		cel.Variable("risk", cel.ObjectType("rules.Risk")),
+	)
+}
+
+// validate checks the fields of a rule other than its expression.
+func validate(def models.FraudRule) error {
+	if def.Name == "" {
+		return errors.New("a rule has no name")
+	}
+	if strings.TrimSpace(def.Expression) == "" {
+		return fmt.Errorf("rule %q: no expression", def.Name)
+	}
+
+	switch def.Event {
+	case EventLogin, EventSignup:
+	default:
+		return fmt.Errorf("rule %q: unknown event %q", def.Name, def.Event)
+	}
+
+	switch def.Action {
+	case ActionAllow, ActionDeny:
+	case ActionChallenge:
+		if def.Event == EventSignup {
+			return fmt.Errorf("rule %q: signups cannot be challenged", def.Name)
+		}
+	case ActionTag:
+		if len(def.Tags) == 0 {
+			return fmt.Errorf("rule %q: tag rule without tags", def.Name)
+		}
+	default:
+		return fmt.Errorf("rule %q: unknown action %q", def.Name, def.Action)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+)
+
+func rule(name, event, expression, action string, tags ...string) models.FraudRule {
+	return models.FraudRule{Name: name, Event: event, Expression: expression, Action: action, Tags: tags, Enabled: true}
+}
+
+func compile(t *testing.T, shadowAll bool, defs ...models.FraudRule) *Set {
+	t.Helper()
+	set, err := Compile(defs, shadowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func TestCompileRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		def  models.FraudRule
+		want string
+	}{
+		{"syntax error", rule("r", EventLogin, `geo.country ==`, ActionDeny), "Syntax error"},
+		{"unknown variable", rule("r", EventLogin, `ip == "192.0.2.1"`, ActionDeny), "undeclared reference"},
+		{"unknown field", rule("r", EventLogin, `geo.region == "BY"`, ActionDeny), "undefined field"},
+		{"type mismatch", rule("r", EventLogin, `velocity.failures > "3"`, ActionDeny), "no matching overload"},
+		{"not a bool", rule("r", EventLogin, `risk.score + 10`, ActionDeny), "want bool"},
+		{"no expression", rule("r", EventLogin, "  ", ActionDeny), "no expression"},
+		{"no name", rule("", EventLogin, `true`, ActionDeny), "no name"},
+		{"unknown event", rule("r", "logout", `true`, ActionDeny), "unknown event"},
+		{"unknown action", rule("r", EventLogin, `true`, "block"), "unknown action"},
+		{"challenged signup", rule("r", EventSignup, `true`, ActionChallenge), "cannot be challenged"},
+		{"tag rule without tags", rule("r", EventLogin, `true`, ActionTag), "without tags"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := Compile([]models.FraudRule{tt.def}, false)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, %v; want an error containing %q", set, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileReportsEveryProblem(t *testing.T) {
+	_, err := Compile([]models.FraudRule{
+		rule("good", EventLogin, `device.known`, ActionAllow),
+		rule("typo", EventLogin, `devise.known`, ActionAllow),
+		rule("good", EventLogin, `true`, ActionDeny),
+		rule("bad-action", EventLogin, `true`, "block"),
+	}, false)
+	if err == nil {
+		t.Fatal("invalid rules compiled")
+	}
+	for _, want := range []string{`rule "typo"`, `rule "good": defined twice`, `rule "bad-action"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	set := compile(t, false,
+		rule("tag-disposable", EventLogin, `user.email_domain in ["mailinator.com"]`, ActionTag, "disposable_email"),
+		rule("allow-office", EventLogin, `request.ip == "192.0.2.10"`, ActionAllow),
+		rule("deny-country", EventLogin, `geo.country in ["KP", "IR"] && !device.known`, ActionDeny),
+		rule("challenge-risky", EventLogin, `risk.score >= 50 || "impossible_travel" in risk.reasons`, ActionChallenge),
+		rule("tag-failures", EventLogin, `velocity.failures > 3`, ActionTag, "guessed", "disposable_email"),
+		rule("deny-signup", EventSignup, `true`, ActionDeny),
+	)
+
+	tests := []struct {
+		name     string
+		ctx      Context
+		decision string
+		rule     string
+		tags     []string
+	}{
+		{"nothing matches", Context{}, "", "", nil},
+		{"first decision wins", Context{Request: Request{IP: "192.0.2.10"}, Geo: Geo{Country: "KP"}}, ActionAllow, "allow-office", nil},
+		{"deny", Context{Geo: Geo{Country: "KP"}}, ActionDeny, "deny-country", nil},
+		{"known device", Context{Geo: Geo{Country: "KP"}, Device: Device{Known: true}}, "", "", nil},
+		{"reason code", Context{Risk: Risk{Reasons: []string{"new_device", "impossible_travel"}}}, ActionChallenge, "challenge-risky", nil},
+		{
+			"tags before and after the decision, once each",
+			Context{User: User{EmailDomain: "mailinator.com"}, Risk: Risk{Score: 70}, Velocity: Velocity{Failures: 5}},
+			ActionChallenge, "challenge-risky", []string{"disposable_email", "guessed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, errs := set.Evaluate(EventLogin, &tt.ctx)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			if outcome.Decision != tt.decision || outcome.Rule != tt.rule {
+				t.Errorf("got %q by %q, want %q by %q", outcome.Decision, outcome.Rule, tt.decision, tt.rule)
+			}
+			if strings.Join(outcome.Tags, ",") != strings.Join(tt.tags, ",") {
+				t.Errorf("got tags %v, want %v", outcome.Tags, tt.tags)
+			}
+		})
+	}
+
+	if !set.Has(EventSignup) || set.Len() != 6 {
+		t.Errorf("Has(signup) = %v, Len() = %d", set.Has(EventSignup), set.Len())
+	}
+	if outcome, _ := set.Evaluate(EventSignup, &Context{}); outcome.Decision != ActionDeny {
+		t.Errorf("signup: got %q, want only the signup rule's deny", outcome.Decision)
+	}
+}
+
+func TestShadowRulesOnlyReport(t *testing.T) {
+	shadowDeny := rule("shadow-deny", EventLogin, `geo.country == "KP"`, ActionDeny)
+	shadowDeny.Shadow = true
+	shadowTag := rule("shadow-tag", EventLogin, `true`, ActionTag, "watched")
+	shadowTag.Shadow = true
+
+	set := compile(t, false,
+		shadowDeny,
+		shadowTag,
+		rule("challenge", EventLogin, `geo.country == "KP"`, ActionChallenge),
+		rule("after-decision", EventLogin, `true`, ActionDeny),
+	)
+
+	outcome, _ := set.Evaluate(EventLogin, &Context{Geo: Geo{Country: "KP"}})
+	if outcome.Decision != ActionChallenge || len(outcome.Tags) != 0 {
+		t.Errorf("got %s, want the challenge without shadow tags", outcome)
+	}
+
+	var shadowed []string
+	for _, match := range outcome.Shadowed() {
+		shadowed = append(shadowed, match.Rule+":"+match.Action)
+	}
+	if got := strings.Join(shadowed, ","); got != "shadow-deny:deny,shadow-tag:tag" {
+		t.Errorf("shadow matches %s", got)
+	}
+	if len(outcome.Matches) != 3 {
+		t.Errorf("got matches %+v, want the two shadow rules and the challenge", outcome.Matches)
+	}
+}
+
+func TestShadowAll(t *testing.T) {
+	set := compile(t, true,
+		rule("deny", EventLogin, `true`, ActionDeny),
+		rule("tag", EventLogin, `true`, ActionTag, "seen"),
+	)
+
+	outcome, _ := set.Evaluate(EventLogin, &Context{})
+	if outcome.Decision != "" || len(outcome.Tags) != 0 {
+		t.Errorf("got %s, want nothing applied in shadow mode", outcome)
+	}
+	if len(outcome.Shadowed()) != 2 {
+		t.Errorf("got %d shadow matches, want 2", len(outcome.Shadowed()))
+	}
+}
+
+func TestEvaluateErrorsDoNotMatch(t *testing.T) {
+	set := compile(t, false,
+		rule("overflow", EventLogin, `velocity.failures * 9223372036854775807 > 0`, ActionDeny),
+		rule("fallback", EventLogin, `velocity.failures > 0`, ActionChallenge),
+	)
+
+	outcome, errs := set.Evaluate(EventLogin, &Context{Velocity: Velocity{Failures: 2}})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `rule "overflow"`) {
+		t.Errorf("got errors %v, want the overflow", errs)
+	}
+	if outcome.Decision != ActionChallenge {
+		t.Errorf("got %s, want the rule after the failing one to decide", outcome)
+	}
+}
+
+func TestOutcomeString(t *testing.T) {
+	outcome := &Outcome{Decision: ActionDeny, Rule: "block-tor", Tags: []string{"tor", "vpn"}}
+	if got := outcome.String(); got != "deny by block-tor; tags tor, vpn" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (&Outcome{}).String(); got != "" {
+		t.Errorf("empty outcome String() = %q", got)
+	}
+}
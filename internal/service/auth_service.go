@@ -1,49 +1,136 @@
 package service
 
 import (
+	"database/sql"
 	"errors"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/abhay786-20/fraud-auth-service/internal/models"
 	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/password"
 	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrEmailNotVerified    = errors.New("email address not verified")
+)
+
+// How logins of users with an unverified email address are treated
+const (
+	UnverifiedLoginAllow    = "allow"    // Full tokens, as for verified users
+	UnverifiedLoginRestrict = "restrict" // Restricted tokens that only reach the account's own endpoints
+	UnverifiedLoginDeny     = "deny"     // Login is refused until the address is verified
+)
+
+// refreshTokenBytes is the entropy of an opaque refresh token.
+const refreshTokenBytes = 32
+
+// TokenPair is what a successful login or refresh hands back to the client.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	IDToken      string // Empty when the client did not request the openid scope
+	Scope        string
+	FamilyID     string // Refresh token family, for linking back to the grant
+	ExpiresIn    time.Duration
+}
+
+// IssueOptions describe the OAuth context tokens are issued in.
+// The zero value issues first-party tokens, as for /api/v1/auth/login.
+type IssueOptions struct {
+	ClientID string           // OAuth client the tokens are issued to
+	Scope    string           // Space-delimited granted scope
+	Nonce    string           // OIDC nonce to echo in the ID token
+	AMR      []string         // Authentication methods used, e.g. {"pwd", "otp", "mfa"}
+	Risk     *utils.RiskClaim // Risk assessment of the login; nil if it was not scored
+	AuthTime time.Time        // When the user authenticated; zero means now
+	FamilyID string           // ID of the refresh token family to start; "" generates one
+}
+
+// wantsIDToken reports whether an ID token should accompany the access token.
+func (o IssueOptions) wantsIDToken() bool {
+	return o.ClientID == "" || utils.HasScope(o.Scope, "openid")
+}
+
+// TokenConfig controls the tokens minted by AuthService.
+type TokenConfig struct {
+	Issuer          string        // "iss" claim; also the OIDC issuer identifier
+	Audience        string        // "aud" claim of access tokens
+	AccessTokenTTL  time.Duration // Lifetime of access and ID tokens
+	RefreshTokenTTL time.Duration // Lifetime of each refresh token
+	UnverifiedLogin string        // UnverifiedLoginAllow, UnverifiedLoginRestrict or UnverifiedLoginDeny
+}
 
 type AuthService struct {
-	userRepo  repository.UserRepository
-	jwtSecret string
-	tokenTTL  time.Duration
+	userRepo    repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	revocations repository.RevocationStore
+	lockout     *LockoutService
+	risk        *RiskService
+	stuffing    *StuffingService
+	policy      *password.Policy
+	hasher      password.Hasher
+	log         *logger.Logger
+	keys        utils.KeySet
+	cfg         TokenConfig
 }
 
 func NewAuthService(
 	userRepo repository.UserRepository,
-	jwtSecret string,
-	tokenTTL time.Duration,
+	refreshRepo repository.RefreshTokenRepository,
+	revocations repository.RevocationStore,
+	lockout *LockoutService,
+	risk *RiskService,
+	stuffing *StuffingService,
+	policy *password.Policy,
+	hasher password.Hasher,
+	log *logger.Logger,
+	keys utils.KeySet,
+	cfg TokenConfig,
 ) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
-		tokenTTL:  tokenTTL,
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		revocations: revocations,
+		lockout:     lockout,
+		risk:        risk,
+		stuffing:    stuffing,
+		policy:      policy,
+		hasher:      hasher,
+		log:         log,
+		keys:        keys,
+		cfg:         cfg,
 	}
 }
 
-func (s *AuthService) Signup(email, password string) (*models.User, error) {
+// Signup creates an account, once the password passes the policy and the
+// signup fraud rules let it through.
+func (s *AuthService) Signup(email, password string, origin risk.Request) (*models.User, error) {
+
+	if err := s.ValidatePassword(password, email); err != nil {
+		return nil, err
+	}
+
+	if err := s.risk.ScreenSignup(email, origin, s.stuffing.UnderAttack(origin.IP)); err != nil {
+		return nil, err
+	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword(
-		[]byte(password),
-		bcrypt.DefaultCost,
-	)
+	hashedPassword, err := s.hasher.Hash(password)
 	if err != nil {
 		return nil, err
 	}
 
 	user := &models.User{
 		Email:    email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 	}
 
 	// Save to DB
@@ -56,31 +143,464 @@ func (s *AuthService) Signup(email, password string) (*models.User, error) {
 
 }
 
+// Login checks the user's password and scores the login for risk. The
+// assessment is nil when scoring is disabled; a challenge decision must be
+// met with a second factor (see StepUpUnavailable for users without one),
+// and denied logins fail with ErrLoginDenied. Callers answer ErrLoginDenied
+// like ErrInvalidCredentials, so it does not confirm the password.
+func (s *AuthService) Login(email, password string, origin risk.Request) (*models.User, *risk.Assessment, error) {
 
-func (s *AuthService) Login(email, password string) (*models.User, error) {
-
+	// Failures count towards credential stuffing detection whether or not
+	// the account exists: attackers' lists are full of unknown emails
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		s.stuffing.RecordFailure(origin, password)
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	err = bcrypt.CompareHashAndPassword(
-		[]byte(user.Password),
-		[]byte(password),
-	)
+	failures, err := s.authenticate(user, password)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.stuffing.RecordFailure(origin, password)
+		}
+		return nil, nil, err
 	}
 
-	return user, nil
+	// Callers answer it like a wrong password, or it would confirm both
+	// the account and the password to strangers
+	if s.cfg.UnverifiedLogin == UnverifiedLoginDeny && !user.IsEmailVerified() {
+		s.log.Info("Login refused for unverified user " + user.ID)
+		return nil, nil, ErrEmailNotVerified
+	}
+
+	assessment, err := s.risk.Assess(user, origin, failures, s.stuffing.UnderAttack(origin.IP))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, assessment, nil
+}
+
+// StepUpUnavailable decides a challenged login of a user without a second
+// factor: it returns ErrLoginDenied unless configured to let it through and
+// no credential stuffing attack covers the client.
+func (s *AuthService) StepUpUnavailable(user *models.User, assessment *risk.Assessment) error {
+	return s.risk.StepUpUnavailable(user.ID, assessment)
+}
+
+// RecordLogin adds a completed interactive login to the user's history,
+// which later risk assessments compare against, and forgets the failed
+// attempts before it. Until then a correct password alone does not reset
+// the lockout, so second-factor guesses keep counting towards it.
+func (s *AuthService) RecordLogin(user *models.User, origin risk.Request, claim *utils.RiskClaim) {
+	if err := s.lockout.RecordSuccess(user.ID); err != nil {
+		s.log.Error("Failed to clear failed logins of user " + user.ID + ": " + err.Error())
+	}
+	s.risk.RecordLogin(user, origin, claim)
+}
+
+// SetPassword replaces the user's password after checking it against the
+// password policy. Existing sessions are left alone; callers decide which
+// of them to revoke.
+func (s *AuthService) SetPassword(user *models.User, password string) error {
+	if err := s.ValidatePassword(password, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.UpdatePassword(user.ID, hashedPassword)
+}
+
+// ValidatePassword checks a new password for the account registered under
+// email against the password policy. Rejections are *password.PolicyError.
+func (s *AuthService) ValidatePassword(password, email string) error {
+	return s.policy.Check(password, email)
+}
+
+// RequiresVerifiedEmail reports whether the user must verify their email
+// before getting unrestricted tokens.
+func (s *AuthService) RequiresVerifiedEmail(user *models.User) bool {
+	return s.cfg.UnverifiedLogin != UnverifiedLoginAllow && !user.IsEmailVerified()
+}
+
+func (s *AuthService) GenerateToken(user *models.User, opts IssueOptions) (string, error) {
+	claims := &utils.Claims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		ClientID: opts.ClientID,
+		Scope:    opts.Scope,
+		AMR:      opts.AMR,
+		Risk:     opts.Risk,
+
+		Restricted: s.RequiresVerifiedEmail(user),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   s.cfg.Issuer,
+			Subject:  user.ID,
+			Audience: jwt.ClaimStrings{s.cfg.Audience},
+		},
+	}
+	return utils.GenerateToken(claims, s.keys, s.cfg.AccessTokenTTL)
+}
+
+// GenerateIDToken mints an OpenID Connect ID token. It is addressed to the
+// OAuth client, or to the platform audience for first-party logins.
+func (s *AuthService) GenerateIDToken(user *models.User, opts IssueOptions) (string, error) {
+	audience := opts.ClientID
+	if audience == "" {
+		audience = s.cfg.Audience
+	}
+
+	claims := &utils.IDTokenClaims{
+		Email:    user.Email,
+		Nonce:    opts.Nonce,
+		AuthTime: jwt.NewNumericDate(opts.AuthTime),
+		AMR:      opts.AMR,
+
+		EmailVerified: user.IsEmailVerified(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   s.cfg.Issuer,
+			Subject:  user.ID,
+			Audience: jwt.ClaimStrings{audience},
+		},
+	}
+	return utils.GenerateIDToken(claims, s.keys, s.cfg.AccessTokenTTL)
+}
+
+// GenerateServiceToken mints a client_credentials access token. The subject
+// is the client ID and no refresh token is issued; services simply request a
+// new token when this one expires.
+func (s *AuthService) GenerateServiceToken(client *models.OAuthClient, scope string) (*TokenPair, error) {
+	claims := &utils.Claims{
+		ClientID: client.ID,
+		Scope:    scope,
+		TokenUse: utils.TokenUseService,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   s.cfg.Issuer,
+			Subject:  client.ID,
+			Audience: jwt.ClaimStrings{s.cfg.Audience},
+		},
+	}
+
+	token, err := utils.GenerateToken(claims, s.keys, s.cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken: token,
+		Scope:       scope,
+		ExpiresIn:   s.cfg.AccessTokenTTL,
+	}, nil
+}
+
+// GetUser loads the current record of an authenticated user.
+func (s *AuthService) GetUser(userID string) (*models.User, error) {
+	return s.userRepo.GetByID(userID)
+}
+
+// IssueTokens starts a new refresh token family for a freshly authenticated user.
+func (s *AuthService) IssueTokens(user *models.User, opts IssueOptions) (*TokenPair, error) {
+	if opts.AuthTime.IsZero() {
+		opts.AuthTime = time.Now()
+	}
+	return s.issueTokenPair(user, opts, opts.FamilyID, nil)
+}
+
+// Refresh rotates a refresh token: the presented token is consumed and a new
+// pair is issued in the same family. Presenting a token that was already
+// rotated means it was copied by someone else, so the whole family is revoked.
+//
+// clientID must match the client the token was issued to ("" for first-party
+// logins), so a token cannot be redeemed by a different client.
+func (s *AuthService) Refresh(rawToken, clientID string) (*TokenPair, error) {
+	current, err := s.refreshRepo.GetByHash(utils.HashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if current.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	issuedTo := ""
+	if current.ClientID != nil {
+		issuedTo = *current.ClientID
+	}
+	if issuedTo != clientID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if current.UsedAt != nil {
+		return nil, s.handleReuse(current)
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	consumed, err := s.refreshRepo.MarkUsed(current.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		// Lost the race against another request presenting the same token
+		return nil, s.handleReuse(current)
+	}
+
+	user, err := s.userRepo.GetByID(current.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	opts := IssueOptions{
+		ClientID: issuedTo,
+		Scope:    current.Scope,
+		AMR:      current.AMR,
+		Risk:     loadedRisk(current.LoginRisk),
+		AuthTime: current.AuthTime, // Refreshing is not authenticating again
+	}
+	return s.issueTokenPair(user, opts, current.FamilyID, &current.ID)
 }
 
-func (s *AuthService) GenerateToken(user *models.User) (string, error) {
-	return utils.GenerateToken(
-		user.ID,
-		user.Email,
-		s.jwtSecret,
-		s.tokenTTL,
+// ValidateToken parses and verifies an access token issued by this service,
+// rejecting tokens that were revoked individually or by a per-user cutoff.
+func (s *AuthService) ValidateToken(token string) (*utils.Claims, error) {
+	claims, err := utils.ParseToken(
+		token,
+		s.keys,
+		jwt.WithIssuer(s.cfg.Issuer),
+		jwt.WithAudience(s.cfg.Audience),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ID != "" {
+		revoked, err := s.revocations.IsRevoked(claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, utils.ErrRevokedToken
+		}
+	}
+
+	// Per-user cutoffs do not apply to service tokens
+	if claims.UserID == "" {
+		return claims, nil
+	}
+
+	validAfter, err := s.revocations.TokensValidAfter(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// iat only has second precision, so a token issued in the second of the
+	// cutoff cannot be told apart from one issued just before it. LogoutAll
+	// stores whole seconds, and any fraction is rounded up to be safe.
+	validAfter = validAfter.Add(time.Second - 1).Truncate(time.Second)
+	if claims.IssuedAt != nil && claims.IssuedAt.Time.Before(validAfter) {
+		return nil, utils.ErrRevokedToken
+	}
+
+	return claims, nil
+}
+
+// Logout revokes the access token described by claims and, when given,
+// the refresh token family it was issued with.
+func (s *AuthService) Logout(claims *utils.Claims, rawRefreshToken string) error {
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.revocations.Revoke(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+
+	if rawRefreshToken == "" {
+		return nil
+	}
+
+	refresh, err := s.refreshRepo.GetByHash(utils.HashToken(rawRefreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	// Never let one user revoke another user's session
+	if refresh.UserID != claims.UserID {
+		return nil
+	}
+
+	return s.refreshRepo.RevokeFamily(refresh.FamilyID)
+}
+
+// LogoutAll invalidates every access and refresh token the user currently
+// holds. Access tokens are cut off at the next whole second, since iat has
+// no finer precision, and LogoutAll returns once it has passed so that
+// tokens issued afterwards are valid.
+func (s *AuthService) LogoutAll(userID string) error {
+	cutoff := time.Now().Truncate(time.Second).Add(time.Second)
+	if err := s.revocations.SetTokensValidAfter(userID, cutoff); err != nil {
+		return err
+	}
+	time.Sleep(time.Until(cutoff))
+
+	if err := s.refreshRepo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+
+	s.log.Info("All sessions revoked for user " + userID)
+	return nil
+}
+
+// authenticate checks the user's password under lockout protection. Locked
+// and throttled accounts get ErrInvalidCredentials without the password
+// being looked at, so the response never tells them apart from a typo.
+// On success it returns the failures that preceded the correct password,
+// nil if there were none; they are cleared by RecordLogin once the login is
+// complete.
+func (s *AuthService) authenticate(user *models.User, password string) (*models.LoginFailures, error) {
+	failures, attempt, err := s.lockout.Reserve(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		s.log.Info("Login refused for locked or throttled user " + user.ID)
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.checkPassword(user, password); err != nil {
+		if recordErr := s.lockout.RecordFailure(user.ID); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+	if err := s.lockout.Release(attempt); err != nil {
+		return nil, err
+	}
+
+	s.upgradeHash(user, password)
+	return failures, nil
+}
+
+// checkPassword returns ErrInvalidCredentials unless plaintext is the user's password.
+func (s *AuthService) checkPassword(user *models.User, plaintext string) error {
+	ok, err := password.Verify(user.Password, plaintext)
+	if err != nil {
+		s.log.Error("Cannot verify password hash of user " + user.ID + ": " + err.Error())
+		return ErrInvalidCredentials
+	}
+	if !ok {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// upgradeHash replaces a hash made with another algorithm or weaker
+// parameters than the configured ones, while the plaintext is at hand after
+// a successful login. The login succeeds even if the upgrade fails; it is
+// tried again next time.
+func (s *AuthService) upgradeHash(user *models.User, plaintext string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashed, err := s.hasher.Hash(plaintext)
+	if err == nil {
+		err = s.userRepo.UpdatePassword(user.ID, hashed)
+	}
+	if err != nil {
+		s.log.Error("Failed to upgrade password hash of user " + user.ID + ": " + err.Error())
+		return
+	}
+
+	s.log.Info("Upgraded password hash of user " + user.ID + " to " + s.hasher.Algorithm())
+	user.Password = hashed
 }
 
+func (s *AuthService) handleReuse(token *models.RefreshToken) error {
+	s.log.Warn("Refresh token reuse detected for user " + token.UserID +
+		" (family " + token.FamilyID + "); revoking token family")
+
+	if err := s.refreshRepo.RevokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// InspectRefreshToken looks up a refresh token and reports whether it could
+// still be redeemed.
+func (s *AuthService) InspectRefreshToken(rawToken string) (*models.RefreshToken, bool, error) {
+	token, err := s.refreshRepo.GetByHash(utils.HashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	active := token.RevokedAt == nil && token.UsedAt == nil && time.Now().Before(token.ExpiresAt)
+	return token, active, nil
+}
+
+// RevokeFamily revokes every refresh token descending from one grant.
+func (s *AuthService) RevokeFamily(familyID string) error {
+	return s.refreshRepo.RevokeFamily(familyID)
+}
+
+func (s *AuthService) issueTokenPair(user *models.User, opts IssueOptions, familyID string, parentID *string) (*TokenPair, error) {
+	accessToken, err := s.GenerateToken(user, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var idToken string
+	if opts.wantsIDToken() {
+		idToken, err = s.GenerateIDToken(user, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rawRefresh, err := utils.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var clientID *string
+	if opts.ClientID != "" {
+		clientID = &opts.ClientID
+	}
+
+	refresh := &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ParentID:  parentID,
+		ClientID:  clientID,
+		Scope:     opts.Scope,
+		AMR:       opts.AMR,
+		AuthTime:  opts.AuthTime,
+		TokenHash: utils.HashToken(rawRefresh),
+		ExpiresAt: time.Now().Add(s.cfg.RefreshTokenTTL),
+		LoginRisk: storedRisk(opts.Risk),
+	}
+	if err := s.refreshRepo.Create(refresh); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: rawRefresh,
+		IDToken:      idToken,
+		Scope:        opts.Scope,
+		FamilyID:     refresh.FamilyID,
+		ExpiresIn:    s.cfg.AccessTokenTTL,
+	}, nil
+}
@@ -0,0 +1,514 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/password"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+// memoryUsers is a UserRepository kept in a map by ID.
+type memoryUsers struct {
+	mu    sync.Mutex
+	users map[string]*models.User
+}
+
+func newMemoryUsers(users ...*models.User) *memoryUsers {
+	r := &memoryUsers{users: make(map[string]*models.User)}
+	for _, u := range users {
+		r.users[u.ID] = u
+	}
+	return r
+}
+
+func (r *memoryUsers) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.ID = "user-" + strconv.Itoa(len(r.users)+1)
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *memoryUsers) GetByEmail(email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == email {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryUsers) GetByID(id string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (r *memoryUsers) MarkEmailVerified(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.users[id].EmailVerifiedAt = &now
+	return nil
+}
+
+func (r *memoryUsers) UpdatePassword(id, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[id].Password = passwordHash
+	return nil
+}
+
+func (r *memoryUsers) Import(user *models.User) (bool, error) {
+	if _, err := r.GetByEmail(user.Email); err == nil {
+		return false, nil
+	}
+	return true, r.Create(user)
+}
+
+// memoryRefreshTokens is a RefreshTokenRepository kept in a map by ID.
+type memoryRefreshTokens struct {
+	mu     sync.Mutex
+	tokens map[string]*models.RefreshToken
+}
+
+func newMemoryRefreshTokens() *memoryRefreshTokens {
+	return &memoryRefreshTokens{tokens: make(map[string]*models.RefreshToken)}
+}
+
+func (r *memoryRefreshTokens) Create(token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = "rt-" + strconv.Itoa(len(r.tokens)+1)
+	if token.FamilyID == "" {
+		token.FamilyID = "family-" + token.ID
+	}
+	token.CreatedAt = time.Now()
+	copied := *token
+	r.tokens[token.ID] = &copied
+	return nil
+}
+
+func (r *memoryRefreshTokens) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tokens {
+		if t.TokenHash == tokenHash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRefreshTokens) MarkUsed(id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.tokens[id]
+	if t.UsedAt != nil || t.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	return true, nil
+}
+
+func (r *memoryRefreshTokens) RevokeFamily(familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, t := range r.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memoryRefreshTokens) RevokeAllForUser(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memoryRefreshTokens) get(t *testing.T, raw string) *models.RefreshToken {
+	t.Helper()
+	token, err := r.GetByHash(utils.HashToken(raw))
+	if err != nil {
+		t.Fatalf("refresh token not stored: %v", err)
+	}
+	return token
+}
+
+var testUser = &models.User{ID: "user-1", Email: "alice@example.com", Role: "user"}
+
+func newTestAuthService(t *testing.T) (*AuthService, *memoryRefreshTokens) {
+	t.Helper()
+	refresh := newMemoryRefreshTokens()
+	s := NewAuthService(
+		newMemoryUsers(testUser),
+		refresh,
+		nil, nil, nil, nil, nil, nil,
+		logger.New(),
+		utils.NewKeyRing(utils.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))),
+		TokenConfig{
+			Issuer:          "https://auth.example.com",
+			Audience:        "platform",
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 24 * time.Hour,
+			UnverifiedLogin: UnverifiedLoginAllow,
+		},
+	)
+	return s, refresh
+}
+
+// authTime reads the auth_time claim of an ID token.
+func authTime(t *testing.T, idToken string) time.Time {
+	t.Helper()
+	var claims utils.IDTokenClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(idToken, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.AuthTime == nil {
+		t.Fatal("ID token without auth_time")
+	}
+	return claims.AuthTime.Time
+}
+
+func TestRefreshRotatesWithinTheFamily(t *testing.T) {
+	s, repo := newTestAuthService(t)
+
+	loggedIn := time.Now().Add(-time.Hour).Truncate(time.Second)
+	first, err := s.IssueTokens(testUser, IssueOptions{AMR: []string{models.AMRPassword}, AuthTime: loggedIn})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.Refresh(first.RefreshToken, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Fatal("Refresh did not issue a new pair")
+	}
+	if second.FamilyID != first.FamilyID {
+		t.Errorf("rotated token in family %q, want %q", second.FamilyID, first.FamilyID)
+	}
+
+	old, rotated := repo.get(t, first.RefreshToken), repo.get(t, second.RefreshToken)
+	if old.UsedAt == nil {
+		t.Error("the presented token was not consumed")
+	}
+	if rotated.ParentID == nil || *rotated.ParentID != old.ID {
+		t.Errorf("rotated token has parent %v, want %s", rotated.ParentID, old.ID)
+	}
+	if len(rotated.AMR) != 1 || rotated.AMR[0] != models.AMRPassword {
+		t.Errorf("rotated token has AMR %v, want the original login's", rotated.AMR)
+	}
+
+	// Refreshing is not authenticating again
+	if got := authTime(t, second.IDToken); !got.Equal(loggedIn) {
+		t.Errorf("refreshed ID token has auth_time %s, want %s", got, loggedIn)
+	}
+
+	third, err := s.Refresh(second.RefreshToken, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.FamilyID != first.FamilyID {
+		t.Errorf("second rotation in family %q, want %q", third.FamilyID, first.FamilyID)
+	}
+}
+
+func TestRefreshReuseRevokesTheFamily(t *testing.T) {
+	s, repo := newTestAuthService(t)
+
+	first, err := s.IssueTokens(testUser, IssueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Refresh(first.RefreshToken, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another login's family is left alone
+	other, err := s.IssueTokens(testUser, IssueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Refresh(first.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replaying a rotated token: got %v, want ErrRefreshTokenReused", err)
+	}
+	if repo.get(t, second.RefreshToken).RevokedAt == nil {
+		t.Error("the family's current token survived the reuse")
+	}
+	if _, err := s.Refresh(second.RefreshToken, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refreshing after reuse: got %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := s.Refresh(other.RefreshToken, ""); err != nil {
+		t.Errorf("another family was revoked: %v", err)
+	}
+}
+
+func TestConcurrentRefreshOnlyOneWins(t *testing.T) {
+	s, _ := newTestAuthService(t)
+
+	pair, err := s.IssueTokens(testUser, IssueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 8
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Refresh(pair.RefreshToken, "")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// Losers that arrive once the family is revoked find the token invalid
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrRefreshTokenReused) && !errors.Is(err, ErrInvalidRefreshToken):
+			t.Errorf("got %v, want the losers refused", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d refreshes succeeded, want exactly 1", succeeded)
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	s, repo := newTestAuthService(t)
+
+	firstParty, err := s.IssueTokens(testUser, IssueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forClient, err := s.IssueTokens(testUser, IssueOptions{ClientID: "console", Scope: "openid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.IssueTokens(testUser, IssueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.tokens[repo.get(t, expired.RefreshToken).ID].ExpiresAt = time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name     string
+		token    string
+		clientID string
+	}{
+		{"unknown token", "not-a-token", ""},
+		{"first-party token at a client", firstParty.RefreshToken, "console"},
+		{"client token without the client", forClient.RefreshToken, ""},
+		{"client token at another client", forClient.RefreshToken, "mobile"},
+		{"expired token", expired.RefreshToken, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Refresh(tt.token, tt.clientID); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("got %v, want ErrInvalidRefreshToken", err)
+			}
+		})
+	}
+
+	// A token presented by the wrong client is not consumed
+	if _, err := s.Refresh(forClient.RefreshToken, "console"); err != nil {
+		t.Errorf("the right client cannot refresh: %v", err)
+	}
+}
+
+func TestLogoutRevokesOnlyTheOwnersFamily(t *testing.T) {
+	s, repo := newTestAuthService(t)
+
+	pair, err := s.IssueTokens(testUser, IssueOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone else's refresh token is ignored
+	if err := s.Logout(&utils.Claims{UserID: "user-2"}, pair.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if repo.get(t, pair.RefreshToken).RevokedAt != nil {
+		t.Fatal("another user revoked the session")
+	}
+
+	if err := s.Logout(&utils.Claims{UserID: testUser.ID}, pair.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(pair.RefreshToken, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refreshing after logout: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestLoginUpgradesTheHash(t *testing.T) {
+	hasher, err := password.NewArgon2Hasher(password.Argon2Params{Memory: 64, Time: 1, Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	weaker, err := password.NewArgon2Hasher(password.Argon2Params{Memory: 32, Time: 1, Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakHash, err := weaker.Hash("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Django's PBKDF2 hash of "hunter2" with the salt "saltysalt"
+	legacyHash, err := password.Import(password.AlgorithmPBKDF2SHA256,
+		"pbkdf2_sha256$1000$saltysalt$4idrNgJDcgpjZOduHmFH8FbAQXA5uaxdDE8hj0bMuew=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		stored  string
+		upgrade bool
+	}{
+		{"legacy PBKDF2", legacyHash, true},
+		{"weaker argon2id", weakHash, true},
+		{"current argon2id", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := tt.stored
+			if stored == "" {
+				if stored, err = hasher.Hash("hunter2"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			users := newMemoryUsers(&models.User{ID: "user-1", Email: "alice@example.com", Password: stored})
+			log := logger.New()
+			s := NewAuthService(
+				users,
+				newMemoryRefreshTokens(),
+				nil,
+				NewLockoutService(newMemoryLoginFailures(), users, discardAudit{}, log, LockoutConfig{}),
+				NewRiskService(nil, NewRulesService(nil, log, RulesConfig{}), nil, nil, nil, discardAudit{}, log, RiskConfig{}),
+				NewStuffingService(nil, discardAudit{}, log),
+				nil,
+				hasher,
+				log,
+				utils.NewKeyRing(utils.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))),
+				TokenConfig{UnverifiedLogin: UnverifiedLoginAllow},
+			)
+
+			// A wrong password changes nothing
+			if _, _, err := s.Login("alice@example.com", "hunter3", risk.Request{}); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("wrong password: got %v, want ErrInvalidCredentials", err)
+			}
+			if users.users["user-1"].Password != stored {
+				t.Fatal("a failed login changed the hash")
+			}
+
+			if _, _, err := s.Login("alice@example.com", "hunter2", risk.Request{}); err != nil {
+				t.Fatal(err)
+			}
+			upgraded := users.users["user-1"].Password
+			if changed := upgraded != stored; changed != tt.upgrade {
+				t.Fatalf("hash changed %v, want %v", changed, tt.upgrade)
+			}
+			if hasher.NeedsRehash(upgraded) {
+				t.Errorf("hash %q still needs a rehash", upgraded)
+			}
+
+			// The new hash verifies the same password
+			if _, _, err := s.Login("alice@example.com", "hunter2", risk.Request{}); err != nil {
+				t.Errorf("login after the upgrade: %v", err)
+			}
+		})
+	}
+}
+
+func TestLogoutAllRevokesTokensFromTheSameSecond(t *testing.T) {
+	s, _ := newTestAuthService(t)
+	s.revocations = repository.NewMemoryRevocationStore()
+
+	// Start at the top of a second so the token and the logout share it
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	before, err := s.IssueTokens(testUser, IssueOptions{AMR: []string{models.AMRPassword}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ValidateToken(before.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.LogoutAll(testUser.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ValidateToken(before.AccessToken); !errors.Is(err, utils.ErrRevokedToken) {
+		t.Errorf("token from the second of the logout: got %v, want ErrRevokedToken", err)
+	}
+
+	after, err := s.IssueTokens(testUser, IssueOptions{AMR: []string{models.AMRPassword}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ValidateToken(after.AccessToken); err != nil {
+		t.Errorf("token issued after the logout: %v", err)
+	}
+}
+
+func TestValidateTokenRoundsTheCutoffUp(t *testing.T) {
+	s, _ := newTestAuthService(t)
+	s.revocations = repository.NewMemoryRevocationStore()
+
+	tokens, err := s.IssueTokens(testUser, IssueOptions{AMR: []string{models.AMRPassword}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.ValidateToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A cutoff stored with a fraction, later in the second the token was issued
+	if err := s.revocations.SetTokensValidAfter(testUser.ID, claims.IssuedAt.Add(time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ValidateToken(tokens.AccessToken); !errors.Is(err, utils.ErrRevokedToken) {
+		t.Errorf("got %v, want ErrRevokedToken", err)
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/device"
+	"github.com/abhay786-20/fraud-auth-service/internal/geoip"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/mailer"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+var ErrDeviceNotFound = errors.New("device not found")
+
+// deviceTokenBytes is the entropy of a device trust token.
+const deviceTokenBytes = 32
+
+// DeviceConfig controls DeviceService.
+type DeviceConfig struct {
+	TrustTTL  time.Duration // How long a trusted device skips the second factor; 0 disables trusting
+	NotifyNew bool          // Email users when a login completes on a device they never used
+}
+
+// DeviceService keeps the registry of devices each user logs in from. A
+// device becomes known once a login from it completes; users can trust it
+// after a second factor so later logins from it skip MFA for a while.
+//
+// Devices are recognised by a fingerprint or their headers, which any
+// client can copy, so recognition only feeds risk scoring. Trust is bound
+// to a random token issued to the device instead.
+type DeviceService struct {
+	repo   repository.DeviceRepository
+	mailer mailer.Mailer
+	log    *logger.Logger
+	cfg    DeviceConfig
+}
+
+func NewDeviceService(
+	repo repository.DeviceRepository,
+	mailer mailer.Mailer,
+	log *logger.Logger,
+	cfg DeviceConfig,
+) *DeviceService {
+	return &DeviceService{
+		repo:   repo,
+		mailer: mailer,
+		log:    log,
+		cfg:    cfg,
+	}
+}
+
+// DeviceID identifies the device a login comes from.
+func DeviceID(origin risk.Request) string {
+	return device.ID(origin.Fingerprint, device.Parse(origin.UserAgent, origin.AcceptLanguage))
+}
+
+// Known returns the user's registered device matching origin, nil if there
+// is none, along with how many devices the user has.
+func (s *DeviceService) Known(userID string, origin risk.Request) (*models.UserDevice, int, error) {
+	count, err := s.repo.Count(userID)
+	if err != nil || count == 0 {
+		return nil, count, err
+	}
+
+	known, err := s.repo.Get(userID, DeviceID(origin))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, count, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return known, count, nil
+}
+
+// Seen records a completed login from origin. The first login from a new
+// device is announced to the user by email, unless it is their first device
+// at all. The login has already succeeded, so failures are only logged.
+func (s *DeviceService) Seen(user *models.User, origin risk.Request, location *geoip.Location) {
+	features := device.Parse(origin.UserAgent, origin.AcceptLanguage)
+	seen := &models.UserDevice{
+		UserID:     user.ID,
+		DeviceHash: device.ID(origin.Fingerprint, features),
+		Name:       features.Name(),
+		UserAgent:  origin.UserAgent,
+		LastIP:     origin.IP,
+	}
+
+	created, err := s.repo.Touch(seen)
+	if err != nil {
+		s.log.Error("Failed to record device of user " + user.ID + ": " + err.Error())
+		return
+	}
+	if !created || !s.cfg.NotifyNew {
+		return
+	}
+
+	count, err := s.repo.Count(user.ID)
+	if err != nil {
+		s.log.Error("Failed to count devices of user " + user.ID + ": " + err.Error())
+		return
+	}
+	if count > 1 {
+		s.notifyNew(user, seen, location)
+	}
+}
+
+// Trust lets the device of origin skip the second factor for the configured
+// period. It returns the trust token the device must present at later
+// logins and when it expires; only a hash of the token is stored. Trusting
+// again replaces the device's token. The token is "" when trusting devices
+// is disabled.
+func (s *DeviceService) Trust(user *models.User, origin risk.Request) (string, time.Time, error) {
+	if s.cfg.TrustTTL <= 0 {
+		return "", time.Time{}, nil
+	}
+
+	token, err := utils.GenerateRandomToken(deviceTokenBytes)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	until := time.Now().Add(s.cfg.TrustTTL)
+
+	trusted, err := s.repo.Trust(user.ID, DeviceID(origin), utils.HashToken(token), until)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !trusted {
+		return "", time.Time{}, ErrDeviceNotFound
+	}
+
+	s.log.Info("User " + user.ID + " trusted a device for " + durationText(s.cfg.TrustTTL))
+	return token, until, nil
+}
+
+// SkipsSecondFactor reports whether a login may go without MFA because it
+// presents the trust token of one of the user's trusted devices. Logins the
+// risk engine challenged or denied never do.
+func (s *DeviceService) SkipsSecondFactor(userID string, origin risk.Request, assessment *risk.Assessment) (bool, error) {
+	if s.cfg.TrustTTL <= 0 || origin.DeviceToken == "" ||
+		(assessment != nil && assessment.Decision != risk.DecisionAllow) {
+		return false, nil
+	}
+
+	trusted, err := s.repo.GetTrusted(userID, utils.HashToken(origin.DeviceToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return trusted.IsTrusted(time.Now()), nil
+}
+
+// List returns the user's devices, most recently seen first.
+func (s *DeviceService) List(userID string) ([]models.UserDevice, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// Delete forgets one of the user's devices, and with it any trust. The next
+// login from it counts as a new device.
+func (s *DeviceService) Delete(userID, id string) error {
+	deleted, err := s.repo.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// notifyNew tells the user about a login from a new device, so an intruder
+// with their password is noticed. Failing to send does not fail the login.
+func (s *DeviceService) notifyNew(user *models.User, seen *models.UserDevice, location *geoip.Location) {
+	from := seen.LastIP
+	if location != nil {
+		from += " (" + location.String() + ")"
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "New sign-in to your account",
+		Body: "Your account was signed in to from a new device on " +
+			seen.FirstSeenAt.UTC().Format("2 January 2006 at 15:04 MST") + ".\n\n" +
+			"  Device:     " + seen.Name + "\n" +
+			"  IP address: " + from + "\n\n" +
+			"If this was you, there is nothing to do. If not, change your password " +
+			"right away and remove the device from your account.\n",
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		s.log.Error("Failed to send new device notice to user " + user.ID + ": " + err.Error())
+	}
+}
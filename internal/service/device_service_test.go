@@ -0,0 +1,209 @@
+package service
+
+import (
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/mailer"
+)
+
+// memoryDevices is a DeviceRepository kept in a map by user and device hash.
+type memoryDevices struct {
+	devices map[string]*models.UserDevice
+}
+
+func newMemoryDevices() *memoryDevices {
+	return &memoryDevices{devices: make(map[string]*models.UserDevice)}
+}
+
+func (r *memoryDevices) Get(userID, deviceHash string) (*models.UserDevice, error) {
+	d, ok := r.devices[userID+"/"+deviceHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *d
+	return &copied, nil
+}
+
+func (r *memoryDevices) GetTrusted(userID, tokenHash string) (*models.UserDevice, error) {
+	for _, d := range r.devices {
+		if d.UserID == userID && d.TrustTokenHash != nil && *d.TrustTokenHash == tokenHash {
+			copied := *d
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryDevices) Count(userID string) (int, error) {
+	count := 0
+	for _, d := range r.devices {
+		if d.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryDevices) Touch(device *models.UserDevice) (bool, error) {
+	key := device.UserID + "/" + device.DeviceHash
+	existing, ok := r.devices[key]
+	if !ok {
+		device.ID = strconv.Itoa(len(r.devices) + 1)
+		device.FirstSeenAt = time.Now()
+		device.LastSeenAt = device.FirstSeenAt
+		copied := *device
+		r.devices[key] = &copied
+		return true, nil
+	}
+	existing.LastSeenAt = time.Now()
+	*device = *existing
+	return false, nil
+}
+
+func (r *memoryDevices) Trust(userID, deviceHash, tokenHash string, until time.Time) (bool, error) {
+	d, ok := r.devices[userID+"/"+deviceHash]
+	if !ok {
+		return false, nil
+	}
+	d.TrustTokenHash = &tokenHash
+	d.TrustedUntil = &until
+	return true, nil
+}
+
+func (r *memoryDevices) ListByUser(userID string) ([]models.UserDevice, error) {
+	var devices []models.UserDevice
+	for _, d := range r.devices {
+		if d.UserID == userID {
+			devices = append(devices, *d)
+		}
+	}
+	return devices, nil
+}
+
+func (r *memoryDevices) Delete(userID, id string) (bool, error) {
+	for key, d := range r.devices {
+		if d.UserID == userID && d.ID == id {
+			delete(r.devices, key)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// discardMailer drops every message.
+type discardMailer struct{}
+
+func (discardMailer) Send(mailer.Message) error { return nil }
+
+func TestDeviceTrustNeedsTheIssuedToken(t *testing.T) {
+	repo := newMemoryDevices()
+	devices := NewDeviceService(repo, discardMailer{}, logger.New(), DeviceConfig{TrustTTL: time.Hour})
+	user := &models.User{ID: "user-1", Email: "alice@example.com"}
+
+	laptop := risk.Request{
+		IP:             "192.0.2.1",
+		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:128.0) Gecko/20100101 Firefox/128.0",
+		AcceptLanguage: "en-GB",
+		Fingerprint:    "laptop",
+		Time:           time.Now(),
+	}
+	devices.Seen(user, laptop, nil)
+
+	token, expires, err := devices.Trust(user, laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" || time.Until(expires) < 59*time.Minute {
+		t.Fatalf("Trust = %q, %s; want a token valid for an hour", token, expires)
+	}
+	stored, err := repo.Get(user.ID, DeviceID(laptop))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.TrustTokenHash == nil || *stored.TrustTokenHash == token {
+		t.Error("the trust token is stored as issued, want only its hash")
+	}
+
+	allow := &risk.Assessment{Decision: risk.DecisionAllow}
+	withToken := laptop
+	withToken.DeviceToken = token
+	wrongToken := laptop
+	wrongToken.DeviceToken = token + "x"
+
+	tests := []struct {
+		name       string
+		userID     string
+		origin     risk.Request
+		assessment *risk.Assessment
+		want       bool
+	}{
+		{"token", user.ID, withToken, allow, true},
+		{"token without scoring", user.ID, withToken, nil, true},
+		// The same fingerprint and headers are easy to copy
+		{"copied fingerprint and headers", user.ID, laptop, allow, false},
+		{"copied fingerprint without scoring", user.ID, laptop, nil, false},
+		{"wrong token", user.ID, wrongToken, allow, false},
+		{"another user's token", "user-2", withToken, allow, false},
+		{"challenged", user.ID, withToken, &risk.Assessment{Decision: risk.DecisionChallenge}, false},
+		{"denied", user.ID, withToken, &risk.Assessment{Decision: risk.DecisionDeny}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := devices.SkipsSecondFactor(tt.userID, tt.origin, tt.assessment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("SkipsSecondFactor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Trusting again replaces the token
+	newToken, _, err := devices.Trust(user, laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skips, _ := devices.SkipsSecondFactor(user.ID, withToken, allow); skips {
+		t.Error("a replaced token still skips the second factor")
+	}
+	withToken.DeviceToken = newToken
+	if skips, _ := devices.SkipsSecondFactor(user.ID, withToken, allow); !skips {
+		t.Error("the new token does not skip the second factor")
+	}
+}
+
+func TestDeviceTrustExpiresAndCanBeDisabled(t *testing.T) {
+	repo := newMemoryDevices()
+	devices := NewDeviceService(repo, discardMailer{}, logger.New(), DeviceConfig{TrustTTL: time.Hour})
+	user := &models.User{ID: "user-1"}
+	origin := risk.Request{UserAgent: "curl/8.0", Time: time.Now()}
+	devices.Seen(user, origin, nil)
+
+	token, _, err := devices.Trust(user, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin.DeviceToken = token
+
+	expired := time.Now().Add(-time.Minute)
+	repo.devices[user.ID+"/"+DeviceID(origin)].TrustedUntil = &expired
+	if skips, _ := devices.SkipsSecondFactor(user.ID, origin, nil); skips {
+		t.Error("an expired token skips the second factor")
+	}
+
+	disabled := NewDeviceService(repo, discardMailer{}, logger.New(), DeviceConfig{})
+	if token, _, err := disabled.Trust(user, origin); token != "" || err != nil {
+		t.Errorf("Trust with trusting disabled = %q, %v; want no token", token, err)
+	}
+
+	if _, _, err := devices.Trust(&models.User{ID: "user-2"}, origin); err != ErrDeviceNotFound {
+		t.Errorf("Trust of an unknown device: got %v, want ErrDeviceNotFound", err)
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/mailer"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid verification link")
+	ErrVerificationTokenExpired = errors.New("verification link has expired")
+	ErrEmailAlreadyVerified     = errors.New("email address is already verified")
+	ErrVerificationThrottled    = errors.New("too many verification emails, try again later")
+)
+
+// EmailVerificationConfig controls EmailVerificationService.
+type EmailVerificationConfig struct {
+	Issuer         string        // "iss" of verification tokens
+	LinkURL        string        // Page the emailed link opens; the token is appended as ?token=
+	TokenTTL       time.Duration // How long a link stays valid
+	ResendInterval time.Duration // Minimum time between two emails to one user
+	MaxPerDay      int           // Emails one user can be sent per 24 hours
+}
+
+// EmailVerificationService proves that users own the address they signed up
+// with. Links carry a signed token naming the user and the address; its jti
+// is recorded so each link works once and a newer link voids older ones.
+type EmailVerificationService struct {
+	repo   repository.EmailVerificationRepository
+	users  repository.UserRepository
+	mailer mailer.Mailer
+	keys   utils.KeySet
+	log    *logger.Logger
+	cfg    EmailVerificationConfig
+}
+
+func NewEmailVerificationService(
+	repo repository.EmailVerificationRepository,
+	users repository.UserRepository,
+	mail mailer.Mailer,
+	keys utils.KeySet,
+	log *logger.Logger,
+	cfg EmailVerificationConfig,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		repo:   repo,
+		users:  users,
+		mailer: mail,
+		keys:   keys,
+		log:    log,
+		cfg:    cfg,
+	}
+}
+
+// Send mails a fresh verification link to the user, voiding earlier links.
+func (s *EmailVerificationService) Send(user *models.User) error {
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	now := time.Now()
+	recent, err := s.repo.CountSince(user.ID, now.Add(-s.cfg.ResendInterval))
+	if err != nil {
+		return err
+	}
+	daily, err := s.repo.CountSince(user.ID, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if recent > 0 || daily >= s.cfg.MaxPerDay {
+		return ErrVerificationThrottled
+	}
+
+	claims := &utils.EmailVerificationClaims{
+		Email: user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:  s.cfg.Issuer,
+			Subject: user.ID,
+		},
+	}
+	token, err := utils.GenerateEmailVerificationToken(claims, s.keys, s.cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Create(&models.EmailVerificationToken{
+		ID:        claims.ID,
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(s.verificationEmail(user.Email, token)); err != nil {
+		s.log.Error("Failed to send verification email to user " + user.ID + ": " + err.Error())
+		return err
+	}
+
+	s.log.Info("Verification email sent to user " + user.ID)
+	return nil
+}
+
+// Resend mails a new link to the account registered under email. Unknown or
+// already verified addresses are silently ignored so the endpoint cannot be
+// used to discover accounts.
+func (s *EmailVerificationService) Resend(email string) error {
+	user, err := s.users.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	err = s.Send(user)
+	if errors.Is(err, ErrEmailAlreadyVerified) {
+		return nil
+	}
+	return err
+}
+
+// Verify redeems a verification link and marks the user's email verified.
+func (s *EmailVerificationService) Verify(token string) (*models.User, error) {
+	claims, err := utils.ParseEmailVerificationToken(token, s.keys, jwt.WithIssuer(s.cfg.Issuer))
+	if err != nil {
+		if errors.Is(err, utils.ErrExpiredToken) {
+			return nil, ErrVerificationTokenExpired
+		}
+		return nil, ErrInvalidVerificationToken
+	}
+
+	used, err := s.repo.Use(claims.ID, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user, err := s.users.GetByID(claims.Subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	// A link only proves ownership of the address it was sent to
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if err := s.users.MarkEmailVerified(user.ID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+func (s *EmailVerificationService) verificationEmail(to, token string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Verify your email address",
+		Body: "Please confirm your email address by opening the link below:\n\n" +
+			tokenLink(s.cfg.LinkURL, token) + "\n\n" +
+			"The link expires in " + durationText(s.cfg.TokenTTL) + ". " +
+			"If you did not create an account, you can ignore this email.\n",
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenLink appends token to the page URL base as the "token" query parameter.
+func tokenLink(base, token string) string {
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
+
+// durationText renders a lifetime for humans, e.g. "30 minutes", "24 hours"
+// or "30 days".
+func durationText(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return plural(int(d.Round(time.Minute).Minutes()), "minute")
+	case d < 48*time.Hour:
+		return plural(int(d.Round(time.Hour).Hours()), "hour")
+	default:
+		return plural(int(d.Round(24*time.Hour).Hours()/24), "day")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(n) + " " + unit + "s"
+}
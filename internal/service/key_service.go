@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+var ErrKeyRotationDisabled = errors.New("key rotation is not enabled (neither AUTH_JWT_KEYS_ENCRYPTION_KEY nor AUTH_JWT_KEYS_DIR is set)")
+
+// keyReloadInterval is how often every instance re-reads the key metadata,
+// so a rotation performed by one replica reaches all of them.
+const keyReloadInterval = time.Minute
+
+// KeyServiceConfig controls managed signing keys.
+type KeyServiceConfig struct {
+	Algorithm      string        // Algorithm of generated keys
+	EncryptionKey  []byte        // AES-256 key sealing private keys stored in Postgres
+	KeysDir        string        // Directory holding <kid>.pem private keys, when EncryptionKey is not set
+	RotationPeriod time.Duration // Age after which the active key is rotated; 0 disables scheduled rotation
+	Overlap        time.Duration // How long a retired key keeps verifying tokens
+}
+
+// KeyService owns the key ring behind token signing. In managed mode keys are
+// generated here, their lifecycle is recorded in Postgres, and the ring is
+// rebuilt from that state so every replica signs with the same key. The
+// private keys are stored sealed next to their metadata when EncryptionKey is
+// set, so any replica can load any key; otherwise they are written to
+// KeysDir, which replicas must then share.
+type KeyService struct {
+	repo repository.SigningKeyRepository
+	ring *utils.KeyRing
+	log  *logger.Logger
+	cfg  KeyServiceConfig
+}
+
+func NewKeyService(
+	repo repository.SigningKeyRepository,
+	ring *utils.KeyRing,
+	log *logger.Logger,
+	cfg KeyServiceConfig,
+) *KeyService {
+	return &KeyService{
+		repo: repo,
+		ring: ring,
+		log:  log,
+		cfg:  cfg,
+	}
+}
+
+// Managed reports whether keys are rotated by this service.
+func (s *KeyService) Managed() bool {
+	return len(s.cfg.EncryptionKey) > 0 || s.cfg.KeysDir != ""
+}
+
+// Load rebuilds the ring from the database, generating the first key on an empty install.
+func (s *KeyService) Load() error {
+	if !s.Managed() {
+		return nil
+	}
+
+	records, err := s.repo.ListVerifiable(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		s.log.Info("No signing keys found; generating initial key")
+		_, err := s.rotate("", false)
+		if errors.Is(err, repository.ErrRotationConflict) {
+			// Another replica booting at the same time created it first
+			s.log.Info("Initial signing key was created by another instance; loading it")
+			return s.Load()
+		}
+		return err
+	}
+
+	var (
+		active *utils.SigningKey
+		others []*utils.SigningKey
+	)
+	for _, rec := range records {
+		key, err := s.loadKey(rec)
+		if err != nil {
+			if rec.IsActive() {
+				return fmt.Errorf("load active signing key: %w", err)
+			}
+			s.log.Error("Skipping retired signing key " + rec.KID + ": " + err.Error())
+			continue
+		}
+
+		if rec.IsActive() {
+			active = key
+		} else {
+			others = append(others, key)
+		}
+	}
+
+	if active == nil {
+		return utils.ErrNoSigningKey
+	}
+
+	s.ring.Replace(active, others)
+	return nil
+}
+
+// Rotate generates and activates a new key. When compromised is true the
+// previous key stops verifying immediately, invalidating every token it signed;
+// otherwise it keeps verifying for the configured overlap.
+func (s *KeyService) Rotate(compromised bool) (*models.SigningKey, error) {
+	if !s.Managed() {
+		return nil, ErrKeyRotationDisabled
+	}
+
+	current, err := s.ring.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rotate(current.ID, compromised)
+}
+
+// List returns the metadata of every managed key.
+func (s *KeyService) List() ([]models.SigningKey, error) {
+	if !s.Managed() {
+		return nil, ErrKeyRotationDisabled
+	}
+	return s.repo.List()
+}
+
+// Run reloads the ring periodically and rotates the active key once it is
+// older than the rotation period. It returns when ctx is cancelled.
+func (s *KeyService) Run(ctx context.Context) {
+	if !s.Managed() {
+		return
+	}
+
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(); err != nil {
+				s.log.Error("Failed to reload signing keys: " + err.Error())
+				continue
+			}
+			if err := s.rotateIfDue(); err != nil {
+				s.log.Error("Scheduled key rotation failed: " + err.Error())
+			}
+		}
+	}
+}
+
+func (s *KeyService) rotateIfDue() error {
+	if s.cfg.RotationPeriod <= 0 {
+		return nil
+	}
+
+	records, err := s.repo.ListVerifiable(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		if !rec.IsActive() || time.Since(*rec.ActivatedAt) < s.cfg.RotationPeriod {
+			continue
+		}
+
+		_, err := s.rotate(rec.KID, false)
+		if errors.Is(err, repository.ErrRotationConflict) {
+			// Another replica got there first; pick up its key on the next reload
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *KeyService) rotate(expectedActiveKID string, compromised bool) (*models.SigningKey, error) {
+	key, err := utils.GenerateSigningKey(s.cfg.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.SigningKey{
+		KID:       key.ID,
+		Algorithm: s.cfg.Algorithm,
+	}
+	if err := s.storeKey(key, record); err != nil {
+		return nil, err
+	}
+
+	verifyUntil := time.Now().Add(s.cfg.Overlap)
+	if compromised {
+		verifyUntil = time.Now()
+	}
+
+	if err := s.repo.Rotate(record, expectedActiveKID, verifyUntil); err != nil {
+		if record.PrivateKey == nil {
+			os.Remove(s.keyPath(key.ID))
+		}
+		return nil, err
+	}
+
+	if compromised {
+		s.log.Warn("Signing key " + expectedActiveKID + " rotated out as compromised; its tokens are no longer valid")
+	}
+	s.log.Info("Activated signing key " + key.ID)
+
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// storeKey saves the private half of a new key: sealed into record, to be
+// inserted with its metadata, or as a file in KeysDir. A file is written
+// before the metadata is published, so no replica ever sees a kid it cannot
+// load.
+func (s *KeyService) storeKey(key *utils.SigningKey, record *models.SigningKey) error {
+	data, err := key.MarshalPrivateKeyPEM()
+	if err != nil {
+		return err
+	}
+
+	if len(s.cfg.EncryptionKey) > 0 {
+		record.PrivateKey, err = utils.Encrypt(s.cfg.EncryptionKey, data)
+		return err
+	}
+
+	if err := os.MkdirAll(s.cfg.KeysDir, 0o700); err != nil {
+		return err
+	}
+
+	// Write then rename so readers never observe a partial file
+	tmp := s.keyPath(key.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.keyPath(key.ID))
+}
+
+// loadKey loads the private half of a recorded key from wherever storeKey
+// put it.
+func (s *KeyService) loadKey(rec models.SigningKey) (*utils.SigningKey, error) {
+	if rec.PrivateKey == nil {
+		if s.cfg.KeysDir == "" {
+			return nil, errors.New("key " + rec.KID + " is stored in AUTH_JWT_KEYS_DIR, which is not set")
+		}
+		return utils.LoadPrivateKeyFile(rec.KID, rec.Algorithm, s.keyPath(rec.KID))
+	}
+
+	if len(s.cfg.EncryptionKey) == 0 {
+		return nil, errors.New("key " + rec.KID + " is stored sealed, but AUTH_JWT_KEYS_ENCRYPTION_KEY is not set")
+	}
+	data, err := utils.Decrypt(s.cfg.EncryptionKey, rec.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("unseal signing key %s: %w", rec.KID, err)
+	}
+	return utils.ParsePrivateKeyPEM(rec.KID, rec.Algorithm, data)
+}
+
+func (s *KeyService) keyPath(kid string) string {
+	return filepath.Join(s.cfg.KeysDir, kid+".pem")
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+)
+
+var ErrUnknownUser = errors.New("user not found")
+
+const (
+	loginFreeAttempts  = 3 // Consecutive failures before backoff starts
+	loginBackoffBase   = time.Second
+	loginBackoffMax    = time.Minute
+	loginFailureWindow = 24 * time.Hour // Failures older than this are forgotten
+)
+
+// LockoutConfig controls LockoutService.
+type LockoutConfig struct {
+	Threshold int           // Consecutive failures that lock the account; 0 disables locking
+	Duration  time.Duration // How long a lock lasts before the account unlocks itself
+}
+
+// LockoutService slows down password guessing against a single account.
+// After a few consecutive failures every further attempt must wait for an
+// exponentially growing delay, and at the threshold the account is locked
+// for a cooldown period. Refused attempts are never told why, so callers
+// answer them exactly like a wrong password.
+type LockoutService struct {
+	repo  repository.LoginFailureRepository
+	users repository.UserRepository
+	audit repository.AuditRepository
+	log   *logger.Logger
+	cfg   LockoutConfig
+}
+
+func NewLockoutService(
+	repo repository.LoginFailureRepository,
+	users repository.UserRepository,
+	audit repository.AuditRepository,
+	log *logger.Logger,
+	cfg LockoutConfig,
+) *LockoutService {
+	return &LockoutService{
+		repo:  repo,
+		users: users,
+		audit: audit,
+		log:   log,
+		cfg:   cfg,
+	}
+}
+
+// Reserve claims an attempt at the user's password or second factor. It
+// returns false while the account is locked or inside its backoff delay
+// after the last attempt: none for the first few failures, then doubling
+// up to a cap. A claimed attempt counts as a failure until Release, so a
+// burst of concurrent attempts is throttled like a sequence of them.
+// Reserve returns the failures outstanding before this attempt, nil if
+// there are none, and the claimed attempt, nil if it was refused.
+func (s *LockoutService) Reserve(userID string) (*models.LoginFailures, *models.LoginAttempt, error) {
+	before, err := s.repo.Get(userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+	if before != nil && before.FailedCount == 0 {
+		before = nil
+	}
+
+	attempt, allowed, err := s.repo.ReserveAttempt(userID, time.Now().Add(-loginFailureWindow),
+		loginFreeAttempts, loginBackoffBase, loginBackoffMax)
+	if err != nil || !allowed {
+		return nil, nil, err
+	}
+	return before, attempt, nil
+}
+
+// Release takes back a reserved attempt that was not a failure, e.g. the
+// correct password. The backoff delay runs from the failure before it again.
+func (s *LockoutService) Release(attempt *models.LoginAttempt) error {
+	return s.repo.ReleaseAttempt(attempt)
+}
+
+// RecordFailure keeps a reserved attempt counted as a failure and locks the
+// account once the threshold is reached.
+func (s *LockoutService) RecordFailure(userID string) error {
+	if s.cfg.Threshold <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	failures, err := s.repo.Get(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if failures.FailedCount < s.cfg.Threshold || failures.IsLocked(now) {
+		return nil
+	}
+
+	until := now.Add(s.cfg.Duration)
+	locked, err := s.repo.Lock(userID, until)
+	if err != nil || !locked {
+		return err
+	}
+
+	s.log.Warn("Account " + userID + " locked after " + strconv.Itoa(failures.FailedCount) + " failed logins")
+	s.record(&models.AuditEvent{
+		Event:  models.AuditAccountLocked,
+		UserID: &userID,
+		Detail: strconv.Itoa(failures.FailedCount) + " consecutive failed logins; locked until " + until.UTC().Format(time.RFC3339),
+	})
+	return nil
+}
+
+// RecordSuccess forgets the user's failures once a login is complete.
+func (s *LockoutService) RecordSuccess(userID string) error {
+	_, err := s.repo.Clear(userID)
+	return err
+}
+
+// Unlock lifts a lockout and resets the failure count on behalf of an admin.
+// It returns false if the account had nothing to unlock.
+func (s *LockoutService) Unlock(userID, actorID string) (bool, error) {
+	if _, err := s.users.GetByID(userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrUnknownUser
+		}
+		return false, err
+	}
+
+	cleared, err := s.repo.Clear(userID)
+	if err != nil || !cleared {
+		return false, err
+	}
+
+	s.log.Info("Account " + userID + " unlocked by admin " + actorID)
+	s.record(&models.AuditEvent{
+		Event:   models.AuditAccountUnlocked,
+		UserID:  &userID,
+		ActorID: &actorID,
+	})
+	return true, nil
+}
+
+// record stores an audit event. The event is also in the log, so a failure
+// to store it does not fail the login.
+func (s *LockoutService) record(event *models.AuditEvent) {
+	if err := s.audit.Record(event); err != nil {
+		s.log.Error("Failed to record " + event.Event + " audit event: " + err.Error())
+	}
+}
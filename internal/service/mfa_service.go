@@ -0,0 +1,511 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+var (
+	ErrMFAUnavailable   = errors.New("multi-factor authentication is not configured")
+	ErrMFAAlreadyActive = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnrolled   = errors.New("multi-factor authentication is not enrolled")
+	ErrInvalidMFACode   = errors.New("invalid verification code")
+	ErrInvalidMFAToken  = errors.New("invalid or expired mfa token")
+	ErrMFAProofRequired = errors.New("a code or a passkey assertion is required")
+)
+
+// Second-factor methods offered to the client after the password step
+const (
+	MFAMethodTOTP     = "totp"
+	MFAMethodWebAuthn = "webauthn"
+)
+
+const (
+	mfaTokenBytes      = 32
+	mfaMaxAttempts     = 5 // Proofs accepted per challenge before it is burned
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // Characters, shown to users as two groups of five
+)
+
+// recoveryAlphabet avoids characters that are easily confused when copied by hand.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// MFAConfig controls MFAService.
+type MFAConfig struct {
+	EncryptionKey []byte        // AES-256 key for TOTP secrets; nil disables enrollment
+	Issuer        string        // Account label shown in authenticator apps
+	ChallengeTTL  time.Duration // Lifetime of the mfa_token handed out after the password step
+}
+
+// TOTPEnrollment is what a user needs to add the account to an authenticator app.
+type TOTPEnrollment struct {
+	Secret string // Base32 secret, for manual entry
+	URI    string // otpauth:// URI, usually shown as a QR code
+}
+
+// MFAStatus summarizes a user's second factors.
+type MFAStatus struct {
+	TOTPEnabled            bool
+	RecoveryCodesRemaining int
+	Passkeys               int
+}
+
+// MFAProof is the second factor presented for a challenge: a TOTP or
+// recovery code, or a WebAuthn assertion answering a BeginWebAuthn session.
+type MFAProof struct {
+	Code               string
+	WebAuthnSession    string
+	WebAuthnCredential []byte
+}
+
+// MFAService implements the second login factor: TOTP (RFC 6238) with
+// one-time recovery codes as a fallback, or a WebAuthn security key/passkey.
+type MFAService struct {
+	repo     repository.MFARepository
+	webauthn *WebAuthnService
+	auth     *AuthService
+	lockout  *LockoutService
+	log      *logger.Logger
+	cfg      MFAConfig
+}
+
+func NewMFAService(
+	repo repository.MFARepository,
+	webauthn *WebAuthnService,
+	auth *AuthService,
+	lockout *LockoutService,
+	log *logger.Logger,
+	cfg MFAConfig,
+) *MFAService {
+	return &MFAService{
+		repo:     repo,
+		webauthn: webauthn,
+		auth:     auth,
+		lockout:  lockout,
+		log:      log,
+		cfg:      cfg,
+	}
+}
+
+// Required reports whether the user must pass a second factor to log in.
+func (s *MFAService) Required(userID string) (bool, error) {
+	methods, err := s.Methods(userID)
+	return len(methods) > 0, err
+}
+
+// Methods lists the second factors the user can present.
+func (s *MFAService) Methods(userID string) ([]string, error) {
+	var methods []string
+
+	totp, err := s.totpEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp {
+		methods = append(methods, MFAMethodTOTP)
+	}
+
+	passkeys, err := s.webauthn.HasCredentials(userID)
+	if err != nil {
+		return nil, err
+	}
+	if passkeys {
+		methods = append(methods, MFAMethodWebAuthn)
+	}
+
+	return methods, nil
+}
+
+// Status reports the user's second factors and how many recovery codes are left.
+func (s *MFAService) Status(userID string) (*MFAStatus, error) {
+	enabled, err := s.totpEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{TOTPEnabled: enabled}
+	if enabled {
+		status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	credentials, err := s.webauthn.ListCredentials(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range credentials {
+		if c.CloneDetectedAt == nil {
+			status.Passkeys++
+		}
+	}
+	return status, nil
+}
+
+func (s *MFAService) totpEnabled(userID string) (bool, error) {
+	totp, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return totp.IsEnabled(), nil
+}
+
+// EnrollTOTP generates a new secret for the user. The enrollment only takes
+// effect once ConfirmTOTP is called with a code from the authenticator app;
+// calling EnrollTOTP again before that replaces the secret.
+func (s *MFAService) EnrollTOTP(user *models.User) (*TOTPEnrollment, error) {
+	if len(s.cfg.EncryptionKey) == 0 {
+		return nil, ErrMFAUnavailable
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := utils.Encrypt(s.cfg.EncryptionKey, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.SaveTOTP(user.ID, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrMFAAlreadyActive
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPURI(s.cfg.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables TOTP once the user proves the authenticator app works,
+// and returns a fresh set of recovery codes. The codes are shown only once.
+func (s *MFAService) ConfirmTOTP(userID, code string) ([]string, error) {
+	totp, err := s.getTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp.IsEnabled() {
+		return nil, ErrMFAAlreadyActive
+	}
+
+	step, ok, err := s.checkTOTP(totp, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.EnableTOTP(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("TOTP enabled for user " + userID)
+	return codes, nil
+}
+
+// DisableTOTP removes the second factor. The user must present a current
+// TOTP or recovery code, so a stolen access token alone cannot downgrade
+// the account.
+func (s *MFAService) DisableTOTP(userID, code string) error {
+	if _, err := s.verifyCodeCounted(userID, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteTOTP(userID); err != nil {
+		return err
+	}
+
+	s.log.Info("TOTP disabled for user " + userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes invalidates all existing recovery codes and
+// returns a new set. Requires a current TOTP or recovery code.
+func (s *MFAService) RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	if _, err := s.verifyCodeCounted(userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyCodeCounted is VerifyCode for a signed-in user. Wrong codes count as
+// failed logins for the account's lockout, as in CompleteChallenge, so a
+// stolen access token cannot be used to guess codes; once the account is
+// locked or throttled, codes are refused without being checked.
+func (s *MFAService) verifyCodeCounted(userID, code string) ([]string, error) {
+	_, attempt, err := s.lockout.Reserve(userID)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		s.log.Info("MFA code refused for locked or throttled user " + userID)
+		return nil, ErrInvalidMFACode
+	}
+
+	amr, err := s.VerifyCode(userID, code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		if recordErr := s.lockout.RecordFailure(userID); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+	if releaseErr := s.lockout.Release(attempt); releaseErr != nil {
+		return nil, releaseErr
+	}
+	return amr, err
+}
+
+// VerifyCode checks a TOTP code or, failing that, consumes a recovery code.
+// It returns the authentication methods to record for the login.
+func (s *MFAService) VerifyCode(userID, code string) ([]string, error) {
+	totp, err := s.getTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !totp.IsEnabled() {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if step, ok, err := s.checkTOTP(totp, code); err != nil {
+		return nil, err
+	} else if ok {
+		used, err := s.repo.UseTOTPStep(userID, step)
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			// Valid code, but it (or a later one) was already accepted
+			return nil, ErrInvalidMFACode
+		}
+		return []string{models.AMRPassword, models.AMROTP, models.AMRMFA}, nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(userID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidMFACode
+	}
+
+	s.log.Warn("Recovery code used by user " + userID)
+	return []string{models.AMRPassword, models.AMROTP, models.AMRMFA}, nil
+}
+
+// StartChallenge is called after a successful password check for a user with
+// MFA enabled. It returns the mfa_token the client must send back with a code.
+// The login's risk claim, if any, is kept for the tokens issued on completion.
+func (s *MFAService) StartChallenge(userID string, riskClaim *utils.RiskClaim) (string, time.Duration, error) {
+	raw, err := utils.GenerateRandomToken(mfaTokenBytes)
+	if err != nil {
+		return "", 0, err
+	}
+
+	challenge := &models.MFAChallenge{
+		UserID:    userID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(s.cfg.ChallengeTTL),
+		LoginRisk: storedRisk(riskClaim),
+	}
+	if err := s.repo.CreateChallenge(challenge); err != nil {
+		return "", 0, err
+	}
+
+	return raw, s.cfg.ChallengeTTL, nil
+}
+
+// BeginWebAuthn starts a passkey assertion for a pending challenge. The
+// returned session token is sent back in MFAProof.WebAuthnSession.
+func (s *MFAService) BeginWebAuthn(mfaToken string) (*protocol.CredentialAssertion, string, error) {
+	challenge, err := s.openChallenge(mfaToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.webauthn.BeginLogin(challenge.UserID)
+}
+
+// CompleteChallenge finishes a two-step login. On success the challenge is
+// consumed and the user is returned with the authentication methods used
+// and the risk claim of the password step.
+// Each proof counts against the challenge before it is checked; after
+// mfaMaxAttempts the user has to start over with their password. Wrong
+// proofs also count as failed logins for the account's lockout, which the
+// password step does not clear until the login completes.
+func (s *MFAService) CompleteChallenge(mfaToken string, proof MFAProof) (*models.User, []string, *utils.RiskClaim, error) {
+	if proof.Code == "" && proof.WebAuthnSession == "" {
+		return nil, nil, nil, ErrMFAProofRequired
+	}
+
+	challenge, err := s.openChallenge(mfaToken)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// A lockout reached through wrong codes also stops challenges in flight
+	_, attempt, err := s.lockout.Reserve(challenge.UserID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if attempt == nil {
+		s.log.Info("MFA attempt refused for locked or throttled user " + challenge.UserID)
+		return nil, nil, nil, ErrInvalidMFAToken
+	}
+
+	attempts, claimed, err := s.repo.ClaimChallengeAttempt(challenge.ID, mfaMaxAttempts)
+	if err == nil && !claimed {
+		err = ErrInvalidMFAToken
+	}
+	if err != nil {
+		if releaseErr := s.lockout.Release(attempt); releaseErr != nil {
+			return nil, nil, nil, releaseErr
+		}
+		return nil, nil, nil, err
+	}
+
+	var amr []string
+	if proof.WebAuthnSession != "" {
+		amr, err = s.verifyWebAuthn(challenge.UserID, proof)
+	} else {
+		amr, err = s.VerifyCode(challenge.UserID, proof.Code)
+	}
+	if errors.Is(err, ErrInvalidMFACode) || errors.Is(err, ErrInvalidPasskey) || errors.Is(err, ErrPasskeyCloned) {
+		if recordErr := s.lockout.RecordFailure(challenge.UserID); recordErr != nil {
+			return nil, nil, nil, recordErr
+		}
+		if attempts >= mfaMaxAttempts {
+			s.log.Warn("Too many failed MFA attempts for user " + challenge.UserID + "; challenge burned")
+		}
+		return nil, nil, nil, err
+	}
+	if releaseErr := s.lockout.Release(attempt); releaseErr != nil {
+		return nil, nil, nil, releaseErr
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	consumed, err := s.repo.ConsumeChallenge(challenge.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !consumed {
+		return nil, nil, nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.auth.GetUser(challenge.UserID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return user, amr, loadedRisk(challenge.LoginRisk), nil
+}
+
+// openChallenge loads a challenge that can still be answered.
+func (s *MFAService) openChallenge(mfaToken string) (*models.MFAChallenge, error) {
+	challenge, err := s.repo.GetChallenge(utils.HashToken(mfaToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	if challenge.ConsumedAt != nil || challenge.Attempts >= mfaMaxAttempts || time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrInvalidMFAToken
+	}
+	return challenge, nil
+}
+
+// verifyWebAuthn checks a passkey assertion made by the challenged user.
+func (s *MFAService) verifyWebAuthn(userID string, proof MFAProof) ([]string, error) {
+	user, _, err := s.webauthn.FinishLogin(proof.WebAuthnSession, proof.WebAuthnCredential)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID != userID {
+		return nil, ErrInvalidPasskey
+	}
+	return []string{models.AMRPassword, models.AMRHardwareKey, models.AMRMFA}, nil
+}
+
+func (s *MFAService) getTOTP(userID string) (*models.UserTOTP, error) {
+	totp, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	return totp, nil
+}
+
+// checkTOTP decrypts the user's secret and validates code against it.
+func (s *MFAService) checkTOTP(totp *models.UserTOTP, code string) (int64, bool, error) {
+	if len(s.cfg.EncryptionKey) == 0 {
+		return 0, false, ErrMFAUnavailable
+	}
+
+	secret, err := utils.Decrypt(s.cfg.EncryptionKey, totp.Secret)
+	if err != nil {
+		s.log.Error("Failed to decrypt TOTP secret for user " + totp.UserID + ": " + err.Error())
+		return 0, false, err
+	}
+
+	step, ok := utils.ValidateTOTP(string(secret), code, time.Now())
+	return step, ok, nil
+}
+
+// generateRecoveryCodes returns recovery codes for display and their hashes for storage.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		raw, err := utils.GenerateRandomString(recoveryCodeLength, recoveryAlphabet)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = raw[:recoveryCodeLength/2] + "-" + raw[recoveryCodeLength/2:]
+		hashes[i] = utils.HashToken(raw)
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts codes with or without the separator and in any case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,610 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+// memoryMFA is an MFARepository for a handful of users.
+type memoryMFA struct {
+	mu         sync.Mutex
+	totp       map[string]*models.UserTOTP
+	recovery   map[string]map[string]bool // User ID to unused code hashes
+	challenges map[string]*models.MFAChallenge
+}
+
+func newMemoryMFA() *memoryMFA {
+	return &memoryMFA{
+		totp:       make(map[string]*models.UserTOTP),
+		recovery:   make(map[string]map[string]bool),
+		challenges: make(map[string]*models.MFAChallenge),
+	}
+}
+
+func (r *memoryMFA) GetTOTP(userID string) (*models.UserTOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	totp, ok := r.totp[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *totp
+	return &copied, nil
+}
+
+func (r *memoryMFA) SaveTOTP(userID string, secret []byte) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if totp, ok := r.totp[userID]; ok && totp.IsEnabled() {
+		return false, nil
+	}
+	r.totp[userID] = &models.UserTOTP{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return true, nil
+}
+
+func (r *memoryMFA) EnableTOTP(userID string, step int64, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.totp[userID].EnabledAt = &now
+	r.totp[userID].LastUsedStep = step
+	r.replaceRecoveryCodes(userID, recoveryCodeHashes)
+	return nil
+}
+
+func (r *memoryMFA) DeleteTOTP(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.totp, userID)
+	delete(r.recovery, userID)
+	return nil
+}
+
+func (r *memoryMFA) UseTOTPStep(userID string, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	totp := r.totp[userID]
+	if step <= totp.LastUsedStep {
+		return false, nil
+	}
+	totp.LastUsedStep = step
+	return true, nil
+}
+
+func (r *memoryMFA) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+func (r *memoryMFA) replaceRecoveryCodes(userID string, codeHashes []string) {
+	r.recovery[userID] = make(map[string]bool)
+	for _, h := range codeHashes {
+		r.recovery[userID][h] = true
+	}
+}
+
+func (r *memoryMFA) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recovery[userID][codeHash] {
+		return false, nil
+	}
+	delete(r.recovery[userID], codeHash)
+	return true, nil
+}
+
+func (r *memoryMFA) CountRecoveryCodes(userID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.recovery[userID]), nil
+}
+
+func (r *memoryMFA) CreateChallenge(challenge *models.MFAChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	challenge.ID = "challenge-" + strconv.Itoa(len(r.challenges)+1)
+	challenge.CreatedAt = time.Now()
+	copied := *challenge
+	r.challenges[challenge.ID] = &copied
+	return nil
+}
+
+func (r *memoryMFA) GetChallenge(tokenHash string) (*models.MFAChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.challenges {
+		if c.TokenHash == tokenHash {
+			copied := *c
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryMFA) ClaimChallengeAttempt(id string, maxAttempts int) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.challenges[id]
+	if c.ConsumedAt != nil || c.Attempts >= maxAttempts || time.Now().After(c.ExpiresAt) {
+		return 0, false, nil
+	}
+	c.Attempts++
+	return c.Attempts, true, nil
+}
+
+func (r *memoryMFA) ConsumeChallenge(id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.challenges[id]
+	if c.ConsumedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	c.ConsumedAt = &now
+	return true, nil
+}
+
+func (r *memoryMFA) challenge(t *testing.T, mfaToken string) *models.MFAChallenge {
+	t.Helper()
+	c, err := r.GetChallenge(utils.HashToken(mfaToken))
+	if err != nil {
+		t.Fatalf("challenge not stored: %v", err)
+	}
+	return c
+}
+
+// memoryLoginFailures is a LoginFailureRepository on a clock the test
+// moves, so backoff delays pass without sleeping.
+type memoryLoginFailures struct {
+	mu   sync.Mutex
+	rows map[string]*models.LoginFailures
+	now  time.Time
+}
+
+func newMemoryLoginFailures() *memoryLoginFailures {
+	return &memoryLoginFailures{rows: make(map[string]*models.LoginFailures), now: time.Now()}
+}
+
+// wait moves the clock past any backoff delay.
+func (r *memoryLoginFailures) wait() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.now = r.now.Add(loginBackoffMax)
+}
+
+func (r *memoryLoginFailures) Get(userID string) (*models.LoginFailures, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.rows[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *f
+	return &copied, nil
+}
+
+func (r *memoryLoginFailures) ReserveAttempt(userID string, resetBefore time.Time, free int, base, max time.Duration) (*models.LoginAttempt, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.rows[userID]
+	if !ok {
+		r.rows[userID] = &models.LoginFailures{UserID: userID, FailedCount: 1, LastFailedAt: r.now}
+		return &models.LoginAttempt{UserID: userID, ReservedAt: r.now}, true, nil
+	}
+	if f.IsLocked(r.now) {
+		return nil, false, nil
+	}
+
+	restart := f.LastFailedAt.Before(resetBefore) || f.LockedUntil != nil
+	if !restart && f.FailedCount >= free {
+		backoff := base << min(f.FailedCount-free, 30)
+		if backoff > max {
+			backoff = max
+		}
+		if r.now.Before(f.LastFailedAt.Add(backoff)) {
+			return nil, false, nil
+		}
+	}
+
+	if restart {
+		f.FailedCount = 1
+	} else {
+		f.FailedCount++
+	}
+	previous := f.LastFailedAt
+	f.LockedUntil = nil
+	f.LastFailedAt = r.now
+	return &models.LoginAttempt{UserID: userID, ReservedAt: r.now, PreviousAt: &previous}, true, nil
+}
+
+func (r *memoryLoginFailures) ReleaseAttempt(attempt *models.LoginAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.rows[attempt.UserID]
+	switch {
+	case !ok:
+	case f.FailedCount > 1:
+		f.FailedCount--
+		if f.LastFailedAt.Equal(attempt.ReservedAt) && attempt.PreviousAt != nil {
+			f.LastFailedAt = *attempt.PreviousAt
+		}
+	case f.LockedUntil == nil:
+		delete(r.rows, attempt.UserID)
+	}
+	return nil
+}
+
+func (r *memoryLoginFailures) Lock(userID string, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.rows[userID]
+	if f.IsLocked(r.now) {
+		return false, nil
+	}
+	f.LockedUntil = &until
+	return true, nil
+}
+
+func (r *memoryLoginFailures) Clear(userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.rows[userID]
+	delete(r.rows, userID)
+	return ok, nil
+}
+
+// discardAudit drops every event.
+type discardAudit struct{}
+
+func (discardAudit) Record(*models.AuditEvent) error { return nil }
+
+// totpCode computes the RFC 6238 code of secret for the step at falls in.
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(utils.TOTPStep(at)))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// wrongCode returns a six-digit code that is not code.
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+type mfaFixture struct {
+	mfa      *MFAService
+	repo     *memoryMFA
+	failures *memoryLoginFailures
+	secret   string
+	enrolled string // The code that confirmed the enrollment
+	recovery []string
+}
+
+// newTestMFAService enrolls testUser in TOTP. The current step's code was
+// used to confirm the enrollment, so logins use the next step's code.
+func newTestMFAService(t *testing.T, lockout LockoutConfig) *mfaFixture {
+	t.Helper()
+	auth, _ := newTestAuthService(t)
+	repo := newMemoryMFA()
+	failures := newMemoryLoginFailures()
+	log := logger.New()
+
+	s := NewMFAService(
+		repo,
+		nil,
+		auth,
+		NewLockoutService(failures, newMemoryUsers(testUser), discardAudit{}, log, lockout),
+		log,
+		MFAConfig{
+			EncryptionKey: []byte("0123456789abcdef0123456789abcdef"),
+			Issuer:        "Example",
+			ChallengeTTL:  5 * time.Minute,
+		},
+	)
+
+	enrollment, err := s.EnrollTOTP(testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enrolled := totpCode(t, enrollment.Secret, time.Now())
+	recovery, err := s.ConfirmTOTP(testUser.ID, enrolled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &mfaFixture{mfa: s, repo: repo, failures: failures, secret: enrollment.Secret, enrolled: enrolled, recovery: recovery}
+}
+
+func (f *mfaFixture) nextCode(t *testing.T) string {
+	t.Helper()
+	return totpCode(t, f.secret, time.Now().Add(utils.TOTPPeriod))
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{})
+
+	stored, err := f.repo.GetTOTP(testUser.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stored.Secret) == f.secret {
+		t.Error("the TOTP secret is stored in the clear")
+	}
+	if len(f.recovery) != recoveryCodeCount {
+		t.Errorf("got %d recovery codes, want %d", len(f.recovery), recoveryCodeCount)
+	}
+
+	if _, err := f.mfa.EnrollTOTP(testUser); !errors.Is(err, ErrMFAAlreadyActive) {
+		t.Errorf("enrolling twice: got %v, want ErrMFAAlreadyActive", err)
+	}
+}
+
+func TestVerifyCodeRejectsReplays(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{})
+
+	// The code that confirmed the enrollment was used up by it
+	if _, err := f.mfa.VerifyCode(testUser.ID, f.enrolled); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("the enrollment code: got %v, want ErrInvalidMFACode", err)
+	}
+
+	code := f.nextCode(t)
+	amr, err := f.mfa.VerifyCode(testUser.ID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(amr) != 3 || amr[1] != models.AMROTP {
+		t.Errorf("got AMR %v, want pwd, otp and mfa", amr)
+	}
+	if _, err := f.mfa.VerifyCode(testUser.ID, code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("replaying a code: got %v, want ErrInvalidMFACode", err)
+	}
+
+	// Recovery codes work once, however they are typed
+	recovery := f.recovery[0]
+	if _, err := f.mfa.VerifyCode(testUser.ID, " "+recovery[:5]+recovery[6:]+" "); err != nil {
+		t.Errorf("recovery code: %v", err)
+	}
+	if _, err := f.mfa.VerifyCode(testUser.ID, recovery); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("reusing a recovery code: got %v, want ErrInvalidMFACode", err)
+	}
+	if left, err := f.repo.CountRecoveryCodes(testUser.ID); err != nil || left != recoveryCodeCount-1 {
+		t.Errorf("%d recovery codes left, want %d", left, recoveryCodeCount-1)
+	}
+}
+
+func TestCompleteChallenge(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{Threshold: 10, Duration: time.Hour})
+
+	risk := &utils.RiskClaim{Score: 40, Reasons: []string{"new_device"}}
+	token, ttl, err := f.mfa.StartChallenge(testUser.ID, risk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl != 5*time.Minute {
+		t.Errorf("challenge lives %s, want 5m", ttl)
+	}
+
+	if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{}); !errors.Is(err, ErrMFAProofRequired) {
+		t.Errorf("no proof: got %v, want ErrMFAProofRequired", err)
+	}
+
+	user, amr, claim, err := f.mfa.CompleteChallenge(token, MFAProof{Code: f.nextCode(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != testUser.ID || len(amr) != 3 {
+		t.Errorf("got user %s with AMR %v", user.ID, amr)
+	}
+	if claim == nil || claim.Score != risk.Score {
+		t.Errorf("got risk %+v, want the password step's %+v", claim, risk)
+	}
+
+	// The right code is not a failed login
+	if _, err := f.failures.Get(testUser.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("failures after a correct code: %v", err)
+	}
+
+	// A completed challenge cannot be answered again
+	if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{Code: f.recovery[0]}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("reusing the challenge: got %v, want ErrInvalidMFAToken", err)
+	}
+	if _, _, _, err := f.mfa.CompleteChallenge("not-a-token", MFAProof{Code: f.recovery[0]}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("unknown challenge: got %v, want ErrInvalidMFAToken", err)
+	}
+}
+
+func TestCompleteChallengeAttemptLimit(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{})
+
+	token, _, err := f.mfa.StartChallenge(testUser.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := f.nextCode(t)
+	for i := 1; i <= mfaMaxAttempts; i++ {
+		f.failures.wait()
+		if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{Code: wrongCode(code)}); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("wrong code %d: got %v, want ErrInvalidMFACode", i, err)
+		}
+	}
+	if attempts := f.repo.challenge(t, token).Attempts; attempts != mfaMaxAttempts {
+		t.Errorf("challenge has %d attempts, want %d", attempts, mfaMaxAttempts)
+	}
+
+	// The challenge is burned; even the right code needs a new password step
+	f.failures.wait()
+	if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{Code: code}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("right code after the limit: got %v, want ErrInvalidMFAToken", err)
+	}
+
+	// Every wrong code counted against the account
+	failures, err := f.failures.Get(testUser.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures.FailedCount != mfaMaxAttempts {
+		t.Errorf("%d failed logins recorded, want %d", failures.FailedCount, mfaMaxAttempts)
+	}
+}
+
+func TestCompleteChallengeLocksTheAccount(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{Threshold: 3, Duration: time.Hour})
+
+	token, _, err := f.mfa.StartChallenge(testUser.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := f.nextCode(t)
+	for i := 0; i < 3; i++ {
+		if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{Code: wrongCode(code)}); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("wrong code %d: got %v, want ErrInvalidMFACode", i+1, err)
+		}
+	}
+
+	failures, err := f.failures.Get(testUser.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures.LockedUntil == nil {
+		t.Fatal("the account is not locked at the threshold")
+	}
+
+	// The lock stops the challenge in flight without using up an attempt
+	f.failures.wait()
+	if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{Code: code}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("right code while locked: got %v, want ErrInvalidMFAToken", err)
+	}
+	if attempts := f.repo.challenge(t, token).Attempts; attempts != 3 {
+		t.Errorf("challenge has %d attempts, want 3", attempts)
+	}
+}
+
+func TestCompleteChallengeBacksOff(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{})
+
+	token, _, err := f.mfa.StartChallenge(testUser.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Guesses past the free failures must wait, like passwords
+	code := f.nextCode(t)
+	for i := 0; i < loginFreeAttempts; i++ {
+		if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{Code: wrongCode(code)}); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("wrong code %d: got %v, want ErrInvalidMFACode", i+1, err)
+		}
+	}
+	if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{Code: code}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("code inside the backoff: got %v, want ErrInvalidMFAToken", err)
+	}
+
+	f.failures.wait()
+	if _, _, _, err := f.mfa.CompleteChallenge(token, MFAProof{Code: code}); err != nil {
+		t.Errorf("code after the backoff: %v", err)
+	}
+}
+
+func TestSignedInCodeChecksLockTheAccount(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{Threshold: 3, Duration: time.Hour})
+
+	// A stolen access token must not give unlimited guesses at the codes
+	// that disable the second factor
+	code := f.nextCode(t)
+	for i := 0; i < 3; i++ {
+		if err := f.mfa.DisableTOTP(testUser.ID, wrongCode(code)); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("wrong code %d: got %v, want ErrInvalidMFACode", i+1, err)
+		}
+	}
+
+	failures, err := f.failures.Get(testUser.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failures.LockedUntil == nil {
+		t.Fatal("the account is not locked at the threshold")
+	}
+
+	// Once locked, even the right code is refused
+	f.failures.wait()
+	if err := f.mfa.DisableTOTP(testUser.ID, code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("right code while locked: got %v, want ErrInvalidMFACode", err)
+	}
+	if _, err := f.mfa.RegenerateRecoveryCodes(testUser.ID, f.recovery[0]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("recovery code while locked: got %v, want ErrInvalidMFACode", err)
+	}
+	if _, err := f.repo.GetTOTP(testUser.ID); err != nil {
+		t.Errorf("TOTP disabled while locked: %v", err)
+	}
+}
+
+func TestSignedInCodeChecksReleaseTheAttempt(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{Threshold: 3, Duration: time.Hour})
+
+	if _, err := f.mfa.RegenerateRecoveryCodes(testUser.ID, f.nextCode(t)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.failures.Get(testUser.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("a right code left a failed attempt behind: %v", err)
+	}
+}
+
+func TestReleasedAttemptKeepsTheBackoff(t *testing.T) {
+	f := newTestMFAService(t, LockoutConfig{})
+
+	code := f.nextCode(t)
+	for i := 0; i < loginFreeAttempts; i++ {
+		if err := f.mfa.DisableTOTP(testUser.ID, wrongCode(code)); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("wrong code %d: got %v, want ErrInvalidMFACode", i+1, err)
+		}
+	}
+	before, err := f.failures.Get(testUser.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A right code after the backoff must not restart it, or it would
+	// postpone the user's own next attempt
+	f.failures.now = f.failures.now.Add(loginBackoffBase)
+	if _, err := f.mfa.RegenerateRecoveryCodes(testUser.ID, code); err != nil {
+		t.Fatal(err)
+	}
+	after, err := f.failures.Get(testUser.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.FailedCount != before.FailedCount || !after.LastFailedAt.Equal(before.LastFailedAt) {
+		t.Errorf("got %d failures, the last at %s; want %d at %s",
+			after.FailedCount, after.LastFailedAt, before.FailedCount, before.LastFailedAt)
+	}
+}
@@ -0,0 +1,661 @@
+package service
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrUnknownClient       = errors.New("unknown client")
+	ErrInvalidRedirectURI  = errors.New("invalid redirect_uri")
+	ErrInvalidClientConfig = errors.New("invalid client configuration")
+)
+
+const (
+	authorizationCodeBytes = 32
+	clientIDBytes          = 16
+	clientSecretBytes      = 32
+)
+
+// OAuthError is an error that maps to an RFC 6749 error response.
+type OAuthError struct {
+	Code        string // e.g. invalid_request, invalid_grant
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// AuthorizeRequest are the parameters of an authorization request.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OAuthService implements the OAuth 2.1 authorization code flow with PKCE
+// for first-party web and mobile clients. Tokens are minted by AuthService.
+type OAuthService struct {
+	clients repository.OAuthClientRepository
+	codes   repository.AuthorizationCodeRepository
+	auth    *AuthService
+	mfa     *MFAService
+	devices *DeviceService
+	log     *logger.Logger
+	issuer  string
+	codeTTL time.Duration
+}
+
+func NewOAuthService(
+	clients repository.OAuthClientRepository,
+	codes repository.AuthorizationCodeRepository,
+	auth *AuthService,
+	mfa *MFAService,
+	devices *DeviceService,
+	log *logger.Logger,
+	issuer string,
+	codeTTL time.Duration,
+) *OAuthService {
+	return &OAuthService{
+		clients: clients,
+		codes:   codes,
+		auth:    auth,
+		mfa:     mfa,
+		devices: devices,
+		log:     log,
+		issuer:  issuer,
+		codeTTL: codeTTL,
+	}
+}
+
+// ClientRegistration describes a client to register.
+type ClientRegistration struct {
+	Name         string
+	ClientType   string   // models.ClientTypePublic (default) or models.ClientTypeConfidential
+	RedirectURIs []string // Required for the authorization_code grant
+	Scopes       []string
+	GrantTypes   []string // Defaults depend on the client type
+}
+
+// ClientCredentials are what a client presents at the token endpoint.
+// Secret is empty for public clients.
+type ClientCredentials struct {
+	ID     string
+	Secret string
+}
+
+// RegisterClient registers a client. Public clients (browser and mobile apps)
+// have no secret and rely on PKCE; confidential clients (services) get a
+// secret that is returned exactly once.
+func (s *OAuthService) RegisterClient(reg ClientRegistration) (*models.OAuthClient, string, error) {
+	if reg.ClientType == "" {
+		reg.ClientType = models.ClientTypePublic
+	}
+	if len(reg.GrantTypes) == 0 {
+		reg.GrantTypes = []string{models.GrantAuthorizationCode, models.GrantRefreshToken}
+		if reg.ClientType == models.ClientTypeConfidential {
+			reg.GrantTypes = []string{models.GrantClientCredentials}
+		}
+	}
+	if len(reg.Scopes) == 0 {
+		reg.Scopes = []string{"openid", "email"}
+	}
+
+	if err := validateRegistration(&reg); err != nil {
+		return nil, "", err
+	}
+
+	id, err := utils.GenerateRandomToken(clientIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &models.OAuthClient{
+		ID:           id,
+		Name:         reg.Name,
+		ClientType:   reg.ClientType,
+		RedirectURIs: pq.StringArray(reg.RedirectURIs),
+		Scopes:       pq.StringArray(reg.Scopes),
+		GrantTypes:   pq.StringArray(reg.GrantTypes),
+	}
+
+	var secret string
+	if client.IsConfidential() {
+		if secret, err = utils.GenerateRandomToken(clientSecretBytes); err != nil {
+			return nil, "", err
+		}
+		hash := utils.HashToken(secret)
+		client.SecretHash = &hash
+	}
+
+	if err := s.clients.Create(client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// RotateClientSecret issues a new secret for a confidential client. The old
+// secret stops working immediately.
+func (s *OAuthService) RotateClientSecret(clientID string) (string, error) {
+	secret, err := utils.GenerateRandomToken(clientSecretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.clients.UpdateSecret(clientID, utils.HashToken(secret)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUnknownClient
+		}
+		return "", err
+	}
+
+	s.log.Info("Client secret rotated for " + clientID)
+	return secret, nil
+}
+
+// DisableClient stops a client from obtaining new tokens. Access tokens it
+// already holds remain valid until they expire.
+func (s *OAuthService) DisableClient(clientID string) error {
+	if err := s.clients.Disable(clientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUnknownClient
+		}
+		return err
+	}
+
+	s.log.Info("OAuth client disabled: " + clientID)
+	return nil
+}
+
+// ListClients returns every registered client.
+func (s *OAuthService) ListClients() ([]models.OAuthClient, error) {
+	return s.clients.List()
+}
+
+// GetClient returns a registered client.
+func (s *OAuthService) GetClient(clientID string) (*models.OAuthClient, error) {
+	client, err := s.clients.GetByID(clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownClient
+	}
+	return client, err
+}
+
+func validateRegistration(reg *ClientRegistration) error {
+	if reg.Name == "" {
+		return ErrInvalidClientConfig
+	}
+	if reg.ClientType != models.ClientTypePublic && reg.ClientType != models.ClientTypeConfidential {
+		return ErrInvalidClientConfig
+	}
+
+	for _, grant := range reg.GrantTypes {
+		switch grant {
+		case models.GrantAuthorizationCode:
+			if len(reg.RedirectURIs) == 0 {
+				return ErrInvalidClientConfig
+			}
+		case models.GrantRefreshToken:
+		case models.GrantClientCredentials:
+			// A public client cannot keep a secret, so it cannot be a machine identity
+			if reg.ClientType != models.ClientTypeConfidential {
+				return ErrInvalidClientConfig
+			}
+		default:
+			return ErrInvalidClientConfig
+		}
+	}
+
+	for _, uri := range reg.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return ErrInvalidRedirectURI
+		}
+	}
+	return nil
+}
+
+// authenticateClient resolves the client at the token endpoint and checks it
+// may use grant. Confidential clients must present their secret; public
+// clients must not have one.
+func (s *OAuthService) authenticateClient(creds ClientCredentials, grant string) (*models.OAuthClient, error) {
+	if creds.ID == "" {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+
+	client, err := s.clients.GetByID(creds.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, oauthError("invalid_client", "client authentication failed")
+		}
+		return nil, err
+	}
+
+	if client.DisabledAt != nil {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+
+	if client.IsConfidential() {
+		if client.SecretHash == nil || creds.Secret == "" ||
+			subtle.ConstantTimeCompare([]byte(utils.HashToken(creds.Secret)), []byte(*client.SecretHash)) != 1 {
+			return nil, oauthError("invalid_client", "client authentication failed")
+		}
+	}
+
+	if grant != "" && !client.AllowsGrant(grant) {
+		return nil, oauthError("unauthorized_client", "client is not allowed to use the "+grant+" grant")
+	}
+
+	return client, nil
+}
+
+// Introspection is the RFC 7662 view of a token. Only Active is meaningful
+// when the token is not active.
+type Introspection struct {
+	Active    bool
+	TokenType string // "access_token" or "refresh_token"
+	Subject   string
+	ClientID  string
+	Username  string
+	Scope     string
+	TokenUse  string
+	Issuer    string
+	Audience  []string
+	JTI       string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+}
+
+// Introspect reports whether a token is currently active, including whether
+// it has been revoked. Only confidential clients (resource servers) may
+// introspect. hint is the optional token_type_hint.
+func (s *OAuthService) Introspect(creds ClientCredentials, token, hint string) (*Introspection, error) {
+	client, err := s.authenticateClient(creds, "")
+	if err != nil {
+		return nil, err
+	}
+	if !client.IsConfidential() {
+		return nil, oauthError("invalid_client", "only confidential clients may introspect tokens")
+	}
+	if token == "" {
+		return nil, oauthError("invalid_request", "token is required")
+	}
+
+	// The hint only decides which lookup runs first
+	if hint == "refresh_token" {
+		if result, err := s.introspectRefreshToken(token); err != nil || result.Active {
+			return result, err
+		}
+		return s.introspectAccessToken(token), nil
+	}
+
+	if result := s.introspectAccessToken(token); result.Active {
+		return result, nil
+	}
+	return s.introspectRefreshToken(token)
+}
+
+func (s *OAuthService) introspectAccessToken(token string) *Introspection {
+	claims, err := s.auth.ValidateToken(token)
+	if err != nil {
+		return &Introspection{Active: false}
+	}
+
+	result := &Introspection{
+		Active:    true,
+		TokenType: "access_token",
+		Subject:   claims.Subject,
+		ClientID:  claims.ClientID,
+		Username:  claims.Email,
+		Scope:     claims.Scope,
+		TokenUse:  claims.TokenUse,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		JTI:       claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Time
+	}
+	return result
+}
+
+func (s *OAuthService) introspectRefreshToken(token string) (*Introspection, error) {
+	refresh, active, err := s.auth.InspectRefreshToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return &Introspection{Active: false}, nil
+	}
+
+	result := &Introspection{
+		Active:    true,
+		TokenType: "refresh_token",
+		Subject:   refresh.UserID,
+		Scope:     refresh.Scope,
+		Issuer:    s.issuer,
+		ExpiresAt: refresh.ExpiresAt,
+		IssuedAt:  refresh.CreatedAt,
+	}
+	if refresh.ClientID != nil {
+		result.ClientID = *refresh.ClientID
+	}
+	return result, nil
+}
+
+// ClientCredentialsGrant issues a service token to a confidential client.
+// The requested scope must be a subset of the client's registered scopes;
+// an empty request grants all of them.
+func (s *OAuthService) ClientCredentialsGrant(creds ClientCredentials, scope string) (*TokenPair, error) {
+	client, err := s.authenticateClient(creds, models.GrantClientCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		requested = client.Scopes
+	}
+	for _, sc := range requested {
+		if !client.AllowsScope(sc) {
+			return nil, oauthError("invalid_scope", "scope "+sc+" is not allowed for this client")
+		}
+	}
+
+	s.log.Info("Issued service token to client " + client.ID)
+	return s.auth.GenerateServiceToken(client, strings.Join(requested, " "))
+}
+
+// ValidateAuthorizeRequest checks an authorization request.
+//
+// ErrUnknownClient and ErrInvalidRedirectURI mean the redirect URI cannot be
+// trusted and the error must be shown to the user instead of redirecting.
+// An *OAuthError can safely be sent back to the client's redirect URI.
+func (s *OAuthService) ValidateAuthorizeRequest(req *AuthorizeRequest) (*models.OAuthClient, error) {
+	client, err := s.clients.GetByID(req.ClientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUnknownClient
+		}
+		return nil, err
+	}
+	if client.DisabledAt != nil {
+		return nil, ErrUnknownClient
+	}
+
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, ErrInvalidRedirectURI
+	}
+
+	if !client.AllowsGrant(models.GrantAuthorizationCode) {
+		return client, oauthError("unauthorized_client", "client is not allowed to use the authorization code flow")
+	}
+
+	if req.ResponseType != "code" {
+		return client, oauthError("unsupported_response_type", "only response_type=code is supported")
+	}
+	if req.CodeChallengeMethod != utils.PKCEMethodS256 || !utils.ValidPKCEChallenge(req.CodeChallenge) {
+		return client, oauthError("invalid_request", "a S256 code_challenge is required")
+	}
+
+	if req.Scope == "" {
+		req.Scope = "openid"
+	}
+	for _, scope := range strings.Fields(req.Scope) {
+		if !client.AllowsScope(scope) {
+			return client, oauthError("invalid_scope", "scope "+scope+" is not allowed for this client")
+		}
+	}
+
+	return client, nil
+}
+
+// Authorize authenticates the user and returns the URL to redirect the
+// browser to, carrying a fresh authorization code.
+//
+// For users with MFA enabled no code is issued yet: the returned mfaToken
+// must be submitted to AuthorizeMFA together with a verification code.
+// origin is the browser submitting the login form.
+func (s *OAuthService) Authorize(req *AuthorizeRequest, email, password string, origin risk.Request) (redirect, mfaToken string, err error) {
+	client, err := s.ValidateAuthorizeRequest(req)
+	if err != nil {
+		return "", "", err
+	}
+
+	user, assessment, err := s.auth.Login(email, password, origin)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Restricted tokens are no use to a third-party client, so unverified
+	// users are turned away here rather than handed one
+	if s.auth.RequiresVerifiedEmail(user) {
+		s.log.Info("Authorization refused for unverified user " + user.ID)
+		return "", "", ErrEmailNotVerified
+	}
+
+	required, err := s.mfa.Required(user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	riskClaim := RiskClaim(assessment)
+	if required {
+		trusted, err := s.devices.SkipsSecondFactor(user.ID, origin, assessment)
+		if err != nil {
+			return "", "", err
+		}
+		if !trusted {
+			mfaToken, _, err = s.mfa.StartChallenge(user.ID, riskClaim)
+			return "", mfaToken, err
+		}
+	} else if assessment != nil && assessment.Decision == risk.DecisionChallenge {
+		if err := s.auth.StepUpUnavailable(user, assessment); err != nil {
+			return "", "", err
+		}
+	}
+
+	redirect, err = s.issueCode(client, req, user.ID, []string{models.AMRPassword}, riskClaim)
+	if err != nil {
+		return "", "", err
+	}
+	s.auth.RecordLogin(user, origin, riskClaim)
+	return redirect, "", nil
+}
+
+// AuthorizeMFA completes the second step of Authorize.
+func (s *OAuthService) AuthorizeMFA(req *AuthorizeRequest, mfaToken string, proof MFAProof, origin risk.Request) (string, error) {
+	client, err := s.ValidateAuthorizeRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	user, amr, riskClaim, err := s.mfa.CompleteChallenge(mfaToken, proof)
+	if err != nil {
+		return "", err
+	}
+
+	redirect, err := s.issueCode(client, req, user.ID, amr, riskClaim)
+	if err != nil {
+		return "", err
+	}
+	s.auth.RecordLogin(user, origin, riskClaim)
+	return redirect, nil
+}
+
+// issueCode stores an authorization code for an authenticated user and
+// returns the redirect carrying it.
+func (s *OAuthService) issueCode(
+	client *models.OAuthClient,
+	req *AuthorizeRequest,
+	userID string,
+	amr []string,
+	riskClaim *utils.RiskClaim,
+) (string, error) {
+	rawCode, err := utils.GenerateRandomToken(authorizationCodeBytes)
+	if err != nil {
+		return "", err
+	}
+
+	code := &models.AuthorizationCode{
+		CodeHash:      utils.HashToken(rawCode),
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         req.Scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AMR:           amr,
+		ExpiresAt:     time.Now().Add(s.codeTTL),
+		LoginRisk:     storedRisk(riskClaim),
+	}
+	if err := s.codes.Create(code); err != nil {
+		return "", err
+	}
+
+	return s.RedirectURL(req.RedirectURI, url.Values{
+		"code":  {rawCode},
+		"state": {req.State},
+	}), nil
+}
+
+// RedirectURL appends response parameters to a client redirect URI.
+// The iss parameter (RFC 9207) lets clients detect mix-up attacks.
+func (s *OAuthService) RedirectURL(redirectURI string, params url.Values) string {
+	u, _ := url.Parse(redirectURI)
+	q := u.Query()
+	for k, v := range params {
+		if len(v) > 0 && v[0] != "" {
+			q.Set(k, v[0])
+		}
+	}
+	q.Set("iss", s.issuer)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// ExchangeCode redeems an authorization code at the token endpoint.
+func (s *OAuthService) ExchangeCode(creds ClientCredentials, rawCode, redirectURI, verifier string) (*TokenPair, error) {
+	if rawCode == "" || redirectURI == "" || verifier == "" {
+		return nil, oauthError("invalid_request", "code, redirect_uri and code_verifier are required")
+	}
+	if !utils.ValidPKCEVerifier(verifier) {
+		return nil, oauthError("invalid_request", "malformed code_verifier")
+	}
+
+	client, err := s.authenticateClient(creds, models.GrantAuthorizationCode)
+	if err != nil {
+		return nil, err
+	}
+	clientID := client.ID
+
+	code, err := s.codes.Consume(utils.HashToken(rawCode))
+	if errors.Is(err, repository.ErrCodeAlreadyUsed) {
+		// A replayed code means it leaked; kill whatever it was exchanged for
+		s.log.Warn("Authorization code replay detected for client " + code.ClientID + " and user " + code.UserID)
+		if code.FamilyID != nil {
+			if err := s.auth.RevokeFamily(*code.FamilyID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, oauthError("invalid_grant", "authorization code is invalid")
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, oauthError("invalid_grant", "authorization code is invalid")
+		}
+		return nil, err
+	}
+
+	switch {
+	case time.Now().After(code.ExpiresAt):
+		return nil, oauthError("invalid_grant", "authorization code has expired")
+	case code.ClientID != clientID:
+		return nil, oauthError("invalid_grant", "authorization code was issued to another client")
+	case code.RedirectURI != redirectURI:
+		return nil, oauthError("invalid_grant", "redirect_uri does not match the authorization request")
+	case !utils.VerifyPKCE(verifier, code.CodeChallenge):
+		return nil, oauthError("invalid_grant", "code_verifier does not match the code_challenge")
+	}
+
+	user, err := s.auth.GetUser(code.UserID)
+	if err != nil {
+		return nil, oauthError("invalid_grant", "authorization code is invalid")
+	}
+
+	tokens, err := s.auth.IssueTokens(user, IssueOptions{
+		ClientID: code.ClientID,
+		Scope:    code.Scope,
+		Nonce:    code.Nonce,
+		AMR:      code.AMR,
+		Risk:     loadedRisk(code.LoginRisk),
+		AuthTime: code.CreatedAt, // Codes are issued right after the user authenticates
+		FamilyID: *code.FamilyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RefreshGrant rotates a refresh token issued to the authenticated client.
+func (s *OAuthService) RefreshGrant(creds ClientCredentials, rawRefresh string) (*TokenPair, error) {
+	if rawRefresh == "" {
+		return nil, oauthError("invalid_request", "refresh_token is required")
+	}
+
+	client, err := s.authenticateClient(creds, models.GrantRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.Refresh(rawRefresh, client.ID)
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+		return nil, oauthError("invalid_grant", "refresh token is invalid")
+	}
+	return tokens, err
+}
+
+// validRedirectURI accepts absolute URIs without fragments. Plain http is
+// only allowed for loopback addresses (native apps, RFC 8252); private-use
+// schemes such as com.example.app:/callback are allowed for mobile apps.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	case "javascript", "data", "file", "vbscript":
+		return false
+	default:
+		return true
+	}
+}
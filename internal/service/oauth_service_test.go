@@ -0,0 +1,305 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+const (
+	testVerifier    = "dBjftJeZ4CVP-mJ92K9JeWaMjM6FdDqY8Nap5GFQ3DM"
+	testChallenge   = "h1m1DLsSo5GcqeO8H9OZ9LhPvMth67u9aMzrqhta510"
+	testRedirectURI = "https://console.example.com/callback"
+)
+
+// memoryClients is an OAuthClientRepository kept in a map by ID.
+type memoryClients map[string]*models.OAuthClient
+
+func (r memoryClients) Create(client *models.OAuthClient) error {
+	r[client.ID] = client
+	return nil
+}
+
+func (r memoryClients) GetByID(id string) (*models.OAuthClient, error) {
+	c, ok := r[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (r memoryClients) List() ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	for _, c := range r {
+		clients = append(clients, *c)
+	}
+	return clients, nil
+}
+
+func (r memoryClients) UpdateSecret(id, secretHash string) error {
+	c, ok := r[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	c.SecretHash = &secretHash
+	return nil
+}
+
+func (r memoryClients) Disable(id string) error {
+	c, ok := r[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	c.DisabledAt = &now
+	return nil
+}
+
+// memoryCodes is an AuthorizationCodeRepository kept in a map by hash.
+type memoryCodes struct {
+	mu    sync.Mutex
+	codes map[string]*models.AuthorizationCode
+	next  int
+}
+
+func (r *memoryCodes) Create(code *models.AuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code.CreatedAt = time.Now()
+	copied := *code
+	r.codes[code.CodeHash] = &copied
+	return nil
+}
+
+func (r *memoryCodes) Consume(codeHash string) (*models.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if code.UsedAt != nil {
+		copied := *code
+		return &copied, repository.ErrCodeAlreadyUsed
+	}
+	now := time.Now()
+	r.next++
+	family := "code-family-" + strconv.Itoa(r.next)
+	code.UsedAt = &now
+	code.FamilyID = &family
+	copied := *code
+	return &copied, nil
+}
+
+var (
+	consoleClient = &models.OAuthClient{
+		ID:           "console",
+		ClientType:   models.ClientTypePublic,
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{"openid", "email"},
+		GrantTypes:   []string{models.GrantAuthorizationCode, models.GrantRefreshToken},
+	}
+	mobileClient = &models.OAuthClient{
+		ID:           "mobile",
+		ClientType:   models.ClientTypePublic,
+		RedirectURIs: []string{"com.example.app:/callback"},
+		Scopes:       []string{"openid"},
+		GrantTypes:   []string{models.GrantAuthorizationCode},
+	}
+)
+
+func newTestOAuthService(t *testing.T) (*OAuthService, *memoryCodes, *memoryRefreshTokens) {
+	t.Helper()
+	auth, refresh := newTestAuthService(t)
+	codes := &memoryCodes{codes: make(map[string]*models.AuthorizationCode)}
+	s := NewOAuthService(
+		memoryClients{consoleClient.ID: consoleClient, mobileClient.ID: mobileClient},
+		codes,
+		auth,
+		nil,
+		nil,
+		logger.New(),
+		"https://auth.example.com",
+		time.Minute,
+	)
+	return s, codes, refresh
+}
+
+// authorize issues a code to the console for testUser and returns it.
+func authorize(t *testing.T, s *OAuthService) string {
+	t.Helper()
+	req := &AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            consoleClient.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid email",
+		State:               "xyz",
+		CodeChallenge:       testChallenge,
+		CodeChallengeMethod: utils.PKCEMethodS256,
+	}
+	if _, err := s.ValidateAuthorizeRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	redirect, err := s.issueCode(consoleClient, req, testUser.ID, []string{models.AMRPassword}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("state") != "xyz" || q.Get("iss") != "https://auth.example.com" {
+		t.Errorf("redirect %s does not carry state and iss", redirect)
+	}
+	return q.Get("code")
+}
+
+func oauthCode(err error) string {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr.Code
+	}
+	return ""
+}
+
+func TestExchangeCode(t *testing.T) {
+	s, _, _ := newTestOAuthService(t)
+	code := authorize(t, s)
+
+	tokens, err := s.ExchangeCode(ClientCredentials{ID: consoleClient.ID}, code, testRedirectURI, testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.IDToken == "" {
+		t.Errorf("got %+v, want access, refresh and ID tokens", tokens)
+	}
+	if tokens.Scope != "openid email" || tokens.FamilyID == "" {
+		t.Errorf("got scope %q and family %q", tokens.Scope, tokens.FamilyID)
+	}
+
+	// The refresh token belongs to the client
+	if _, err := s.RefreshGrant(ClientCredentials{ID: consoleClient.ID}, tokens.RefreshToken); err != nil {
+		t.Errorf("RefreshGrant: %v", err)
+	}
+}
+
+func TestExchangeCodeReplayRevokesTheTokens(t *testing.T) {
+	s, _, refresh := newTestOAuthService(t)
+	code := authorize(t, s)
+	creds := ClientCredentials{ID: consoleClient.ID}
+
+	tokens, err := s.ExchangeCode(creds, code, testRedirectURI, testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ExchangeCode(creds, code, testRedirectURI, testVerifier); oauthCode(err) != "invalid_grant" {
+		t.Fatalf("replaying a code: got %v, want invalid_grant", err)
+	}
+	if refresh.get(t, tokens.RefreshToken).RevokedAt == nil {
+		t.Error("tokens from a replayed code were not revoked")
+	}
+	if _, err := s.RefreshGrant(creds, tokens.RefreshToken); oauthCode(err) != "invalid_grant" {
+		t.Errorf("refreshing after a replay: got %v, want invalid_grant", err)
+	}
+}
+
+func TestExchangeCodeRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		creds       ClientCredentials
+		redirectURI string
+		verifier    string
+		code        string // "" for the issued code
+		expire      bool
+		want        string
+	}{
+		{"wrong verifier", ClientCredentials{ID: "console"}, testRedirectURI, "a" + testVerifier[1:], "", false, "invalid_grant"},
+		{"malformed verifier", ClientCredentials{ID: "console"}, testRedirectURI, "short", "", false, "invalid_request"},
+		{"missing verifier", ClientCredentials{ID: "console"}, testRedirectURI, "", "", false, "invalid_request"},
+		{"other redirect_uri", ClientCredentials{ID: "console"}, testRedirectURI + "2", testVerifier, "", false, "invalid_grant"},
+		{"other client", ClientCredentials{ID: "mobile"}, testRedirectURI, testVerifier, "", false, "invalid_grant"},
+		{"unknown client", ClientCredentials{ID: "nobody"}, testRedirectURI, testVerifier, "", false, "invalid_client"},
+		{"unknown code", ClientCredentials{ID: "console"}, testRedirectURI, testVerifier, "not-a-code", false, "invalid_grant"},
+		{"expired code", ClientCredentials{ID: "console"}, testRedirectURI, testVerifier, "", true, "invalid_grant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, codes, _ := newTestOAuthService(t)
+			code := authorize(t, s)
+			if tt.expire {
+				codes.codes[utils.HashToken(code)].ExpiresAt = time.Now().Add(-time.Second)
+			}
+			if tt.code != "" {
+				code = tt.code
+			}
+
+			tokens, err := s.ExchangeCode(tt.creds, code, tt.redirectURI, tt.verifier)
+			if got := oauthCode(err); got != tt.want {
+				t.Errorf("got %v, %v; want %s", tokens, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAuthorizeRequest(t *testing.T) {
+	valid := AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            consoleClient.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid",
+		CodeChallenge:       testChallenge,
+		CodeChallengeMethod: utils.PKCEMethodS256,
+	}
+
+	tests := []struct {
+		name   string
+		change func(*AuthorizeRequest)
+		want   string // OAuth error code; "" for success
+		client bool   // Whether the error may be sent to the redirect URI
+	}{
+		{"valid", func(*AuthorizeRequest) {}, "", true},
+		{"default scope", func(r *AuthorizeRequest) { r.Scope = "" }, "", true},
+		{"plain PKCE", func(r *AuthorizeRequest) { r.CodeChallengeMethod = "plain"; r.CodeChallenge = testVerifier }, "invalid_request", true},
+		{"no PKCE", func(r *AuthorizeRequest) { r.CodeChallenge = ""; r.CodeChallengeMethod = "" }, "invalid_request", true},
+		{"implicit flow", func(r *AuthorizeRequest) { r.ResponseType = "token" }, "unsupported_response_type", true},
+		{"scope not allowed", func(r *AuthorizeRequest) { r.Scope = "openid admin" }, "invalid_scope", true},
+		{"unregistered redirect", func(r *AuthorizeRequest) { r.RedirectURI = "https://evil.example.com/callback" }, "", false},
+		{"unknown client", func(r *AuthorizeRequest) { r.ClientID = "nobody" }, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _ := newTestOAuthService(t)
+			req := valid
+			tt.change(&req)
+
+			client, err := s.ValidateAuthorizeRequest(&req)
+			switch {
+			case !tt.client:
+				// Never redirect to an unverified client or URI
+				if err == nil || oauthCode(err) != "" {
+					t.Errorf("got %v, want an error shown to the user", err)
+				}
+			case tt.want == "":
+				if err != nil || client == nil {
+					t.Errorf("got %v, want the client", err)
+				}
+			default:
+				if oauthCode(err) != tt.want || client == nil {
+					t.Errorf("got %v, want %s with the client", err, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/mailer"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset link")
+	ErrResetThrottled    = errors.New("too many password reset requests, try again later")
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrPasswordUnchanged = errors.New("new password must differ from the current one")
+)
+
+const (
+	resetTokenBytes      = 32
+	resetRequestInterval = time.Minute // Minimum time between two reset emails to one user
+	resetRequestsPerDay  = 5
+)
+
+// PasswordConfig controls PasswordService.
+type PasswordConfig struct {
+	ResetURL      string        // Page the emailed link opens; the token is appended as ?token=
+	ResetTokenTTL time.Duration // How long a reset link stays valid
+}
+
+// PasswordService lets signed-in users change their password and lets
+// everyone else recover their account with an emailed, single-use reset link.
+type PasswordService struct {
+	resets repository.PasswordResetRepository
+	users  repository.UserRepository
+	auth   *AuthService
+	mailer mailer.Mailer
+	log    *logger.Logger
+	cfg    PasswordConfig
+}
+
+func NewPasswordService(
+	resets repository.PasswordResetRepository,
+	users repository.UserRepository,
+	auth *AuthService,
+	mail mailer.Mailer,
+	log *logger.Logger,
+	cfg PasswordConfig,
+) *PasswordService {
+	return &PasswordService{
+		resets: resets,
+		users:  users,
+		auth:   auth,
+		mailer: mail,
+		log:    log,
+		cfg:    cfg,
+	}
+}
+
+// ForgotPassword mails a reset link to the account registered under email.
+// Unknown addresses are silently ignored so the endpoint cannot be used to
+// discover accounts; callers should treat ErrResetThrottled the same way.
+func (s *PasswordService) ForgotPassword(email string) error {
+	user, err := s.users.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	recent, err := s.resets.CountSince(user.ID, now.Add(-resetRequestInterval))
+	if err != nil {
+		return err
+	}
+	daily, err := s.resets.CountSince(user.ID, now.Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if recent > 0 || daily >= resetRequestsPerDay {
+		return ErrResetThrottled
+	}
+
+	rawToken, err := utils.GenerateRandomToken(resetTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	err = s.resets.Create(&models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(rawToken),
+		ExpiresAt: now.Add(s.cfg.ResetTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(s.resetEmail(user.Email, rawToken)); err != nil {
+		s.log.Error("Failed to send password reset email to user " + user.ID + ": " + err.Error())
+		return err
+	}
+
+	s.log.Info("Password reset email sent to user " + user.ID)
+	return nil
+}
+
+// ResetPassword redeems a reset link: it sets the new password, revokes
+// every access and refresh token the user holds and notifies the user.
+// A password the policy rejects leaves the link usable for another try.
+func (s *PasswordService) ResetPassword(rawToken, newPassword string) error {
+	tokenHash := utils.HashToken(rawToken)
+	token, err := s.resets.Get(tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.users.GetByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if err := s.auth.ValidatePassword(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	// Only one of two concurrent resets with the same link gets through
+	if _, err := s.resets.Consume(tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if err := s.auth.SetPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	// Whoever held the old password must not keep a session
+	if err := s.auth.LogoutAll(user.ID); err != nil {
+		return err
+	}
+
+	s.log.Info("Password reset for user " + user.ID)
+	s.notifyChanged(user, "All devices were signed out.")
+	return nil
+}
+
+// ChangePassword sets a new password for the signed-in user after checking
+// the current one. Every other session is signed out: all tokens the user
+// holds are revoked and a fresh pair, with the same client, scope and amr
+// as the presented token, is returned to keep the caller signed in.
+func (s *PasswordService) ChangePassword(claims *utils.Claims, currentPassword, newPassword string) (*TokenPair, error) {
+	user, err := s.users.GetByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.auth.authenticate(user, currentPassword); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			return nil, ErrWrongPassword
+		}
+		return nil, err
+	}
+	if newPassword == currentPassword {
+		return nil, ErrPasswordUnchanged
+	}
+
+	if err := s.auth.SetPassword(user, newPassword); err != nil {
+		return nil, err
+	}
+	if err := s.auth.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.IssueTokens(user, IssueOptions{
+		ClientID: claims.ClientID,
+		Scope:    claims.Scope,
+		AMR:      claims.AMR,
+		Risk:     claims.Risk,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Password changed for user " + user.ID)
+	s.notifyChanged(user, "Your other devices were signed out.")
+	return tokens, nil
+}
+
+// notifyChanged tells the user their password changed, so an unexpected
+// change is noticed. Failing to send does not undo the change.
+func (s *PasswordService) notifyChanged(user *models.User, sessions string) {
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: "The password of your account was changed on " +
+			time.Now().UTC().Format("2 January 2006 at 15:04 MST") + ".\n\n" +
+			sessions + " If you did not make this change, " +
+			"reset your password right away and contact support.\n",
+	}
+	if err := s.mailer.Send(msg); err != nil {
+		s.log.Error("Failed to send password change notice to user " + user.ID + ": " + err.Error())
+	}
+}
+
+func (s *PasswordService) resetEmail(to, token string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: "Someone asked to reset the password of your account. " +
+			"To choose a new password, open the link below:\n\n" +
+			tokenLink(s.cfg.ResetURL, token) + "\n\n" +
+			"The link expires in " + durationText(s.cfg.ResetTokenTTL) + " and works once. " +
+			"If you did not ask for this, you can ignore this email; your password stays the same.\n",
+	}
+}
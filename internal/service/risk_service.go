@@ -0,0 +1,404 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/device"
+	"github.com/abhay786-20/fraud-auth-service/internal/geoip"
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
+	"github.com/abhay786-20/fraud-auth-service/internal/rules"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+var (
+	ErrLoginDenied  = errors.New("login denied")
+	ErrSignupDenied = errors.New("signup denied")
+)
+
+// What a challenged login gets when the user has no second factor to step up with
+const (
+	RiskFallbackAllow = "allow" // Tokens are issued anyway; the risk claim tells resource servers
+	RiskFallbackDeny  = "deny"  // The login is refused
+)
+
+// loginHistoryDepth is how many earlier logins signals compare against.
+const loginHistoryDepth = 50
+
+// RiskConfig controls RiskService.
+type RiskConfig struct {
+	Enabled           bool
+	ChallengeFallback string // RiskFallbackAllow or RiskFallbackDeny
+}
+
+// RiskService scores password logins with the risk engine and the fraud
+// rules, and keeps the login history they compare against. Every assessment
+// is written to the audit log. Signups are screened by the fraud rules.
+type RiskService struct {
+	engine  *risk.Engine
+	rules   *RulesService
+	history repository.LoginHistoryRepository
+	devices *DeviceService
+	geo     geoip.Locator // nil when no GeoIP database is configured
+	audit   repository.AuditRepository
+	log     *logger.Logger
+	cfg     RiskConfig
+}
+
+func NewRiskService(
+	engine *risk.Engine,
+	rules *RulesService,
+	history repository.LoginHistoryRepository,
+	devices *DeviceService,
+	geo geoip.Locator,
+	audit repository.AuditRepository,
+	log *logger.Logger,
+	cfg RiskConfig,
+) *RiskService {
+	return &RiskService{
+		engine:  engine,
+		rules:   rules,
+		history: history,
+		devices: devices,
+		geo:     geo,
+		audit:   audit,
+		log:     log,
+		cfg:     cfg,
+	}
+}
+
+// Assess scores a login whose password was correct. failures are the
+// attempts that failed before it, nil if none did. attack is the scope of a
+// credential stuffing attack covering the client (see StuffingService), ""
+// if there is none; logins during an attack are challenged even when
+// scoring is disabled. Login fraud rules run after the score, and may
+// override its decision. It returns nil when there is nothing to assess,
+// and ErrLoginDenied when the login is denied.
+func (s *RiskService) Assess(user *models.User, origin risk.Request, failures *models.LoginFailures, attack string) (*risk.Assessment, error) {
+	if !s.cfg.Enabled && attack == "" && !s.rules.Has(rules.EventLogin) {
+		return nil, nil
+	}
+
+	location := s.locate(origin.IP)
+	login, err := s.login(user, origin, failures, location)
+	if err != nil {
+		return nil, err
+	}
+
+	assessment := &risk.Assessment{Decision: risk.DecisionAllow}
+	if s.cfg.Enabled {
+		assessment = s.engine.Assess(login)
+	}
+	s.applyRules(user, login, attack, assessment)
+	if attack != "" {
+		assessment.Attack = attack
+		assessment.Challenge(risk.Reason{Signal: "credential_stuffing", Detail: "attack in progress (" + attackScopeText(attack) + ")"})
+	}
+
+	from := origin.IP
+	if location != nil {
+		from += " (" + location.String() + ")"
+	}
+
+	if assessment.Decision == risk.DecisionChallenge {
+		s.log.Warn("Login of user " + user.ID + " from " + from + " scored " + assessment.String())
+	}
+
+	event := &models.AuditEvent{
+		Event:  models.AuditLoginRisk,
+		UserID: &user.ID,
+		Detail: assessment.String() + "; ip " + from,
+	}
+	if err := s.audit.Record(event); err != nil {
+		s.log.Error("Failed to record " + event.Event + " audit event: " + err.Error())
+	}
+
+	if assessment.Decision == risk.DecisionDeny {
+		return nil, s.deny(user.ID, "scored "+assessment.String()+"; ip "+from)
+	}
+	return assessment, nil
+}
+
+// login gathers what the risk signals look at.
+func (s *RiskService) login(user *models.User, origin risk.Request, failures *models.LoginFailures, location *geoip.Location) (*risk.Login, error) {
+	history, err := s.history.Recent(user.ID, loginHistoryDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	known, devices, err := s.devices.Known(user.ID, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	login := &risk.Login{
+		Request:        origin,
+		UserID:         user.ID,
+		AccountCreated: user.CreatedAt,
+		Location:       location,
+		Device:         known,
+		KnownDevices:   devices,
+		History:        history,
+	}
+	if failures != nil {
+		login.Failures = failures.FailedCount
+		login.LastFailure = failures.LastFailedAt
+	}
+	return login, nil
+}
+
+// applyRules runs the login fraud rules and applies their outcome: tags
+// become zero-score reasons, and a deciding rule replaces the decision.
+func (s *RiskService) applyRules(user *models.User, login *risk.Login, attack string, assessment *risk.Assessment) {
+	ctx := &rules.Context{
+		User: rules.User{
+			ID:            user.ID,
+			Email:         strings.ToLower(user.Email),
+			EmailDomain:   emailDomain(user.Email),
+			EmailVerified: user.IsEmailVerified(),
+			Role:          user.Role,
+			CreatedAt:     user.CreatedAt,
+		},
+		Request: requestContext(login.Request),
+		Device:  deviceContext(login.Request, login.Device, login.KnownDevices),
+		Geo:     geoContext(login.Location),
+		Velocity: rules.Velocity{
+			Failures:    login.Failures,
+			UnderAttack: attack != "",
+			AttackScope: attack,
+		},
+		Risk: rules.Risk{
+			Score:    assessment.Score,
+			Decision: assessment.Decision,
+			Reasons:  assessment.Codes(),
+		},
+	}
+
+	countries := make(map[string]bool)
+	for _, record := range login.History {
+		age := login.Time.Sub(record.CreatedAt)
+		if age <= time.Hour {
+			ctx.Velocity.Logins1h++
+		}
+		if age <= 24*time.Hour {
+			ctx.Velocity.Logins24h++
+			if record.Country != "" {
+				countries[record.Country] = true
+			}
+		}
+	}
+	ctx.Velocity.Countries24h = len(countries)
+
+	outcome := s.rules.Evaluate(rules.EventLogin, "login of user "+user.ID, ctx)
+	if outcome == nil {
+		return
+	}
+
+	for _, tag := range outcome.Tags {
+		assessment.Reasons = append(assessment.Reasons, risk.Reason{Signal: "tag:" + tag})
+	}
+	reason := risk.Reason{Signal: "rule:" + outcome.Rule}
+	switch outcome.Decision {
+	case rules.ActionAllow:
+		assessment.Decide(risk.DecisionAllow, reason)
+	case rules.ActionChallenge:
+		assessment.Decide(risk.DecisionChallenge, reason)
+	case rules.ActionDeny:
+		assessment.Decide(risk.DecisionDeny, reason)
+	}
+}
+
+// ScreenSignup runs the signup fraud rules on an account about to be
+// created for email. It returns ErrSignupDenied if a rule refuses it.
+// Matches are written to the audit log, tags included.
+func (s *RiskService) ScreenSignup(email string, origin risk.Request, attack string) error {
+	if !s.rules.Has(rules.EventSignup) {
+		return nil
+	}
+
+	location := s.locate(origin.IP)
+	ctx := &rules.Context{
+		User: rules.User{
+			Email:       strings.ToLower(email),
+			EmailDomain: emailDomain(email),
+		},
+		Request: requestContext(origin),
+		Device:  deviceContext(origin, nil, 0),
+		Geo:     geoContext(location),
+		Velocity: rules.Velocity{
+			UnderAttack: attack != "",
+			AttackScope: attack,
+		},
+	}
+
+	outcome := s.rules.Evaluate(rules.EventSignup, "signup of "+email, ctx)
+	if outcome == nil || outcome.String() == "" {
+		return nil
+	}
+
+	event := &models.AuditEvent{
+		Event:  models.AuditSignupScreened,
+		Detail: "signup of " + email + " from " + origin.IP + ": " + outcome.String(),
+	}
+	if err := s.audit.Record(event); err != nil {
+		s.log.Error("Failed to record " + event.Event + " audit event: " + err.Error())
+	}
+
+	if outcome.Decision == rules.ActionDeny {
+		s.log.Warn("Signup of " + email + " from " + origin.IP + " denied by fraud rule " + outcome.Rule)
+		return ErrSignupDenied
+	}
+	return nil
+}
+
+// StepUpUnavailable is called for a challenged login when the user has no
+// second factor. It returns ErrLoginDenied unless the fallback allows it.
+// During a credential stuffing attack the fallback does not apply: a correct
+// password is exactly what the attacker has, and there is no other step.
+func (s *RiskService) StepUpUnavailable(userID string, assessment *risk.Assessment) error {
+	if assessment.Attack != "" {
+		return s.deny(userID, "challenged without a second factor during a credential stuffing attack ("+attackScopeText(assessment.Attack)+")")
+	}
+	if s.cfg.ChallengeFallback == RiskFallbackAllow {
+		return nil
+	}
+	return s.deny(userID, "challenged without a second factor")
+}
+
+// deny refuses a login whose password was correct. Callers answer it like a
+// wrong password, so the reason is only logged and audited.
+func (s *RiskService) deny(userID, reason string) error {
+	s.log.Warn("Login of user " + userID + " denied: " + reason)
+	event := &models.AuditEvent{
+		Event:  models.AuditLoginDenied,
+		UserID: &userID,
+		Detail: reason,
+	}
+	if err := s.audit.Record(event); err != nil {
+		s.log.Error("Failed to record " + event.Event + " audit event: " + err.Error())
+	}
+	return ErrLoginDenied
+}
+
+// RecordLogin adds a completed login to the user's history and device
+// registry. The login has already succeeded, so a failure to store it is
+// only logged.
+func (s *RiskService) RecordLogin(user *models.User, origin risk.Request, claim *utils.RiskClaim) {
+	location := s.locate(origin.IP)
+	s.devices.Seen(user, origin, location)
+
+	record := &models.LoginRecord{
+		UserID:    user.ID,
+		IP:        origin.IP,
+		UserAgent: origin.UserAgent,
+	}
+	if claim != nil {
+		record.RiskScore = &claim.Score
+	}
+	if location != nil {
+		record.Country = location.Country
+		record.City = location.City
+		record.Latitude = &location.Latitude
+		record.Longitude = &location.Longitude
+		record.AccuracyKm = location.RadiusKm
+	}
+
+	if err := s.history.Record(record); err != nil {
+		s.log.Error("Failed to record login of user " + user.ID + ": " + err.Error())
+	}
+}
+
+// locate looks up where ip is. Lookup errors only cost the location-based
+// signals, so they are logged rather than failing the login.
+func (s *RiskService) locate(ip string) *geoip.Location {
+	if s.geo == nil {
+		return nil
+	}
+
+	location, err := s.geo.Lookup(ip)
+	if err != nil {
+		s.log.Error("GeoIP lookup of " + ip + " failed: " + err.Error())
+		return nil
+	}
+	return location
+}
+
+// requestContext, deviceContext and geoContext describe the client to
+// fraud rules. known is the user's device the request comes from, nil if
+// it is new.
+func requestContext(origin risk.Request) rules.Request {
+	return rules.Request{
+		IP:             origin.IP,
+		UserAgent:      origin.UserAgent,
+		AcceptLanguage: origin.AcceptLanguage,
+		Time:           origin.Time,
+		Hour:           origin.Time.UTC().Hour(),
+	}
+}
+
+func deviceContext(origin risk.Request, known *models.UserDevice, knownDevices int) rules.Device {
+	features := device.Parse(origin.UserAgent, origin.AcceptLanguage)
+	ctx := rules.Device{
+		ID:            DeviceID(origin),
+		Name:          features.Name(),
+		Browser:       features.Browser,
+		OS:            features.OS,
+		Language:      features.Language,
+		Fingerprinted: origin.Fingerprint != "",
+		KnownDevices:  knownDevices,
+	}
+	if known != nil {
+		ctx.Known = true
+		ctx.Trusted = origin.DeviceToken != "" && known.IsTrustedWith(utils.HashToken(origin.DeviceToken), origin.Time)
+	}
+	return ctx
+}
+
+func geoContext(location *geoip.Location) rules.Geo {
+	if location == nil {
+		return rules.Geo{}
+	}
+	return rules.Geo{
+		Located:    true,
+		Country:    location.Country,
+		City:       location.City,
+		Latitude:   location.Latitude,
+		Longitude:  location.Longitude,
+		AccuracyKm: location.RadiusKm,
+	}
+}
+
+// emailDomain returns the lower-case part of an address after the @.
+func emailDomain(email string) string {
+	_, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	return domain
+}
+
+// RiskClaim is the token claim for an assessment, nil if the login was not scored.
+func RiskClaim(assessment *risk.Assessment) *utils.RiskClaim {
+	if assessment == nil {
+		return nil
+	}
+	return &utils.RiskClaim{Score: assessment.Score, Reasons: assessment.Codes()}
+}
+
+// storedRisk is the form a risk claim is stored in along with a grant.
+func storedRisk(claim *utils.RiskClaim) models.LoginRisk {
+	if claim == nil {
+		return models.LoginRisk{}
+	}
+	score := claim.Score
+	return models.LoginRisk{RiskScore: &score, RiskReasons: claim.Reasons}
+}
+
+// loadedRisk turns a stored risk back into a token claim.
+func loadedRisk(stored models.LoginRisk) *utils.RiskClaim {
+	if stored.RiskScore == nil {
+		return nil
+	}
+	return &utils.RiskClaim{Score: *stored.RiskScore, Reasons: stored.RiskReasons}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
+	"github.com/abhay786-20/fraud-auth-service/internal/rules"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+)
+
+// recordedAudit keeps every event.
+type recordedAudit struct {
+	events []*models.AuditEvent
+}
+
+func (a *recordedAudit) Record(event *models.AuditEvent) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func TestStepUpUnavailable(t *testing.T) {
+	challenged := &risk.Assessment{Decision: risk.DecisionChallenge}
+	attacked := &risk.Assessment{Decision: risk.DecisionChallenge, Attack: "192.0.2.0/24"}
+
+	tests := []struct {
+		name       string
+		fallback   string
+		assessment *risk.Assessment
+		want       error
+	}{
+		{"allowed by the fallback", RiskFallbackAllow, challenged, nil},
+		{"denied by the fallback", RiskFallbackDeny, challenged, ErrLoginDenied},
+		{"attack overrides allow", RiskFallbackAllow, attacked, ErrLoginDenied},
+		{"attack with deny", RiskFallbackDeny, attacked, ErrLoginDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &recordedAudit{}
+			s := &RiskService{audit: audit, log: logger.New(), cfg: RiskConfig{ChallengeFallback: tt.fallback}}
+			if err := s.StepUpUnavailable("user-1", tt.assessment); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+
+			// Denials are answered like a wrong password, so the audit
+			// log is the only place the reason shows up
+			denied := len(audit.events) == 1 && audit.events[0].Event == models.AuditLoginDenied &&
+				*audit.events[0].UserID == "user-1"
+			if denied != (tt.want != nil) {
+				t.Errorf("got audit events %+v", audit.events)
+			}
+		})
+	}
+}
+
+// noHistory is a login history without earlier logins.
+type noHistory struct{}
+
+func (noHistory) Record(*models.LoginRecord) error                 { return nil }
+func (noHistory) Recent(string, int) ([]models.LoginRecord, error) { return nil, nil }
+
+func TestAssessAuditsDenials(t *testing.T) {
+	source := &memoryRules{}
+	source.set(loginRule("deny-office", `request.ip == "192.0.2.10"`, rules.ActionDeny))
+	log := logger.New()
+	ruleSet := NewRulesService(source, log, RulesConfig{})
+	if err := ruleSet.Load(); err != nil {
+		t.Fatal(err)
+	}
+	audit := &recordedAudit{}
+	devices := NewDeviceService(newMemoryDevices(), discardMailer{}, log, DeviceConfig{})
+	s := NewRiskService(nil, ruleSet, noHistory{}, devices, nil, audit, log, RiskConfig{})
+
+	assessment, err := s.Assess(testUser, risk.Request{IP: "192.0.2.10"}, nil, "")
+	if !errors.Is(err, ErrLoginDenied) || assessment != nil {
+		t.Fatalf("got %v, %v; want ErrLoginDenied", assessment, err)
+	}
+	if len(audit.events) != 2 || audit.events[0].Event != models.AuditLoginRisk || audit.events[1].Event != models.AuditLoginDenied {
+		t.Fatalf("got audit events %+v, want login.risk then login.denied", audit.events)
+	}
+	if detail := audit.events[1].Detail; !strings.Contains(detail, "deny-office") || !strings.Contains(detail, "192.0.2.10") {
+		t.Errorf("denial detail %q does not give the rule and IP", detail)
+	}
+
+	if _, err := s.Assess(testUser, risk.Request{IP: "198.51.100.1"}, nil, ""); err != nil {
+		t.Errorf("login from elsewhere: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/rules"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+)
+
+// RulesConfig controls RulesService.
+type RulesConfig struct {
+	Shadow         bool          // Run every rule in shadow mode
+	ReloadInterval time.Duration // How often the source is checked for changes; 0 disables reloading
+}
+
+// RulesService holds the fraud rules analysts maintain outside the code and
+// keeps them current: the source is polled and reloaded when it changes.
+// A reload that fails keeps the rules that were running.
+type RulesService struct {
+	source rules.Source // nil when no rules are configured
+	log    *logger.Logger
+	cfg    RulesConfig
+
+	mu      sync.RWMutex
+	set     *rules.Set
+	version string
+}
+
+func NewRulesService(
+	source rules.Source,
+	log *logger.Logger,
+	cfg RulesConfig,
+) *RulesService {
+	return &RulesService{
+		source: source,
+		log:    log,
+		cfg:    cfg,
+	}
+}
+
+// Load compiles the rules from the source, unless they are unchanged since
+// the last load.
+func (s *RulesService) Load() error {
+	if s.source == nil {
+		return nil
+	}
+
+	version, err := s.source.Version()
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	unchanged := s.set != nil && version == s.version
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	defs, err := s.source.List()
+	if err != nil {
+		return err
+	}
+	set, err := rules.Compile(defs, s.cfg.Shadow)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.set = set
+	s.version = version
+	s.mu.Unlock()
+
+	mode := ""
+	if s.cfg.Shadow {
+		mode = " in shadow mode"
+	}
+	s.log.Info("Loaded " + strconv.Itoa(set.Len()) + " fraud rules" + mode)
+	return nil
+}
+
+// Run reloads the rules whenever the source changes. It returns when ctx is
+// cancelled.
+func (s *RulesService) Run(ctx context.Context) {
+	if s.source == nil || s.cfg.ReloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(); err != nil {
+				s.log.Error("Failed to reload fraud rules, keeping the previous ones: " + err.Error())
+			}
+		}
+	}
+}
+
+// Has reports whether any rule runs on event.
+func (s *RulesService) Has(event string) bool {
+	set := s.current()
+	return set != nil && set.Has(event)
+}
+
+// Evaluate runs the rules for event. what names the login or signup in
+// logs, e.g. "login of user 42". Shadow matches and rule errors are logged
+// here; the caller applies the rest of the outcome. It returns nil when no
+// rules are loaded.
+func (s *RulesService) Evaluate(event, what string, ctx *rules.Context) *rules.Outcome {
+	set := s.current()
+	if set == nil {
+		return nil
+	}
+
+	outcome, errs := set.Evaluate(event, ctx)
+	for _, err := range errs {
+		s.log.Error("Fraud rule failed on " + what + ": " + err.Error())
+	}
+	for _, match := range outcome.Shadowed() {
+		s.log.Info("Shadow fraud rule " + match.Rule + " would have applied " + match.Action + " to " + what)
+	}
+	return outcome
+}
+
+func (s *RulesService) current() *rules.Set {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.set
+}
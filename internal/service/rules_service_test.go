@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/rules"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+)
+
+// memoryRules is a rules.Source whose version changes with every set call.
+type memoryRules struct {
+	defs    []models.FraudRule
+	version int
+	lists   int
+}
+
+func (s *memoryRules) set(defs ...models.FraudRule) {
+	s.defs = defs
+	s.version++
+}
+
+func (s *memoryRules) List() ([]models.FraudRule, error) {
+	s.lists++
+	return s.defs, nil
+}
+
+func (s *memoryRules) Version() (string, error) {
+	return strconv.Itoa(s.version), nil
+}
+
+func loginRule(name, expression, action string) models.FraudRule {
+	return models.FraudRule{Name: name, Event: rules.EventLogin, Expression: expression, Action: action, Enabled: true}
+}
+
+func TestRulesServiceReloadsChanges(t *testing.T) {
+	source := &memoryRules{}
+	source.set(loginRule("deny-kp", `geo.country == "KP"`, rules.ActionDeny))
+	s := NewRulesService(source, logger.New(), RulesConfig{})
+
+	if outcome := s.Evaluate(rules.EventLogin, "login", &rules.Context{}); outcome != nil {
+		t.Errorf("got %s before loading, want no outcome", outcome)
+	}
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	kp := &rules.Context{Geo: rules.Geo{Country: "KP"}}
+	if outcome := s.Evaluate(rules.EventLogin, "login", kp); outcome.Decision != rules.ActionDeny {
+		t.Errorf("got %s, want deny", outcome)
+	}
+
+	// An unchanged source is not read again
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if source.lists != 1 {
+		t.Errorf("source listed %d times, want once", source.lists)
+	}
+
+	source.set(loginRule("challenge-kp", `geo.country == "KP"`, rules.ActionChallenge))
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if outcome := s.Evaluate(rules.EventLogin, "login", kp); outcome.Decision != rules.ActionChallenge {
+		t.Errorf("got %s after the reload, want challenge", outcome)
+	}
+
+	// A broken change keeps the running rules, and is retried
+	source.set(loginRule("typo", `geo.contry == "KP"`, rules.ActionDeny))
+	if err := s.Load(); err == nil {
+		t.Fatal("a broken rule loaded")
+	}
+	if outcome := s.Evaluate(rules.EventLogin, "login", kp); outcome.Decision != rules.ActionChallenge {
+		t.Errorf("got %s after a failed reload, want the previous rules", outcome)
+	}
+	if err := s.Load(); err == nil {
+		t.Error("a failed version was not retried")
+	}
+}
+
+func TestRulesServiceShadowMode(t *testing.T) {
+	source := &memoryRules{}
+	source.set(loginRule("deny-all", `true`, rules.ActionDeny))
+	s := NewRulesService(source, logger.New(), RulesConfig{Shadow: true})
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	outcome := s.Evaluate(rules.EventLogin, "login of user 1", &rules.Context{})
+	if outcome.Decision != "" || len(outcome.Shadowed()) != 1 {
+		t.Errorf("got %s with %d shadow matches, want only the shadow match", outcome, len(outcome.Shadowed()))
+	}
+	if !s.Has(rules.EventLogin) || s.Has(rules.EventSignup) {
+		t.Error("Has does not report the loaded events")
+	}
+}
+
+func TestRulesServiceWithoutSource(t *testing.T) {
+	s := NewRulesService(nil, logger.New(), RulesConfig{})
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Has(rules.EventLogin) || s.Evaluate(rules.EventLogin, "login", &rules.Context{}) != nil {
+		t.Error("rules without a source")
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/internal/risk"
+	"github.com/abhay786-20/fraud-auth-service/internal/stuffing"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+)
+
+// StuffingService watches failed logins across all accounts for credential
+// stuffing and password spraying. While an attack is in progress, password
+// logins from the networks concerned (or from everywhere, for a sprayed
+// password) must pass a second factor and get tighter rate limits. Each
+// campaign is written to the audit log when it is detected.
+//
+// The detector's state lives in the rate limiter's backend, so it fails
+// open like the rate limiter does: errors are logged and the login goes on.
+type StuffingService struct {
+	detector *stuffing.Detector // nil when detection is disabled
+	audit    repository.AuditRepository
+	log      *logger.Logger
+}
+
+func NewStuffingService(
+	detector *stuffing.Detector,
+	audit repository.AuditRepository,
+	log *logger.Logger,
+) *StuffingService {
+	return &StuffingService{
+		detector: detector,
+		audit:    audit,
+		log:      log,
+	}
+}
+
+// RecordFailure counts a failed password login, including ones for emails
+// without an account.
+func (s *StuffingService) RecordFailure(origin risk.Request, password string) {
+	if s.detector == nil {
+		return
+	}
+
+	campaigns, err := s.detector.Observe(context.Background(), origin.IP, password)
+	if err != nil {
+		s.log.Error("Credential stuffing detector failed: " + err.Error())
+	}
+
+	for _, campaign := range campaigns {
+		detail := campaignText(campaign)
+		s.log.Warn("Credential stuffing detected: " + detail)
+
+		event := &models.AuditEvent{
+			Event:  models.AuditAttackDetected,
+			Detail: detail,
+		}
+		if err := s.audit.Record(event); err != nil {
+			s.log.Error("Failed to record " + event.Event + " audit event: " + err.Error())
+		}
+	}
+}
+
+// UnderAttack returns the scope of an attack covering ip: its network,
+// stuffing.GlobalScope, or "" if there is none.
+func (s *StuffingService) UnderAttack(ip string) string {
+	if s.detector == nil {
+		return ""
+	}
+
+	scope, err := s.detector.UnderAttack(context.Background(), ip)
+	if err != nil {
+		s.log.Error("Credential stuffing detector failed: " + err.Error())
+		return ""
+	}
+	return scope
+}
+
+// campaignText describes a campaign for logs and audit events, e.g.
+// "203.0.113.7: more than 20 failed logins within 10 minutes; challenging
+// logins from 203.0.113.0/24 until 2024-05-01T12:00:00Z".
+func campaignText(campaign stuffing.Campaign) string {
+	var source string
+	switch campaign.Trigger {
+	case stuffing.TriggerPassword:
+		source = "one password (hash prefix " + campaign.Key + ")"
+	default:
+		source = campaign.Key
+	}
+
+	return fmt.Sprintf("%s: more than %d failed logins within %s; challenging logins %s until %s",
+		source, campaign.Failures, durationText(campaign.Window),
+		attackScopeText(campaign.Scope), campaign.Until.UTC().Format(time.RFC3339))
+}
+
+// attackScopeText describes the logins an attack covers.
+func attackScopeText(scope string) string {
+	if scope == stuffing.GlobalScope {
+		return "from every network"
+	}
+	return "from " + scope
+}
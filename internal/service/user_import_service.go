@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/password"
+)
+
+// Import file formats
+const (
+	ImportFormatJSONL = "jsonl"
+	ImportFormatCSV   = "csv"
+)
+
+// maxImportLineBytes bounds one JSONL record.
+const maxImportLineBytes = 1 << 20
+
+var ErrUnknownImportFormat = errors.New("unknown import format, use jsonl or csv")
+
+// UserImportRecord is one account exported from another identity store.
+type UserImportRecord struct {
+	Line          int    `json:"-"` // Position in the input, for error reports
+	Email         string `json:"email"`
+	PasswordHash  string `json:"password_hash"`  // As exported, e.g. "pbkdf2_sha256$260000$…"
+	HashAlgorithm string `json:"hash_algorithm"` // bcrypt, argon2id, pbkdf2-sha256, scrypt or sha1
+	Role          string `json:"role"`           // Optional, defaults to user
+	EmailVerified bool   `json:"email_verified"` // Optional; unverified users must verify as after signup
+}
+
+// UserImportError explains why one record was not imported.
+type UserImportError struct {
+	Line  int
+	Email string
+	Error string
+}
+
+// UserImportResult summarises an import. Skipped records belong to emails
+// that already have an account.
+type UserImportResult struct {
+	Created int
+	Skipped int
+	Failed  int
+	Errors  []UserImportError
+}
+
+// UserImportService creates accounts migrated from other identity stores.
+// Their password hashes are stored as exported, so users keep their
+// passwords; the first successful login rehashes them with the configured
+// algorithm.
+type UserImportService struct {
+	users repository.UserRepository
+	audit repository.AuditRepository
+	log   *logger.Logger
+}
+
+func NewUserImportService(
+	users repository.UserRepository,
+	audit repository.AuditRepository,
+	log *logger.Logger,
+) *UserImportService {
+	return &UserImportService{
+		users: users,
+		audit: audit,
+		log:   log,
+	}
+}
+
+// Import creates an account for every valid record. Invalid records are
+// reported in the result and do not stop the others. Existing accounts are
+// never changed, so running the same import again is safe. actorID is the
+// admin running the import, or "" from the command line.
+func (s *UserImportService) Import(records []UserImportRecord, actorID string) (*UserImportResult, error) {
+	result := &UserImportResult{}
+
+	for _, record := range records {
+		user, err := importedUser(record)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, UserImportError{
+				Line:  record.Line,
+				Email: record.Email,
+				Error: err.Error(),
+			})
+			continue
+		}
+
+		created, err := s.users.Import(user)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", record.Line, err)
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Skipped++
+		}
+	}
+
+	s.log.Info(fmt.Sprintf("Imported users: %d created, %d skipped, %d failed",
+		result.Created, result.Skipped, result.Failed))
+
+	event := &models.AuditEvent{
+		Event:  models.AuditUsersImported,
+		Detail: fmt.Sprintf("%d created, %d skipped, %d failed", result.Created, result.Skipped, result.Failed),
+	}
+	if actorID != "" {
+		event.ActorID = &actorID
+	}
+	if err := s.audit.Record(event); err != nil {
+		s.log.Error("Failed to record " + event.Event + " audit event: " + err.Error())
+	}
+
+	return result, nil
+}
+
+// importedUser validates a record and builds the account it describes.
+func importedUser(record UserImportRecord) (*models.User, error) {
+	email := strings.TrimSpace(record.Email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, errors.New("invalid email address")
+	}
+
+	role := record.Role
+	switch role {
+	case "":
+		role = models.RoleUser
+	case models.RoleUser, models.RoleAdmin:
+	default:
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+
+	hash, err := password.Import(record.HashAlgorithm, strings.TrimSpace(record.PasswordHash))
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:    email,
+		Password: hash,
+		Role:     role,
+	}
+	if record.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	return user, nil
+}
+
+// DecodeUserImport reads import records in the given format. JSONL has one
+// JSON object per line. CSV starts with a header naming the columns:
+// email, password_hash and hash_algorithm are required, role and
+// email_verified optional. A syntax error fails the whole file.
+func DecodeUserImport(r io.Reader, format string) ([]UserImportRecord, error) {
+	switch format {
+	case ImportFormatJSONL:
+		return decodeImportJSONL(r)
+	case ImportFormatCSV:
+		return decodeImportCSV(r)
+	default:
+		return nil, ErrUnknownImportFormat
+	}
+}
+
+func decodeImportJSONL(r io.Reader) ([]UserImportRecord, error) {
+	var records []UserImportRecord
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		record := UserImportRecord{Line: line}
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func decodeImportCSV(r io.Reader) ([]UserImportRecord, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"email", "password_hash", "hash_algorithm"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header has no %q column", required)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []UserImportRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := UserImportRecord{
+			Line:          line,
+			Email:         field(row, "email"),
+			PasswordHash:  field(row, "password_hash"),
+			HashAlgorithm: field(row, "hash_algorithm"),
+			Role:          field(row, "role"),
+		}
+		if verified := field(row, "email_verified"); verified != "" {
+			if record.EmailVerified, err = strconv.ParseBool(verified); err != nil {
+				return nil, fmt.Errorf("line %d: email_verified must be true or false", line)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
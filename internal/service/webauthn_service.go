@@ -0,0 +1,418 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/models"
+	"github.com/abhay786-20/fraud-auth-service/internal/repository"
+	"github.com/abhay786-20/fraud-auth-service/pkg/logger"
+	"github.com/abhay786-20/fraud-auth-service/pkg/utils"
+)
+
+var (
+	ErrWebAuthnSession = errors.New("invalid or expired webauthn session")
+	ErrInvalidPasskey  = errors.New("passkey verification failed")
+	ErrPasskeyCloned   = errors.New("passkey has been disabled: possible cloned authenticator")
+	ErrPasskeyNotFound = errors.New("passkey not found")
+	ErrNoPasskeys      = errors.New("no passkeys registered")
+)
+
+const (
+	webauthnSessionBytes = 32
+	webauthnSessionTTL   = 5 * time.Minute
+)
+
+// WebAuthnConfig identifies this service as a WebAuthn relying party.
+type WebAuthnConfig struct {
+	RPID    string   // Registrable domain credentials are scoped to, e.g. "auth.example.com"
+	RPName  string   // Shown by the browser during ceremonies
+	Origins []string // Exact origins allowed to run ceremonies
+}
+
+// WebAuthnService implements WebAuthn registration and assertion ceremonies
+// for security keys and passkeys, used either as a second factor after the
+// password or as a passwordless primary login.
+type WebAuthnService struct {
+	webauthn *webauthn.WebAuthn
+	repo     repository.WebAuthnRepository
+	auth     *AuthService
+	log      *logger.Logger
+}
+
+func NewWebAuthnService(
+	repo repository.WebAuthnRepository,
+	auth *AuthService,
+	log *logger.Logger,
+	cfg WebAuthnConfig,
+) (*WebAuthnService, error) {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPName,
+		RPOrigins:     cfg.Origins,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnService{
+		webauthn: wa,
+		repo:     repo,
+		auth:     auth,
+		log:      log,
+	}, nil
+}
+
+// BeginRegistration starts adding a credential to the user's account. It
+// returns the options for navigator.credentials.create() and the session
+// token to send back with the result.
+func (s *WebAuthnService) BeginRegistration(user *models.User) (*protocol.CredentialCreation, string, error) {
+	wu, err := s.loadUser(user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Never register the same authenticator twice, including flagged ones
+	exclusions := make([]protocol.CredentialDescriptor, len(wu.credentials))
+	for i, c := range wu.credentials {
+		exclusions[i] = toWebAuthnCredential(&c).Descriptor()
+	}
+
+	creation, session, err := s.webauthn.BeginRegistration(
+		wu,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.saveSession(session, &user.ID, models.WebAuthnRegistration)
+	if err != nil {
+		return nil, "", err
+	}
+	return creation, token, nil
+}
+
+// FinishRegistration verifies the authenticator's attestation response and
+// stores the new credential under name.
+func (s *WebAuthnService) FinishRegistration(user *models.User, sessionToken, name string, response []byte) (*models.WebAuthnCredential, error) {
+	session, data, err := s.takeSession(sessionToken, models.WebAuthnRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID == nil || *session.UserID != user.ID {
+		return nil, ErrWebAuthnSession
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	wu, err := s.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webauthn.CreateCredential(wu, *data, parsed)
+	if err != nil {
+		s.log.Warn("WebAuthn registration rejected for user " + user.ID + ": " + err.Error())
+		return nil, ErrInvalidPasskey
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+
+	stored := &models.WebAuthnCredential{
+		UserID:          user.ID,
+		CredentialID:    credential.ID,
+		Name:            name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		Attachment:      string(credential.Authenticator.Attachment),
+		SignCount:       int64(credential.Authenticator.SignCount),
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := s.repo.CreateCredential(stored); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("WebAuthn credential registered for user " + user.ID)
+	return stored, nil
+}
+
+// ListCredentials returns the user's registered credentials.
+func (s *WebAuthnService) ListCredentials(userID string) ([]models.WebAuthnCredential, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// DeleteCredential removes one of the user's credentials.
+func (s *WebAuthnService) DeleteCredential(userID, id string) error {
+	deleted, err := s.repo.DeleteCredential(userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// HasCredentials reports whether the user has a usable credential.
+func (s *WebAuthnService) HasCredentials(userID string) (bool, error) {
+	count, err := s.repo.CountUsable(userID)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// BeginLogin starts an assertion restricted to the user's credentials, for
+// use as a second factor once the password was checked.
+func (s *WebAuthnService) BeginLogin(userID string) (*protocol.CredentialAssertion, string, error) {
+	user, err := s.auth.GetUser(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	wu, err := s.loadUser(user)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(wu.WebAuthnCredentials()) == 0 {
+		return nil, "", ErrNoPasskeys
+	}
+
+	assertion, session, err := s.webauthn.BeginLogin(wu)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.saveSession(session, &user.ID, models.WebAuthnLogin)
+	if err != nil {
+		return nil, "", err
+	}
+	return assertion, token, nil
+}
+
+// BeginPasswordlessLogin starts a discoverable-credential assertion: the
+// browser offers the user's passkeys and the user is identified from the
+// response. User verification (PIN or biometric) is required, making the
+// passkey a complete multi-factor login on its own.
+func (s *WebAuthnService) BeginPasswordlessLogin() (*protocol.CredentialAssertion, string, error) {
+	assertion, session, err := s.webauthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.saveSession(session, nil, models.WebAuthnLogin)
+	if err != nil {
+		return nil, "", err
+	}
+	return assertion, token, nil
+}
+
+// FinishLogin verifies an assertion for a session started by BeginLogin or
+// BeginPasswordlessLogin and returns the authenticated user with the
+// authentication methods to record.
+//
+// A signature counter that did not increase means two copies of the private
+// key may exist. The credential is flagged and refused from then on.
+func (s *WebAuthnService) FinishLogin(sessionToken string, response []byte) (*models.User, []string, error) {
+	session, data, err := s.takeSession(sessionToken, models.WebAuthnLogin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, nil, ErrInvalidPasskey
+	}
+
+	var (
+		wu         *webauthnUser
+		credential *webauthn.Credential
+	)
+	if session.UserID != nil {
+		user, err := s.auth.GetUser(*session.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if wu, err = s.loadUser(user); err != nil {
+			return nil, nil, err
+		}
+		credential, err = s.webauthn.ValidateLogin(wu, *data, parsed)
+	} else {
+		credential, err = s.webauthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			user, err := s.auth.GetUser(string(userHandle))
+			if err != nil {
+				return nil, err
+			}
+			wu, err = s.loadUser(user)
+			return wu, err
+		}, *data, parsed)
+	}
+	if err != nil {
+		if wu != nil && wu.hasFlagged(parsed.RawID) {
+			return nil, nil, ErrPasskeyCloned
+		}
+		s.log.Warn("WebAuthn assertion rejected: " + err.Error())
+		return nil, nil, ErrInvalidPasskey
+	}
+
+	stored := wu.find(credential.ID)
+	if stored == nil {
+		return nil, nil, ErrInvalidPasskey
+	}
+
+	if credential.Authenticator.CloneWarning {
+		s.log.Warn("WebAuthn sign counter regression for credential " + stored.ID +
+			" of user " + wu.user.ID + "; possible cloned authenticator, credential disabled")
+		if err := s.repo.FlagCloned(stored.ID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrPasskeyCloned
+	}
+
+	stored.SignCount = int64(credential.Authenticator.SignCount)
+	stored.UserVerified = credential.Flags.UserVerified
+	stored.BackupState = credential.Flags.BackupState
+	if err := s.repo.RecordUse(stored); err != nil {
+		return nil, nil, err
+	}
+
+	amr := []string{models.AMRHardwareKey}
+	if session.UserID == nil {
+		amr = append(amr, models.AMRMFA)
+	}
+	return wu.user, amr, nil
+}
+
+func (s *WebAuthnService) loadUser(user *models.User) (*webauthnUser, error) {
+	credentials, err := s.repo.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &webauthnUser{user: user, credentials: credentials}, nil
+}
+
+func (s *WebAuthnService) saveSession(data *webauthn.SessionData, userID *string, ceremony string) (string, error) {
+	raw, err := utils.GenerateRandomToken(webauthnSessionBytes)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	session := &models.WebAuthnSession{
+		TokenHash: utils.HashToken(raw),
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Data:      encoded,
+		ExpiresAt: time.Now().Add(webauthnSessionTTL),
+	}
+	if err := s.repo.CreateSession(session); err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
+
+func (s *WebAuthnService) takeSession(token, ceremony string) (*models.WebAuthnSession, *webauthn.SessionData, error) {
+	session, err := s.repo.TakeSession(utils.HashToken(token), ceremony)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrWebAuthnSession
+		}
+		return nil, nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, nil, ErrWebAuthnSession
+	}
+
+	var data webauthn.SessionData
+	if err := json.Unmarshal(session.Data, &data); err != nil {
+		return nil, nil, err
+	}
+	return session, &data, nil
+}
+
+// webauthnUser adapts a user and their stored credentials to webauthn.User.
+// The user handle is the user ID, which carries no personal data.
+type webauthnUser struct {
+	user        *models.User
+	credentials []models.WebAuthnCredential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte          { return []byte(u.user.ID) }
+func (u *webauthnUser) WebAuthnName() string        { return u.user.Email }
+func (u *webauthnUser) WebAuthnDisplayName() string { return u.user.Email }
+
+// WebAuthnCredentials returns the credentials that may be used to sign in;
+// credentials flagged as cloned are left out.
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	var credentials []webauthn.Credential
+	for i := range u.credentials {
+		if u.credentials[i].CloneDetectedAt == nil {
+			credentials = append(credentials, toWebAuthnCredential(&u.credentials[i]))
+		}
+	}
+	return credentials
+}
+
+func (u *webauthnUser) find(credentialID []byte) *models.WebAuthnCredential {
+	for i := range u.credentials {
+		if bytes.Equal(u.credentials[i].CredentialID, credentialID) {
+			return &u.credentials[i]
+		}
+	}
+	return nil
+}
+
+func (u *webauthnUser) hasFlagged(credentialID []byte) bool {
+	c := u.find(credentialID)
+	return c != nil && c.CloneDetectedAt != nil
+}
+
+func toWebAuthnCredential(c *models.WebAuthnCredential) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, len(c.Transports))
+	for i, t := range c.Transports {
+		transports[i] = protocol.AuthenticatorTransport(t)
+	}
+
+	return webauthn.Credential{
+		ID:              c.CredentialID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserPresent:    true,
+			UserVerified:   c.UserVerified,
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:     c.AAGUID,
+			SignCount:  uint32(c.SignCount),
+			Attachment: protocol.AuthenticatorAttachment(c.Attachment),
+		},
+	}
+}
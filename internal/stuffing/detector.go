@@ -0,0 +1,183 @@
+// Package stuffing detects credential stuffing and password spraying: many
+// accounts being tried at once, which per-account lockout cannot see. Failed
+// logins are counted across all accounts per source IP, per network and per
+// password, in sliding windows kept by a ratelimit.Limiter. A count crossing
+// its threshold puts the network, or for a sprayed password every network,
+// under attack for a while.
+package stuffing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"time"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/ratelimit"
+)
+
+// passwordPrefixLength is how many hex digits of a password's HMAC the
+// password counter is keyed by. Different passwords rarely share 24 bits.
+// The HMAC key keeps the prefix from being looked up in a table of common
+// passwords' hashes by whoever reads the limiter keys or the audit log.
+const passwordPrefixLength = 6
+
+// GlobalScope is the scope of an attack that is not tied to one network.
+const GlobalScope = "global"
+
+// What made a campaign stand out
+const (
+	TriggerIP       = "ip"       // Many failures from one address
+	TriggerNetwork  = "network"  // Many failures from one /24 (IPv4) or /48 (IPv6)
+	TriggerPassword = "password" // One password failing against many logins
+)
+
+// Config sets the thresholds. A threshold of 0 disables that counter.
+type Config struct {
+	Window            time.Duration // Sliding window failures are counted in
+	IPThreshold       int           // Failures per window from one IP
+	NetworkThreshold  int           // Failures per window from one network
+	PasswordThreshold int           // Failures per window with one password
+	AttackTTL         time.Duration // How long an attack lasts after its last failure over a threshold
+	PasswordKey       []byte        // HMAC key of the password counter; random if empty, so not shared with other processes
+}
+
+// Campaign describes an attack when it is detected.
+type Campaign struct {
+	Trigger  string // TriggerIP, TriggerNetwork or TriggerPassword
+	Key      string // The IP, the network or the password's HMAC prefix
+	Scope    string // Network under attack, or GlobalScope
+	Failures int    // Threshold that was exceeded
+	Window   time.Duration
+	Until    time.Time
+}
+
+// counter is one of the counts a failure adds to.
+type counter struct {
+	trigger   string
+	key       string
+	scope     string // What comes under attack when the threshold is crossed
+	threshold int
+}
+
+// Detector counts failed logins and keeps the attack state.
+type Detector struct {
+	limiter ratelimit.Limiter
+	store   Store
+	cfg     Config
+}
+
+func NewDetector(limiter ratelimit.Limiter, store Store, cfg Config) *Detector {
+	if len(cfg.PasswordKey) == 0 {
+		cfg.PasswordKey = make([]byte, sha256.Size)
+		rand.Read(cfg.PasswordKey)
+	}
+	return &Detector{
+		limiter: limiter,
+		store:   store,
+		cfg:     cfg,
+	}
+}
+
+// Observe counts a failed login from ip with password, which may be for an
+// account that does not exist. It returns the campaigns this failure
+// revealed; failures of an attack already in progress only extend it.
+func (d *Detector) Observe(ctx context.Context, ip, password string) ([]Campaign, error) {
+	var counters []counter
+	if network, ok := NetworkOf(ip); ok {
+		counters = append(counters,
+			counter{TriggerIP, ip, network, d.cfg.IPThreshold},
+			counter{TriggerNetwork, network, network, d.cfg.NetworkThreshold},
+		)
+	}
+	if password != "" {
+		counters = append(counters, counter{TriggerPassword, d.passwordPrefix(password), GlobalScope, d.cfg.PasswordThreshold})
+	}
+
+	var campaigns []Campaign
+	for _, c := range counters {
+		if c.threshold <= 0 {
+			continue
+		}
+
+		rule := ratelimit.Rule{
+			Name:      "stuffing-" + c.trigger,
+			Algorithm: ratelimit.SlidingWindow,
+			Limit:     c.threshold,
+			Window:    d.cfg.Window,
+		}
+		result, err := d.limiter.Allow(ctx, rule, c.key)
+		if err != nil {
+			return campaigns, err
+		}
+		if result.Allowed {
+			continue
+		}
+
+		until := time.Now().Add(d.cfg.AttackTTL)
+		raised, err := d.store.Raise(ctx, c.scope, until)
+		if err != nil {
+			return campaigns, err
+		}
+		if raised {
+			campaigns = append(campaigns, Campaign{
+				Trigger:  c.trigger,
+				Key:      c.key,
+				Scope:    c.scope,
+				Failures: c.threshold,
+				Window:   d.cfg.Window,
+				Until:    until,
+			})
+		}
+	}
+	return campaigns, nil
+}
+
+// UnderAttack returns the scope of an attack covering ip, its network
+// before GlobalScope, or "" if there is none.
+func (d *Detector) UnderAttack(ctx context.Context, ip string) (string, error) {
+	scopes := []string{GlobalScope}
+	if network, ok := NetworkOf(ip); ok {
+		scopes = []string{network, GlobalScope}
+	}
+
+	for _, scope := range scopes {
+		active, err := d.store.Active(ctx, scope)
+		if err != nil {
+			return "", err
+		}
+		if active {
+			return scope, nil
+		}
+	}
+	return "", nil
+}
+
+// NetworkOf returns the network an address is counted in: its /24 for
+// IPv4, its /48 for IPv6.
+func NetworkOf(ip string) (string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", false
+	}
+	addr = addr.Unmap()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return "", false
+	}
+	return prefix.String(), true
+}
+
+// passwordPrefix keys the password counter.
+func (d *Detector) passwordPrefix(password string) string {
+	mac := hmac.New(sha256.New, d.cfg.PasswordKey)
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil))[:passwordPrefixLength]
+}
@@ -0,0 +1,247 @@
+package stuffing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/abhay786-20/fraud-auth-service/internal/ratelimit"
+)
+
+var testConfig = Config{
+	Window:            time.Minute,
+	IPThreshold:       3,
+	NetworkThreshold:  5,
+	PasswordThreshold: 4,
+	AttackTTL:         time.Hour,
+}
+
+func newDetector(t *testing.T, cfg Config) *Detector {
+	t.Helper()
+	return NewDetector(ratelimit.NewMemoryLimiter(), NewMemoryStore(), cfg)
+}
+
+// fail observes failures from ip with distinct passwords, so only the IP
+// and network counters see them all, and returns the campaigns raised.
+func fail(t *testing.T, d *Detector, ip string, n int) []Campaign {
+	t.Helper()
+	var campaigns []Campaign
+	for i := 0; i < n; i++ {
+		raised, err := d.Observe(context.Background(), ip, fmt.Sprintf("%s-password-%d", ip, i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		campaigns = append(campaigns, raised...)
+	}
+	return campaigns
+}
+
+func underAttack(t *testing.T, d *Detector, ip string) string {
+	t.Helper()
+	scope, err := d.UnderAttack(context.Background(), ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scope
+}
+
+func TestIPThreshold(t *testing.T) {
+	d := newDetector(t, testConfig)
+
+	// The threshold itself is still allowed; the failure after it is not
+	if campaigns := fail(t, d, "192.0.2.1", 3); len(campaigns) != 0 {
+		t.Fatalf("got campaigns %+v at the threshold, want none", campaigns)
+	}
+	if scope := underAttack(t, d, "192.0.2.1"); scope != "" {
+		t.Fatalf("under attack at the threshold: %q", scope)
+	}
+
+	campaigns := fail(t, d, "192.0.2.1", 1)
+	if len(campaigns) != 1 {
+		t.Fatalf("got campaigns %+v, want one", campaigns)
+	}
+	c := campaigns[0]
+	if c.Trigger != TriggerIP || c.Key != "192.0.2.1" || c.Scope != "192.0.2.0/24" || c.Failures != 3 {
+		t.Errorf("got campaign %+v, want the IP trigger over 192.0.2.0/24", c)
+	}
+	if time.Until(c.Until) < 59*time.Minute {
+		t.Errorf("attack ends at %s, want in an hour", c.Until)
+	}
+
+	// The whole network is covered, other networks are not
+	for ip, want := range map[string]string{
+		"192.0.2.1":   "192.0.2.0/24",
+		"192.0.2.200": "192.0.2.0/24",
+		"192.0.3.1":   "",
+	} {
+		if scope := underAttack(t, d, ip); scope != want {
+			t.Errorf("UnderAttack(%s) = %q, want %q", ip, scope, want)
+		}
+	}
+
+	// More failures extend the attack without announcing it again
+	if campaigns := fail(t, d, "192.0.2.1", 3); len(campaigns) != 0 {
+		t.Errorf("got campaigns %+v during an attack, want none", campaigns)
+	}
+}
+
+func TestNetworkThreshold(t *testing.T) {
+	d := newDetector(t, testConfig)
+
+	// Five addresses of one network, each under the IP threshold
+	var campaigns []Campaign
+	for i := 1; i <= 6; i++ {
+		campaigns = append(campaigns, fail(t, d, fmt.Sprintf("198.51.100.%d", i), 1)...)
+	}
+	if len(campaigns) != 1 || campaigns[0].Trigger != TriggerNetwork || campaigns[0].Scope != "198.51.100.0/24" {
+		t.Fatalf("got campaigns %+v, want one network campaign", campaigns)
+	}
+	if scope := underAttack(t, d, "198.51.100.99"); scope != "198.51.100.0/24" {
+		t.Errorf("UnderAttack = %q, want the network", scope)
+	}
+}
+
+func TestIPv6CountsPer48(t *testing.T) {
+	d := newDetector(t, testConfig)
+
+	var campaigns []Campaign
+	for i := 1; i <= 6; i++ {
+		campaigns = append(campaigns, fail(t, d, fmt.Sprintf("2001:db8:1:%x::1", i), 1)...)
+	}
+	if len(campaigns) != 1 || campaigns[0].Scope != "2001:db8:1::/48" {
+		t.Fatalf("got campaigns %+v, want one over 2001:db8:1::/48", campaigns)
+	}
+	if scope := underAttack(t, d, "2001:db8:2::1"); scope != "" {
+		t.Errorf("another /48 is under attack: %q", scope)
+	}
+}
+
+func TestPasswordPrefixIsKeyed(t *testing.T) {
+	keyed := func(key string) *Detector {
+		cfg := testConfig
+		cfg.PasswordKey = []byte(key)
+		return newDetector(t, cfg)
+	}
+
+	// Replicas sharing a key count the same password together
+	a, b, other := keyed("replica key"), keyed("replica key"), keyed("another key")
+	if a.passwordPrefix("Summer2024!") != b.passwordPrefix("Summer2024!") {
+		t.Error("the same key gave different prefixes")
+	}
+	if a.passwordPrefix("Summer2024!") == other.passwordPrefix("Summer2024!") {
+		t.Error("different keys gave the same prefix")
+	}
+}
+
+func TestPasswordThresholdIsGlobal(t *testing.T) {
+	d := newDetector(t, testConfig)
+	ctx := context.Background()
+
+	// One password sprayed from addresses in different networks
+	var campaigns []Campaign
+	for i := 1; i <= 5; i++ {
+		raised, err := d.Observe(ctx, fmt.Sprintf("203.0.%d.1", i), "Summer2024!")
+		if err != nil {
+			t.Fatal(err)
+		}
+		campaigns = append(campaigns, raised...)
+	}
+	if len(campaigns) != 1 || campaigns[0].Trigger != TriggerPassword || campaigns[0].Scope != GlobalScope {
+		t.Fatalf("got campaigns %+v, want one global password campaign", campaigns)
+	}
+	if campaigns[0].Key == "Summer2024!" || len(campaigns[0].Key) != passwordPrefixLength {
+		t.Errorf("campaign key %q, want a short hash prefix", campaigns[0].Key)
+	}
+	unkeyed := sha256.Sum256([]byte("Summer2024!"))
+	if campaigns[0].Key == hex.EncodeToString(unkeyed[:])[:passwordPrefixLength] {
+		t.Errorf("campaign key %q is the unkeyed SHA-256 prefix", campaigns[0].Key)
+	}
+
+	// Every client is covered, its own network first
+	if scope := underAttack(t, d, "192.0.2.1"); scope != GlobalScope {
+		t.Errorf("UnderAttack = %q, want %q", scope, GlobalScope)
+	}
+	fail(t, d, "192.0.2.1", 4)
+	if scope := underAttack(t, d, "192.0.2.1"); scope != "192.0.2.0/24" {
+		t.Errorf("UnderAttack = %q, want the network before the global scope", scope)
+	}
+}
+
+func TestDisabledThresholds(t *testing.T) {
+	d := newDetector(t, Config{Window: time.Minute, AttackTTL: time.Hour})
+	if campaigns := fail(t, d, "192.0.2.1", 50); len(campaigns) != 0 {
+		t.Errorf("got campaigns %+v with every threshold off, want none", campaigns)
+	}
+}
+
+func TestUnparseableIPOnlyCountsThePassword(t *testing.T) {
+	cfg := testConfig
+	cfg.PasswordThreshold = 1
+	d := newDetector(t, cfg)
+
+	for i := 0; i < 2; i++ {
+		if _, err := d.Observe(context.Background(), "not an ip", "hunter2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if scope := underAttack(t, d, "not an ip"); scope != GlobalScope {
+		t.Errorf("UnderAttack = %q, want %q", scope, GlobalScope)
+	}
+}
+
+func TestAttacksEnd(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	d := NewDetector(ratelimit.NewMemoryLimiter(), store, testConfig)
+
+	fail(t, d, "192.0.2.1", 4)
+	if scope := underAttack(t, d, "192.0.2.1"); scope == "" {
+		t.Fatal("no attack after the threshold")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if scope := underAttack(t, d, "192.0.2.1"); scope != "" {
+		t.Errorf("attack still on after its TTL: %q", scope)
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	until := time.Now().Add(time.Hour)
+	for i, want := range []bool{true, false} {
+		raised, err := store.Raise(ctx, "192.0.2.0/24", until)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raised != want {
+			t.Errorf("Raise %d = %v, want %v", i, raised, want)
+		}
+	}
+
+	for scope, want := range map[string]bool{"192.0.2.0/24": true, GlobalScope: false} {
+		active, err := store.Active(ctx, scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active != want {
+			t.Errorf("Active(%q) = %v, want %v", scope, active, want)
+		}
+	}
+
+	server.FastForward(2 * time.Hour)
+	if active, _ := store.Active(ctx, "192.0.2.0/24"); active {
+		t.Error("attack still active after it expired")
+	}
+}
@@ -0,0 +1,86 @@
+package stuffing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces the attack state kept in Redis.
+const keyPrefix = "stuffing:attack:"
+
+// Store keeps which scopes are under attack. Every replica must see the
+// same state, so use RedisStore when running more than one.
+type Store interface {
+	// Raise puts scope under attack until then. It returns true if scope
+	// was not under attack before, false if an attack was only extended.
+	Raise(ctx context.Context, scope string, until time.Time) (bool, error)
+
+	// Active reports whether scope is under attack.
+	Active(ctx context.Context, scope string) (bool, error)
+}
+
+// MemoryStore keeps the attack state in process memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	attacks map[string]time.Time // Scope to end of attack
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		attacks: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Raise(_ context.Context, scope string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, end := range s.attacks {
+		if !now.Before(end) {
+			delete(s.attacks, key)
+		}
+	}
+
+	_, ongoing := s.attacks[scope]
+	if until.After(s.attacks[scope]) {
+		s.attacks[scope] = until
+	}
+	return !ongoing, nil
+}
+
+func (s *MemoryStore) Active(_ context.Context, scope string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.now().Before(s.attacks[scope]), nil
+}
+
+// RedisStore keeps the attack state in a Redis-compatible server, as keys
+// that expire when the attack does.
+type RedisStore struct {
+	client redis.Cmdable
+}
+
+func NewRedisStore(client redis.Cmdable) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Raise(ctx context.Context, scope string, until time.Time) (bool, error) {
+	key := keyPrefix + scope
+	raised, err := s.client.SetNX(ctx, key, until.UTC().Format(time.RFC3339), time.Until(until)).Result()
+	if err != nil || raised {
+		return raised, err
+	}
+	return false, s.client.Set(ctx, key, until.UTC().Format(time.RFC3339), time.Until(until)).Err()
+}
+
+func (s *RedisStore) Active(ctx context.Context, scope string) (bool, error) {
+	n, err := s.client.Exists(ctx, keyPrefix+scope).Result()
+	return n > 0, err
+}
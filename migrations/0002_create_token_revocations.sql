@@ -0,0 +1,17 @@
+-- Individually revoked access tokens, keyed by their jti claim. Rows can be
+-- deleted once expires_at has passed because the token is dead anyway.
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti         TEXT PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    revoked_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+-- Per-user cutoff: any access token issued before valid_after is rejected.
+-- Written by "logout everywhere" and credential changes.
+CREATE TABLE IF NOT EXISTS user_token_cutoffs (
+    user_id     UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    valid_after TIMESTAMPTZ NOT NULL
+);
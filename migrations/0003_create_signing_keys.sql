@@ -0,0 +1,21 @@
+-- Metadata for managed token signing keys. The private key material lives in
+-- AUTH_JWT_KEYS_DIR as <kid>.pem, or sealed in private_key (see 0016); this
+-- table records each key's lifecycle:
+--   created_at    key generated
+--   activated_at  key became the signer for new tokens
+--   retired_at    key stopped signing
+--   verify_until  key stops verifying (retired_at + overlap, or immediately
+--                 when rotated out after a suspected compromise)
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid           TEXT PRIMARY KEY,
+    algorithm     TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    activated_at  TIMESTAMPTZ,
+    retired_at    TIMESTAMPTZ,
+    verify_until  TIMESTAMPTZ
+);
+
+-- At most one key signs at any time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signing_keys_single_active
+    ON signing_keys ((TRUE))
+    WHERE activated_at IS NOT NULL AND retired_at IS NULL;
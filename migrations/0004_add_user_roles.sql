@@ -0,0 +1,3 @@
+-- Coarse-grained roles carried in the access token "role" claim.
+-- Promote an operator with: UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
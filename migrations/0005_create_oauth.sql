@@ -0,0 +1,33 @@
+-- Registered OAuth clients. Redirect URIs are matched exactly.
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id             TEXT PRIMARY KEY,
+    name           TEXT NOT NULL,
+    client_type    TEXT NOT NULL DEFAULT 'public',
+    redirect_uris  TEXT[] NOT NULL DEFAULT '{}',
+    scopes         TEXT[] NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Authorization codes are single use and short lived. Only a hash of the
+-- code is stored. family_id links to the refresh token family issued for the
+-- code, so a replayed code can revoke the tokens it produced.
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    code_hash       TEXT PRIMARY KEY,
+    client_id       TEXT NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+    user_id         UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    redirect_uri    TEXT NOT NULL,
+    scope           TEXT NOT NULL DEFAULT '',
+    nonce           TEXT NOT NULL DEFAULT '',
+    code_challenge  TEXT NOT NULL,
+    family_id       UUID,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    used_at         TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_expires_at ON oauth_authorization_codes (expires_at);
+
+-- Refresh tokens remember the client and scope they were granted for
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS client_id TEXT REFERENCES oauth_clients (id) ON DELETE CASCADE;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS scope TEXT NOT NULL DEFAULT '';
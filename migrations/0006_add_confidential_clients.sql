@@ -0,0 +1,8 @@
+-- Confidential clients (machine identities such as the transaction scorer)
+-- authenticate with a secret. Only its SHA-256 hash is stored; secrets are
+-- high-entropy random values, so a slow password hash is unnecessary.
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS secret_hash TEXT;
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS secret_rotated_at TIMESTAMPTZ;
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS grant_types TEXT[] NOT NULL
+    DEFAULT '{authorization_code,refresh_token}';
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
@@ -0,0 +1,42 @@
+-- TOTP second factor. The shared secret is encrypted with AES-256-GCM
+-- (AUTH_MFA_ENCRYPTION_KEY) since it must be recoverable to verify codes.
+-- enabled_at stays NULL until the user proves enrollment with a valid code.
+-- last_used_step blocks replaying a code inside its 30 second window.
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id          UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret           BYTEA NOT NULL,
+    enabled_at       TIMESTAMPTZ,
+    last_used_step   BIGINT NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One-time recovery codes, stored as SHA-256 hashes.
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash   TEXT NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_hash ON mfa_recovery_codes (user_id, code_hash);
+
+-- Pending second-factor challenges. The password step hands out an opaque
+-- mfa_token (stored hashed); it is single use, short lived and allows a
+-- limited number of wrong codes.
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash   TEXT NOT NULL UNIQUE,
+    attempts     INT NOT NULL DEFAULT 0,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    consumed_at  TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires_at ON mfa_challenges (expires_at);
+
+-- Authentication methods (RFC 8176 "amr") carried from login into every
+-- token of the grant, so refreshed tokens keep reporting how the user signed in
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{}';
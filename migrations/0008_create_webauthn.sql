@@ -0,0 +1,42 @@
+-- WebAuthn credentials (security keys and passkeys). The user handle given to
+-- authenticators is the user's UUID, so discoverable (passwordless) logins
+-- resolve straight back to users.id.
+-- clone_detected_at is set when an authenticator reports a signature counter
+-- that did not increase, which suggests the key was cloned; such credentials
+-- are refused until the user removes them.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id            UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    credential_id      BYTEA NOT NULL UNIQUE,
+    name               TEXT NOT NULL DEFAULT '',
+    public_key         BYTEA NOT NULL,
+    attestation_type   TEXT NOT NULL DEFAULT '',
+    transports         TEXT[] NOT NULL DEFAULT '{}',
+    aaguid             BYTEA,
+    attachment         TEXT NOT NULL DEFAULT '',
+    sign_count         BIGINT NOT NULL DEFAULT 0,
+    user_verified      BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_eligible    BOOLEAN NOT NULL DEFAULT FALSE,
+    backup_state       BOOLEAN NOT NULL DEFAULT FALSE,
+    clone_detected_at  TIMESTAMPTZ,
+    last_used_at       TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials (user_id);
+
+-- In-flight registration and login ceremonies. The client holds an opaque
+-- session token (stored hashed); data is the library's session state,
+-- including the challenge. user_id is NULL for passwordless logins, where the
+-- user is only known once the authenticator answers.
+CREATE TABLE IF NOT EXISTS webauthn_sessions (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token_hash   TEXT NOT NULL UNIQUE,
+    user_id      UUID REFERENCES users (id) ON DELETE CASCADE,
+    ceremony     TEXT NOT NULL,
+    data         JSONB NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_sessions_expires_at ON webauthn_sessions (expires_at);
@@ -0,0 +1,20 @@
+-- Email verification. email_verified_at stays NULL until the user follows the
+-- link mailed at signup; accounts that existed before this migration are
+-- grandfathered in as verified.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+-- Verification links are signed JWTs; this table makes each one single use.
+-- id is the token's jti. Sending a new link marks the previous ones used, and
+-- rows created in the last day drive the resend throttle.
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id          TEXT PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    email       TEXT NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user ON email_verification_tokens (user_id, created_at);
@@ -0,0 +1,25 @@
+-- Consecutive failed logins per account. A row exists only while the user
+-- has failures outstanding; a successful login or an admin unlock deletes it.
+-- locked_until is set once failed_count reaches AUTH_LOCKOUT_THRESHOLD and
+-- the account unlocks by itself when it passes.
+CREATE TABLE IF NOT EXISTS login_failures (
+    user_id         UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    failed_count    INTEGER NOT NULL DEFAULT 0,
+    last_failed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until    TIMESTAMPTZ
+);
+
+-- Security-relevant events (lockouts, admin actions) for investigation.
+-- user_id is the account concerned, actor_id whoever triggered the event
+-- when that is not the user (e.g. an admin).
+CREATE TABLE IF NOT EXISTS audit_events (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event       TEXT NOT NULL,
+    user_id     UUID REFERENCES users (id) ON DELETE SET NULL,
+    actor_id    TEXT,
+    detail      TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_user ON audit_events (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_event ON audit_events (event, created_at);
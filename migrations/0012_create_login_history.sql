@@ -0,0 +1,24 @@
+-- Successful interactive logins, newest first per user. The risk engine
+-- compares each new login against them to spot unknown devices, networks
+-- and times of day. risk_score is NULL for logins that were not scored,
+-- such as passkey logins.
+CREATE TABLE IF NOT EXISTS login_history (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ip          TEXT NOT NULL,
+    user_agent  TEXT NOT NULL DEFAULT '',
+    risk_score  INTEGER,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_history_user ON login_history (user_id, created_at DESC);
+
+-- Login risk carried from the password step into the tokens of the grant:
+-- through a pending MFA challenge or authorization code, and on to every
+-- refresh, like amr. NULL when the login was not scored.
+ALTER TABLE mfa_challenges ADD COLUMN IF NOT EXISTS risk_score INTEGER;
+ALTER TABLE mfa_challenges ADD COLUMN IF NOT EXISTS risk_reasons TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS risk_score INTEGER;
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS risk_reasons TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS risk_score INTEGER;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS risk_reasons TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,9 @@
+-- Where each login came from, looked up in the offline GeoIP database
+-- (AUTH_GEOIP_DB). Coordinates are NULL when no database is configured or
+-- the address is not in it, e.g. private ranges. accuracy_km is the radius
+-- around the coordinates the database vouches for.
+ALTER TABLE login_history ADD COLUMN IF NOT EXISTS country TEXT NOT NULL DEFAULT '';
+ALTER TABLE login_history ADD COLUMN IF NOT EXISTS city TEXT NOT NULL DEFAULT '';
+ALTER TABLE login_history ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE login_history ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE login_history ADD COLUMN IF NOT EXISTS accuracy_km INTEGER NOT NULL DEFAULT 0;
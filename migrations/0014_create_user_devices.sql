@@ -0,0 +1,17 @@
+-- Devices each user has completed a login from. device_hash is a SHA-256 of
+-- the client's fingerprint, or of features derived from its User-Agent and
+-- Accept-Language when it sent none. trusted_until is set when the user
+-- asks to trust the device after a second factor; until then logins from it
+-- skip MFA unless the risk engine challenges them.
+CREATE TABLE IF NOT EXISTS user_devices (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id        UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    device_hash    TEXT NOT NULL,
+    name           TEXT NOT NULL,
+    user_agent     TEXT NOT NULL DEFAULT '',
+    last_ip        TEXT NOT NULL DEFAULT '',
+    first_seen_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    trusted_until  TIMESTAMPTZ,
+    UNIQUE (user_id, device_hash)
+);
@@ -0,0 +1,19 @@
+-- Fraud rules loaded when AUTH_RULES_SOURCE=postgres. expression is a CEL
+-- expression over the rule context (see README) that must evaluate to a
+-- bool. Rules run in priority order, lowest first. Edits are picked up
+-- within AUTH_RULES_RELOAD_SEC seconds without a restart; a rule that does
+-- not compile keeps the previous set of rules in place.
+CREATE TABLE IF NOT EXISTS fraud_rules (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name         TEXT NOT NULL UNIQUE,
+    description  TEXT NOT NULL DEFAULT '',
+    event        TEXT NOT NULL CHECK (event IN ('login', 'signup')),
+    expression   TEXT NOT NULL,
+    action       TEXT NOT NULL CHECK (action IN ('allow', 'challenge', 'deny', 'tag')),
+    tags         TEXT[] NOT NULL DEFAULT '{}',
+    shadow       BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled      BOOLEAN NOT NULL DEFAULT TRUE,
+    priority     INTEGER NOT NULL DEFAULT 100,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Private key material of managed signing keys, sealed with AES-256-GCM under
+-- AUTH_JWT_KEYS_ENCRYPTION_KEY, so every replica can load every key without a
+-- shared AUTH_JWT_KEYS_DIR volume. NULL for keys kept as <kid>.pem files.
+ALTER TABLE signing_keys ADD COLUMN IF NOT EXISTS private_key BYTEA;
@@ -0,0 +1,11 @@
+-- When the login that started a refresh token family happened. Every token
+-- in the family carries it forward, so ID tokens minted on refresh report
+-- the original authentication as auth_time instead of the refresh. Existing
+-- families fall back to their oldest remaining token.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMPTZ;
+
+UPDATE refresh_tokens t
+SET auth_time = (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id)
+WHERE auth_time IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN auth_time SET NOT NULL;
@@ -0,0 +1,10 @@
+-- Trust in a device is bound to a random token the service issues when the
+-- user asks to trust it, kept by the browser in an HttpOnly cookie. Only a
+-- SHA-256 of the token is stored. Trust granted before this was keyed on
+-- device_hash alone, which a client can reproduce from headers, so it is
+-- withdrawn.
+ALTER TABLE user_devices ADD COLUMN IF NOT EXISTS trust_token_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_devices_trust_token_hash_idx ON user_devices (trust_token_hash);
+
+UPDATE user_devices SET trusted_until = NULL WHERE trust_token_hash IS NULL;
@@ -8,6 +8,61 @@ const (
 	EnvServerHost = "AUTH_SERVER_HOST" // Server bind address (default: "0.0.0.0")
 	EnvServerPort = "AUTH_SERVER_PORT" // Server port (default: "8081")
 	EnvGinMode    = "GIN_MODE"         // Gin mode: debug, release, test (default: "debug")
+
+	EnvGRPCExtAuthzAddr = "AUTH_GRPC_EXT_AUTHZ_ADDR" // Envoy ext_authz gRPC listen address, e.g. ":9001" (default: "" - disabled)
+	EnvTrustedProxies   = "AUTH_TRUSTED_PROXIES"     // Comma-separated proxy IPs/CIDRs whose X-Forwarded-For is trusted (default: "" - none)
+)
+
+// Redis configuration environment variables
+const (
+	EnvRedisAddr     = "AUTH_REDIS_ADDR"     // Redis (or compatible) host:port (default: "localhost:6379")
+	EnvRedisPassword = "AUTH_REDIS_PASSWORD" // Redis password (default: "")
+	EnvRedisDB       = "AUTH_REDIS_DB"       // Redis database number (default: 0)
+)
+
+// Rate limiting environment variables
+const (
+	EnvRateLimitBackend     = "AUTH_RATE_LIMIT_BACKEND"       // Where counters live: memory, redis, off (default: "memory")
+	EnvRateLimitAlgorithm   = "AUTH_RATE_LIMIT_ALGORITHM"     // token_bucket or sliding_window (default: "sliding_window")
+	EnvRateLimitIPPerMin    = "AUTH_RATE_LIMIT_IP_PER_MIN"    // Requests per minute per IP on public auth endpoints (default: 30)
+	EnvRateLimitEmailPerMin = "AUTH_RATE_LIMIT_EMAIL_PER_MIN" // Requests per minute per submitted email (default: 10)
+	EnvRateLimitUserPerMin  = "AUTH_RATE_LIMIT_USER_PER_MIN"  // Requests per minute per user on authenticated endpoints (default: 120)
+)
+
+// Login risk scoring environment variables
+const (
+	EnvRiskEnabled           = "AUTH_RISK_ENABLED"            // Score password logins for takeover risk (default: true)
+	EnvRiskChallengeScore    = "AUTH_RISK_CHALLENGE_SCORE"    // Score from which a second factor is required, 0 disables (default: 50)
+	EnvRiskDenyScore         = "AUTH_RISK_DENY_SCORE"         // Score from which the login is refused, 0 disables (default: 90)
+	EnvRiskChallengeFallback = "AUTH_RISK_CHALLENGE_FALLBACK" // Challenged users without a second factor: allow, deny (default: "allow")
+	EnvGeoIPDB               = "AUTH_GEOIP_DB"                // MaxMind-format .mmdb city database for impossible-travel checks (default: "" - disabled)
+)
+
+// Fraud rules environment variables
+const (
+	EnvRulesSource    = "AUTH_RULES_SOURCE"     // Where fraud rules come from: file, postgres (default: "" - no rules)
+	EnvRulesFile      = "AUTH_RULES_FILE"       // YAML rules file for AUTH_RULES_SOURCE=file (default: "./rules.yaml")
+	EnvRulesReloadSec = "AUTH_RULES_RELOAD_SEC" // How often changed rules are reloaded, 0 disables (default: 10)
+	EnvRulesShadow    = "AUTH_RULES_SHADOW"     // Only log what every rule would have done (default: false)
+)
+
+// Credential stuffing detection environment variables
+const (
+	EnvStuffingEnabled           = "AUTH_STUFFING_ENABLED"            // Detect failed logins spread across many accounts (default: true)
+	EnvStuffingWindowMin         = "AUTH_STUFFING_WINDOW_MIN"         // Sliding window failed logins are counted in, in minutes (default: 10)
+	EnvStuffingIPThreshold       = "AUTH_STUFFING_IP_THRESHOLD"       // Failed logins per window from one IP, 0 disables (default: 20)
+	EnvStuffingNetworkThreshold  = "AUTH_STUFFING_NETWORK_THRESHOLD"  // Failed logins per window from one /24 or /48, 0 disables (default: 50)
+	EnvStuffingPasswordThreshold = "AUTH_STUFFING_PASSWORD_THRESHOLD" // Failed logins per window with one password, 0 disables (default: 25)
+	EnvStuffingAttackMin         = "AUTH_STUFFING_ATTACK_MIN"         // How long an attack lasts after its last failure over a threshold, in minutes (default: 60)
+	EnvStuffingIPPerMin          = "AUTH_STUFFING_IP_PER_MIN"         // Login requests per minute per IP during an attack, 0 disables (default: 5)
+	EnvStuffingEmailPerMin       = "AUTH_STUFFING_EMAIL_PER_MIN"      // Login requests per minute per email during an attack, 0 disables (default: 3)
+	EnvStuffingPasswordKey       = "AUTH_STUFFING_PASSWORD_KEY"       // Base64 32-byte HMAC key of the password counter, shared by every replica (default: "" - random per process)
+)
+
+// Device registry environment variables
+const (
+	EnvDeviceTrustDays = "AUTH_DEVICE_TRUST_DAYS" // Days a trusted device skips the second factor, 0 disables trusting (default: 30)
+	EnvNewDeviceEmail  = "AUTH_NEW_DEVICE_EMAIL"  // Email users about logins from new devices (default: true)
 )
 
 // Database configuration environment variables
@@ -24,8 +79,67 @@ const (
 
 // Authentication configuration environment variables
 const (
-	EnvJWTSecret  = "AUTH_JWT_SECRET"    // JWT signing secret (REQUIRED)
-	EnvJWTTTLHours = "AUTH_JWT_TTL_HOURS" // JWT token TTL in hours (default: 24)
+	EnvJWTSecret            = "AUTH_JWT_SECRET"              // JWT signing secret (REQUIRED for HS256)
+	EnvJWTAlgorithm         = "AUTH_JWT_ALGORITHM"           // Signing algorithm: HS256, RS256, ES256, EdDSA (default: "HS256")
+	EnvJWTPrivateKeyPath    = "AUTH_JWT_PRIVATE_KEY_PATH"    // PEM private key file (REQUIRED for RS256/ES256/EdDSA)
+	EnvJWTKeyID             = "AUTH_JWT_KEY_ID"              // "kid" header (default: RFC 7638 key thumbprint)
+	EnvJWTKeysDir           = "AUTH_JWT_KEYS_DIR"            // Directory of managed, rotating keys (default: "" - rotation disabled)
+	EnvJWTKeysEncryptionKey = "AUTH_JWT_KEYS_ENCRYPTION_KEY" // Base64 AES-256 key sealing managed keys stored in Postgres (default: "" - use AUTH_JWT_KEYS_DIR)
+	EnvJWTKeyRotationDays   = "AUTH_JWT_KEY_ROTATION_DAYS"   // Scheduled rotation period in days, 0 disables (default: 30)
+	EnvJWTKeyOverlapMin     = "AUTH_JWT_KEY_OVERLAP_MIN"     // Minutes a retired key keeps verifying tokens (default: 60)
+	EnvIssuer               = "AUTH_ISSUER"                  // Public base URL, used as "iss" and OIDC issuer (default: "http://localhost:8081")
+	EnvAudience             = "AUTH_AUDIENCE"                // "aud" claim of access tokens (default: "fraud-platform")
+	EnvJWTAccessTTLMin      = "AUTH_JWT_ACCESS_TTL_MIN"      // Access token TTL in minutes (default: 15)
+	EnvRefreshTokenTTLHours = "AUTH_REFRESH_TOKEN_TTL_HOURS" // Refresh token TTL in hours (default: 720)
+	EnvOAuthCodeTTLSec      = "AUTH_OAUTH_CODE_TTL_SEC"      // Authorization code lifetime in seconds (default: 60)
+	EnvRevocationStore      = "AUTH_REVOCATION_STORE"        // Token revocation backend: postgres, memory (default: "postgres")
+	EnvLockoutThreshold     = "AUTH_LOCKOUT_THRESHOLD"       // Consecutive failed logins that lock an account, 0 disables (default: 10)
+	EnvLockoutDurationMin   = "AUTH_LOCKOUT_DURATION_MIN"    // Minutes an account stays locked (default: 15)
+)
+
+// Password policy environment variables
+const (
+	EnvPasswordMinLength    = "AUTH_PASSWORD_MIN_LENGTH"    // Minimum password length in characters (default: 8)
+	EnvPasswordMaxLength    = "AUTH_PASSWORD_MAX_LENGTH"    // Maximum password length in bytes, at most 72 with bcrypt (default: 72)
+	EnvPasswordMinClasses   = "AUTH_PASSWORD_MIN_CLASSES"   // Character classes (lower, upper, digit, symbol) a password must mix (default: 0)
+	EnvPasswordMinStrength  = "AUTH_PASSWORD_MIN_STRENGTH"  // Minimum strength score from 0 to 4 (default: 2)
+	EnvPasswordBreachedFile = "AUTH_PASSWORD_BREACHED_FILE" // File of breached password SHA-1 hashes (default: "" - check disabled)
+
+	EnvPasswordHash    = "AUTH_PASSWORD_HASH"     // Algorithm for new password hashes: argon2id, bcrypt (default: "argon2id")
+	EnvBcryptCost      = "AUTH_BCRYPT_COST"       // bcrypt cost factor (default: 10)
+	EnvArgon2MemoryKiB = "AUTH_ARGON2_MEMORY_KIB" // argon2id memory in KiB (default: 19456)
+	EnvArgon2Time      = "AUTH_ARGON2_TIME"       // argon2id passes over memory (default: 2)
+	EnvArgon2Threads   = "AUTH_ARGON2_THREADS"    // argon2id parallelism (default: 1)
+)
+
+// Multi-factor authentication environment variables
+const (
+	EnvMFAEncryptionKey   = "AUTH_MFA_ENCRYPTION_KEY"    // Base64 AES-256 key encrypting TOTP secrets (default: "" - enrollment disabled)
+	EnvMFAIssuer          = "AUTH_MFA_ISSUER"            // Account label shown in authenticator apps (default: "Fraud Auth Service")
+	EnvMFAChallengeTTLSec = "AUTH_MFA_CHALLENGE_TTL_SEC" // Lifetime of the mfa_token between login steps in seconds (default: 300)
+
+	EnvWebAuthnRPID    = "AUTH_WEBAUTHN_RP_ID"   // Relying party ID, a registrable domain (default: host of AUTH_ISSUER)
+	EnvWebAuthnRPName  = "AUTH_WEBAUTHN_RP_NAME" // Relying party name shown by browsers (default: "Fraud Auth Service")
+	EnvWebAuthnOrigins = "AUTH_WEBAUTHN_ORIGINS" // Comma-separated origins allowed to run ceremonies (default: AUTH_ISSUER)
+)
+
+// Email verification, password reset and outgoing mail environment variables
+const (
+	EnvUnverifiedLogin            = "AUTH_UNVERIFIED_LOGIN"               // Unverified users at login: allow, restrict, deny (default: "restrict")
+	EnvEmailVerificationURL       = "AUTH_EMAIL_VERIFICATION_URL"         // Page the emailed link opens, token appended as ?token= (default: AUTH_ISSUER + "/verify-email")
+	EnvEmailVerificationTTLHours  = "AUTH_EMAIL_VERIFICATION_TTL_HOURS"   // Lifetime of a verification link in hours (default: 24)
+	EnvEmailVerificationResendSec = "AUTH_EMAIL_VERIFICATION_RESEND_SEC"  // Minimum seconds between two verification emails (default: 60)
+	EnvEmailVerificationMaxPerDay = "AUTH_EMAIL_VERIFICATION_MAX_PER_DAY" // Verification emails per user per day (default: 5)
+	EnvPasswordResetURL           = "AUTH_PASSWORD_RESET_URL"             // Page the reset link opens, token appended as ?token= (default: AUTH_ISSUER + "/reset-password")
+	EnvPasswordResetTTLMin        = "AUTH_PASSWORD_RESET_TTL_MIN"         // Lifetime of a password reset link in minutes (default: 30)
+
+	EnvMailDriver   = "AUTH_MAIL_DRIVER"   // Mail transport: smtp, file, stdout (default: "stdout")
+	EnvMailFrom     = "AUTH_MAIL_FROM"     // Sender address (default: "Fraud Auth Service <no-reply@localhost>")
+	EnvMailFile     = "AUTH_MAIL_FILE"     // File the "file" driver appends to (default: "./mail.log")
+	EnvSMTPHost     = "AUTH_SMTP_HOST"     // SMTP relay host (REQUIRED for the smtp driver)
+	EnvSMTPPort     = "AUTH_SMTP_PORT"     // SMTP relay port; 465 uses implicit TLS (default: 587)
+	EnvSMTPUsername = "AUTH_SMTP_USERNAME" // SMTP username (default: "" - no authentication)
+	EnvSMTPPassword = "AUTH_SMTP_PASSWORD" // SMTP password
 )
 
 // RequiredEnvVars contains all environment variables that MUST be set.
@@ -34,7 +148,6 @@ var RequiredEnvVars = []string{
 	EnvDBUser,
 	EnvDBPassword,
 	EnvDBName,
-	EnvJWTSecret,
 }
 
 // OptionalEnvVars contains environment variables with default values.
@@ -42,10 +155,85 @@ var RequiredEnvVars = []string{
 var OptionalEnvVars = []string{
 	EnvServerHost,
 	EnvServerPort,
+	EnvGRPCExtAuthzAddr,
+	EnvTrustedProxies,
 	EnvDBHost,
 	EnvDBPort,
 	EnvDBMaxOpenConns,
 	EnvDBMaxIdleConns,
 	EnvDBMaxLifetimeMin,
-	EnvJWTTTLHours,
+	EnvJWTSecret,
+	EnvJWTAlgorithm,
+	EnvJWTPrivateKeyPath,
+	EnvJWTKeyID,
+	EnvJWTKeysDir,
+	EnvJWTKeysEncryptionKey,
+	EnvJWTKeyRotationDays,
+	EnvJWTKeyOverlapMin,
+	EnvIssuer,
+	EnvAudience,
+	EnvJWTAccessTTLMin,
+	EnvRefreshTokenTTLHours,
+	EnvOAuthCodeTTLSec,
+	EnvRevocationStore,
+	EnvLockoutThreshold,
+	EnvLockoutDurationMin,
+	EnvPasswordMinLength,
+	EnvPasswordMaxLength,
+	EnvPasswordMinClasses,
+	EnvPasswordMinStrength,
+	EnvPasswordBreachedFile,
+	EnvPasswordHash,
+	EnvBcryptCost,
+	EnvArgon2MemoryKiB,
+	EnvArgon2Time,
+	EnvArgon2Threads,
+	EnvMFAEncryptionKey,
+	EnvMFAIssuer,
+	EnvMFAChallengeTTLSec,
+	EnvWebAuthnRPID,
+	EnvWebAuthnRPName,
+	EnvWebAuthnOrigins,
+	EnvUnverifiedLogin,
+	EnvEmailVerificationURL,
+	EnvEmailVerificationTTLHours,
+	EnvEmailVerificationResendSec,
+	EnvEmailVerificationMaxPerDay,
+	EnvPasswordResetURL,
+	EnvPasswordResetTTLMin,
+	EnvMailDriver,
+	EnvMailFrom,
+	EnvMailFile,
+	EnvSMTPHost,
+	EnvSMTPPort,
+	EnvSMTPUsername,
+	EnvSMTPPassword,
+	EnvRedisAddr,
+	EnvRedisPassword,
+	EnvRedisDB,
+	EnvRateLimitBackend,
+	EnvRateLimitAlgorithm,
+	EnvRateLimitIPPerMin,
+	EnvRateLimitEmailPerMin,
+	EnvRateLimitUserPerMin,
+	EnvRiskEnabled,
+	EnvRiskChallengeScore,
+	EnvRiskDenyScore,
+	EnvRiskChallengeFallback,
+	EnvGeoIPDB,
+	EnvDeviceTrustDays,
+	EnvNewDeviceEmail,
+	EnvStuffingEnabled,
+	EnvStuffingWindowMin,
+	EnvStuffingIPThreshold,
+	EnvStuffingNetworkThreshold,
+	EnvStuffingPasswordThreshold,
+	EnvStuffingAttackMin,
+	EnvStuffingIPPerMin,
+	EnvStuffingEmailPerMin,
+	EnvStuffingPasswordKey,
+	EnvRulesSource,
+	EnvRulesFile,
+	EnvRulesReloadSec,
+	EnvRulesShadow,
 }
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterMailer writes every message to an io.Writer instead of sending it.
+// Meant for local development and tests: follow verification links straight
+// from the terminal or the mail file.
+type WriterMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+// NewWriterMailer writes messages to w.
+func NewWriterMailer(w io.Writer, from string) *WriterMailer {
+	return &WriterMailer{w: w, from: from}
+}
+
+// NewStdoutMailer prints messages to standard output.
+func NewStdoutMailer(from string) *WriterMailer {
+	return NewWriterMailer(os.Stdout, from)
+}
+
+// NewFileMailer appends messages to the file at path, creating it if needed.
+func NewFileMailer(path, from string) (*WriterMailer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterMailer(f, from), nil
+}
+
+func (m *WriterMailer) Send(msg Message) error {
+	data, err := format(m.from, msg, true)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.w.Write(data); err != nil {
+		return err
+	}
+	_, err = io.WriteString(m.w, "\r\n----------------------------------------\r\n")
+	return err
+}
@@ -0,0 +1,138 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2SaltBytes = 16
+	argon2KeyBytes  = 32
+)
+
+// Bounds on stored and imported parameters, so a bad record cannot make a
+// login burn minutes of CPU or gigabytes of memory
+const (
+	maxArgon2Memory   = 1 << 20 // KiB: 1 GiB
+	maxArgon2Time     = 16
+	maxArgon2KeyBytes = 64
+)
+
+// Argon2Params are the cost parameters of argon2id.
+type Argon2Params struct {
+	Memory  uint32 // KiB
+	Time    uint32 // Passes over memory
+	Threads uint8
+}
+
+// DefaultArgon2Params are the OWASP minimum recommendation: 19 MiB of
+// memory and two passes on one thread.
+var DefaultArgon2Params = Argon2Params{Memory: 19 * 1024, Time: 2, Threads: 1}
+
+// Argon2Hasher hashes passwords with argon2id (RFC 9106) and encodes them
+// as PHC strings.
+type Argon2Hasher struct {
+	Params Argon2Params
+}
+
+func NewArgon2Hasher(params Argon2Params) (*Argon2Hasher, error) {
+	if params.Memory < 8*uint32(params.Threads) || params.Time < 1 || params.Threads < 1 {
+		return nil, errors.New("argon2id needs at least one pass, one thread and 8 KiB of memory per thread")
+	}
+	if params.Memory > maxArgon2Memory || params.Time > maxArgon2Time {
+		return nil, fmt.Errorf("argon2id takes at most %d KiB of memory and %d passes", maxArgon2Memory, maxArgon2Time)
+	}
+	return &Argon2Hasher{Params: params}, nil
+}
+
+func (h *Argon2Hasher) Algorithm() string {
+	return AlgorithmArgon2id
+}
+
+func (h *Argon2Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.Params
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, argon2KeyBytes)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2Hasher) Verify(encoded, password string) (bool, error) {
+	return argon2Verifier{}.Verify(encoded, password)
+}
+
+func (h *Argon2Hasher) Validate(encoded string) error {
+	return argon2Verifier{}.Validate(encoded)
+}
+
+func (h *Argon2Hasher) NeedsRehash(encoded string) bool {
+	params, _, key, err := decodeArgon2(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.Params.Memory ||
+		params.Time < h.Params.Time ||
+		params.Threads < h.Params.Threads ||
+		len(key) < argon2KeyBytes
+}
+
+type argon2Verifier struct{}
+
+func (argon2Verifier) Validate(encoded string) error {
+	_, _, _, err := decodeArgon2(encoded)
+	return err
+}
+
+func (argon2Verifier) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+// decodeArgon2 parses "$argon2id$v=19$m=<KiB>,t=<passes>,p=<threads>$<salt>$<hash>".
+func decodeArgon2(encoded string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 6 || fields[0] != "" || fields[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported argon2 version", ErrMalformedHash)
+	}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	if params.Time < 1 || params.Threads < 1 || params.Memory < 8*uint32(params.Threads) ||
+		params.Memory > maxArgon2Memory || params.Time > maxArgon2Time {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil || len(key) == 0 || len(key) > maxArgon2KeyBytes {
+		return params, nil, nil, ErrMalformedHash
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,148 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testArgon2 keeps hashing in tests fast.
+var testArgon2 = Argon2Params{Memory: 64, Time: 1, Threads: 1}
+
+func TestArgon2HashAndVerify(t *testing.T) {
+	hasher, err := NewArgon2Hasher(testArgon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("got hash %q, want a PHC string with the parameters", encoded)
+	}
+	if Identify(encoded) != AlgorithmArgon2id {
+		t.Errorf("Identify(%q) = %q, want argon2id", encoded, Identify(encoded))
+	}
+
+	for password, want := range map[string]bool{"correct horse": true, "correct horse ": false, "": false} {
+		ok, err := Verify(encoded, password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != want {
+			t.Errorf("Verify(%q) = %v, want %v", password, ok, want)
+		}
+	}
+
+	again, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == encoded {
+		t.Error("two hashes of a password are equal, want different salts")
+	}
+}
+
+func TestArgon2ParsesPHCStrings(t *testing.T) {
+	const (
+		salt = "c29tZXNhbHRzb21lc2FsdA"
+		key  = "dGhpcnR5LXR3byBieXRlcyBvZiBoYXNoIG91dHB1dCE"
+	)
+	tests := []struct {
+		name    string
+		encoded string
+		valid   bool
+	}{
+		{"valid", "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$" + key, true},
+		{"largest allowed cost", "$argon2id$v=19$m=1048576,t=16,p=4$" + salt + "$" + key, true},
+		{"old version", "$argon2id$v=16$m=19456,t=2,p=1$" + salt + "$" + key, false},
+		{"argon2i", "$argon2i$v=19$m=19456,t=2,p=1$" + salt + "$" + key, false},
+		{"missing field", "$argon2id$v=19$m=19456,t=2,p=1$" + salt, false},
+		{"zero passes", "$argon2id$v=19$m=19456,t=0,p=1$" + salt + "$" + key, false},
+		{"zero threads", "$argon2id$v=19$m=19456,t=2,p=0$" + salt + "$" + key, false},
+		{"too little memory per thread", "$argon2id$v=19$m=16,t=2,p=4$" + salt + "$" + key, false},
+		{"too much memory", "$argon2id$v=19$m=1048577,t=2,p=1$" + salt + "$" + key, false},
+		{"memory overflows uint32", "$argon2id$v=19$m=4294967296,t=2,p=1$" + salt + "$" + key, false},
+		{"too many passes", "$argon2id$v=19$m=19456,t=17,p=1$" + salt + "$" + key, false},
+		{"too many threads", "$argon2id$v=19$m=19456,t=2,p=256$" + salt + "$" + key, false},
+		{"bad salt", "$argon2id$v=19$m=19456,t=2,p=1$!!$" + key, false},
+		{"empty hash", "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$", false},
+		{"hash too long", "$argon2id$v=19$m=19456,t=2,p=1$" + salt + "$" + strings.Repeat("A", 100), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := argon2Verifier{}.Validate(tt.encoded)
+			if tt.valid && err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrMalformedHash) {
+				t.Errorf("Validate: got %v, want ErrMalformedHash", err)
+			}
+
+			_, err = Import(AlgorithmArgon2id, tt.encoded)
+			if tt.valid != (err == nil) {
+				t.Errorf("Import: got %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestNewArgon2HasherBounds(t *testing.T) {
+	for _, params := range []Argon2Params{
+		{Memory: 64, Time: 0, Threads: 1},
+		{Memory: 64, Time: 1, Threads: 0},
+		{Memory: 16, Time: 1, Threads: 4},
+		{Memory: maxArgon2Memory + 1, Time: 1, Threads: 1},
+		{Memory: 64, Time: maxArgon2Time + 1, Threads: 1},
+	} {
+		if _, err := NewArgon2Hasher(params); err == nil {
+			t.Errorf("NewArgon2Hasher(%+v) accepted invalid parameters", params)
+		}
+	}
+}
+
+func TestArgon2NeedsRehash(t *testing.T) {
+	weak, err := NewArgon2Hasher(testArgon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := weak.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHasher, err := NewBcryptHasher(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcrypted, err := bcryptHasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		params  Argon2Params
+		encoded string
+		want    bool
+	}{
+		{"same parameters", testArgon2, encoded, false},
+		{"weaker configuration", Argon2Params{Memory: 32, Time: 1, Threads: 1}, encoded, false},
+		{"more memory", Argon2Params{Memory: 128, Time: 1, Threads: 1}, encoded, true},
+		{"more passes", Argon2Params{Memory: 64, Time: 2, Threads: 1}, encoded, true},
+		{"more threads", Argon2Params{Memory: 64, Time: 1, Threads: 2}, encoded, true},
+		{"other algorithm", testArgon2, bcrypted, true},
+		{"malformed", testArgon2, "$argon2id$garbage", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewArgon2Hasher(tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
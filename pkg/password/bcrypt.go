@@ -0,0 +1,92 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxBcryptCost bounds stored and imported hashes: each step doubles the
+// work, and cost 31 takes hours to verify a single password.
+const maxBcryptCost = 15
+
+// BcryptHasher hashes passwords with bcrypt. Passwords longer than
+// BcryptMaxBytes cannot be hashed.
+type BcryptHasher struct {
+	Cost int
+}
+
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > maxBcryptCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, maxBcryptCost)
+	}
+	return &BcryptHasher{Cost: cost}, nil
+}
+
+func (h *BcryptHasher) Algorithm() string {
+	return AlgorithmBcrypt
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	return bcryptVerifier{}.Verify(encoded, password)
+}
+
+func (h *BcryptHasher) Validate(encoded string) error {
+	return bcryptVerifier{}.Validate(encoded)
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	if !isBcrypt(encoded) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+type bcryptVerifier struct{}
+
+func (bcryptVerifier) Validate(encoded string) error {
+	if !isBcrypt(encoded) {
+		return ErrMalformedHash
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil || cost > maxBcryptCost || len(encoded) != 60 {
+		return ErrMalformedHash
+	}
+	return nil
+}
+
+func (bcryptVerifier) Verify(encoded, password string) (bool, error) {
+	if cost, err := bcrypt.Cost([]byte(encoded)); err == nil && cost > maxBcryptCost {
+		return false, ErrMalformedHash
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// isBcrypt reports whether encoded is in bcrypt's "$2a$", "$2b$" or "$2y$" format.
+func isBcrypt(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// rangePrefixLength is the number of leading hex digits of a SHA-1 hash that
+// select a range, as in the Pwned Passwords range API.
+const rangePrefixLength = 5
+
+// maxLineLength bounds a line of a breached password file: 40 hex digits,
+// a count and a line break.
+const maxLineLength = 128
+
+// corpusSamples is how many lines OpenCorpus checks for format and order.
+const corpusSamples = 64
+
+var errCorpusLine = errors.New("line is not a SHA-1 hash")
+
+// Corpus is a set of passwords known from data breaches, held as SHA-1
+// hashes. Lookups follow the k-anonymity model of the Pwned Passwords range
+// API: a password's hash prefix selects a range of candidate suffixes,
+// which are compared locally. Contains therefore only needs Range, and the
+// same flow works against a remote range source.
+//
+// The hashes stay on disk: the full Pwned Passwords list holds close to a
+// billion of them, far too many to keep in memory. Each lookup is a binary
+// search over the sorted file, a few dozen small reads. A Corpus is safe for
+// concurrent use.
+type Corpus struct {
+	file *os.File
+	size int64
+}
+
+// OpenCorpus opens a breached password file with one uppercase or lowercase
+// hex SHA-1 hash per line, optionally followed by ":count", sorted by hash,
+// as in the downloadable Pwned Passwords lists ordered by hash. Blank lines
+// are skipped. The file is searched in place, so it must not change while
+// open. A sample of lines is checked up front; a file that is not sorted is
+// rejected if the sample shows it, and otherwise misses hashes.
+func OpenCorpus(path string) (*Corpus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	c := &Corpus{file: f, size: info.Size()}
+	if err := c.checkSample(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Close closes the file.
+func (c *Corpus) Close() error {
+	return c.file.Close()
+}
+
+// Size returns the size of the file in bytes.
+func (c *Corpus) Size() int64 {
+	return c.size
+}
+
+// Range returns the uppercase hex suffixes of every hash that starts with
+// the five hex digit prefix. A read error yields no suffixes.
+func (c *Corpus) Range(prefix string) []string {
+	if len(prefix) != rangePrefixLength {
+		return nil
+	}
+	// Five hex digits are 20 bits: two whole bytes and the top half of a third
+	raw, err := hex.DecodeString(prefix + "0")
+	if err != nil {
+		return nil
+	}
+	var low, high [sha1.Size]byte
+	copy(low[:], raw)
+	copy(high[:], raw)
+	high[2] |= 0x0f
+	for i := 3; i < sha1.Size; i++ {
+		high[i] = 0xff
+	}
+
+	start, err := c.seek(low)
+	if err != nil {
+		return nil
+	}
+
+	var suffixes []string
+	lines := bufio.NewReaderSize(io.NewSectionReader(c.file, start, c.size-start), 4096)
+	for {
+		line, err := lines.ReadSlice('\n')
+		if len(line) > 0 {
+			sum, ok, parseErr := parseHashLine(line)
+			if parseErr != nil || (ok && compareHash(sum, high) > 0) {
+				break
+			}
+			if ok {
+				suffixes = append(suffixes, strings.ToUpper(hex.EncodeToString(sum[:]))[rangePrefixLength:])
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return suffixes
+}
+
+// Contains reports whether password is in the corpus.
+func (c *Corpus) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return slices.Contains(c.Range(digest[:rangePrefixLength]), digest[rangePrefixLength:])
+}
+
+// seek returns the offset of the first line whose hash is not below target,
+// or the file size if there is none.
+func (c *Corpus) seek(target [sha1.Size]byte) (int64, error) {
+	// Find the smallest offset from which the next hash is >= target. In a
+	// sorted file that predicate only turns from false to true once.
+	lo, hi := int64(0), c.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := c.nextLine(mid)
+		if err != nil {
+			return 0, err
+		}
+		sum, _, found, err := c.hashFrom(start)
+		if err != nil {
+			return 0, err
+		}
+		if !found || compareHash(sum, target) >= 0 {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return c.nextLine(lo)
+}
+
+// checkSample parses evenly spaced lines and checks they are in order.
+func (c *Corpus) checkSample() error {
+	var previous []byte
+	for i := int64(0); i < corpusSamples; i++ {
+		start, err := c.nextLine(c.size * i / corpusSamples)
+		if err != nil {
+			return err
+		}
+		sum, _, found, err := c.hashFrom(start)
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+		if previous != nil && bytes.Compare(previous, sum[:]) > 0 {
+			return errors.New("hashes are not sorted")
+		}
+		previous = sum[:]
+	}
+	return nil
+}
+
+// nextLine returns the offset of the first line starting at or after off.
+func (c *Corpus) nextLine(off int64) (int64, error) {
+	if off == 0 {
+		return 0, nil
+	}
+	// The line holding the byte before off ends where the next one starts
+	_, next, err := c.lineAt(off - 1)
+	return next, err
+}
+
+// hashFrom returns the hash of the first non-blank line starting at or
+// after the line start off, and the offset of the line after it.
+func (c *Corpus) hashFrom(off int64) ([sha1.Size]byte, int64, bool, error) {
+	for off < c.size {
+		line, next, err := c.lineAt(off)
+		if err != nil {
+			return [sha1.Size]byte{}, 0, false, err
+		}
+		sum, ok, err := parseHashLine(line)
+		if err != nil {
+			return [sha1.Size]byte{}, 0, false, fmt.Errorf("offset %d: %w", off, err)
+		}
+		if ok {
+			return sum, next, true, nil
+		}
+		off = next
+	}
+	return [sha1.Size]byte{}, c.size, false, nil
+}
+
+// lineAt reads from off to the end of its line. It returns the bytes read,
+// without the line break, and the offset of the next line.
+func (c *Corpus) lineAt(off int64) ([]byte, int64, error) {
+	buf := make([]byte, maxLineLength)
+	n, err := c.file.ReadAt(buf, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+	buf = buf[:n]
+
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		return buf[:i], off + int64(i) + 1, nil
+	}
+	if off+int64(n) < c.size {
+		return nil, 0, fmt.Errorf("offset %d: %w", off, errCorpusLine)
+	}
+	return buf, c.size, nil
+}
+
+// parseHashLine parses "HASH" or "HASH:count". It returns false for blank
+// lines.
+func parseHashLine(line []byte) ([sha1.Size]byte, bool, error) {
+	var sum [sha1.Size]byte
+
+	text := bytes.TrimSpace(line)
+	if len(text) == 0 {
+		return sum, false, nil
+	}
+	if i := bytes.IndexByte(text, ':'); i >= 0 {
+		text = text[:i]
+	}
+	if len(text) != hex.EncodedLen(sha1.Size) {
+		return sum, false, errCorpusLine
+	}
+	if _, err := hex.Decode(sum[:], text); err != nil {
+		return sum, false, errCorpusLine
+	}
+	return sum, true, nil
+}
+
+func compareHash(a, b [sha1.Size]byte) int {
+	return bytes.Compare(a[:], b[:])
+}
@@ -0,0 +1,133 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeCorpus writes the SHA-1 hashes of passwords in Pwned Passwords
+// format, sorted unless told otherwise.
+func writeCorpus(t *testing.T, passwords []string, sorted bool) string {
+	t.Helper()
+
+	var lines []string
+	for i, p := range passwords {
+		sum := sha1.Sum([]byte(p))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	if sorted {
+		slices.Sort(lines)
+	}
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func breachedPasswords(n int) []string {
+	passwords := make([]string, n)
+	for i := range passwords {
+		passwords[i] = fmt.Sprintf("breached-%d", i)
+	}
+	return passwords
+}
+
+func TestCorpusContains(t *testing.T) {
+	passwords := breachedPasswords(5000)
+	corpus, err := OpenCorpus(writeCorpus(t, passwords, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer corpus.Close()
+
+	for _, p := range passwords {
+		if !corpus.Contains(p) {
+			t.Fatalf("Contains(%q) = false, want true", p)
+		}
+	}
+	for _, p := range []string{"", "breached-5000", "correct horse battery staple"} {
+		if corpus.Contains(p) {
+			t.Errorf("Contains(%q) = true, want false", p)
+		}
+	}
+}
+
+func TestCorpusRange(t *testing.T) {
+	passwords := breachedPasswords(5000)
+	corpus, err := OpenCorpus(writeCorpus(t, passwords, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer corpus.Close()
+
+	// Every range matches a linear scan, including the first and last
+	// possible prefixes
+	want := make(map[string][]string)
+	for _, p := range passwords {
+		sum := sha1.Sum([]byte(p))
+		digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+		want[digest[:5]] = append(want[digest[:5]], digest[5:])
+	}
+	for prefix := range want {
+		slices.Sort(want[prefix])
+	}
+
+	for _, prefix := range []string{"00000", "FFFFF", "7A3B1"} {
+		if got := corpus.Range(prefix); len(got) != len(want[prefix]) {
+			t.Errorf("Range(%q) = %v, want %v", prefix, got, want[prefix])
+		}
+	}
+	for prefix, suffixes := range want {
+		if got := corpus.Range(strings.ToLower(prefix)); !slices.Equal(got, suffixes) {
+			t.Fatalf("Range(%q) = %v, want %v", prefix, got, suffixes)
+		}
+	}
+
+	for _, prefix := range []string{"", "0000", "000000", "XYZ12"} {
+		if got := corpus.Range(prefix); got != nil {
+			t.Errorf("Range(%q) = %v, want nil", prefix, got)
+		}
+	}
+}
+
+func TestOpenCorpusRejectsBadFiles(t *testing.T) {
+	if _, err := OpenCorpus(writeCorpus(t, breachedPasswords(5000), false)); err == nil {
+		t.Error("OpenCorpus accepted an unsorted file")
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.txt")
+	if err := os.WriteFile(path, []byte("not a hash\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenCorpus(path); err == nil {
+		t.Error("OpenCorpus accepted a file without hashes")
+	}
+
+	if _, err := OpenCorpus(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("OpenCorpus accepted a missing file")
+	}
+}
+
+func TestEmptyCorpus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.txt")
+	if err := os.WriteFile(path, []byte("\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	corpus, err := OpenCorpus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer corpus.Close()
+
+	if corpus.Contains("password") {
+		t.Error("an empty corpus contains a password")
+	}
+}
@@ -0,0 +1,81 @@
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+// Hash algorithms, named as in the hash strings they produce
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrUnknownHash   = errors.New("unrecognised password hash format")
+	ErrMalformedHash = errors.New("malformed password hash")
+)
+
+// Verifier checks passwords against hashes of one algorithm, whatever
+// parameters they were made with.
+type Verifier interface {
+	Verify(encoded, password string) (bool, error)
+
+	// Validate reports whether encoded is a well-formed hash of the
+	// algorithm, without the cost of checking a password.
+	Validate(encoded string) error
+}
+
+// Hasher hashes new passwords with one algorithm and set of parameters.
+// Hashes are self-describing strings: bcrypt's own "$2a$<cost>$…" format,
+// or a PHC string such as "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>"
+// that records the algorithm and every parameter next to the salt.
+type Hasher interface {
+	Verifier
+
+	// Algorithm names the hashes this hasher produces.
+	Algorithm() string
+
+	// Hash returns a new salted hash of password.
+	Hash(password string) (string, error)
+
+	// NeedsRehash reports whether encoded should be replaced with a fresh
+	// hash: it uses another algorithm or weaker parameters than Hash would.
+	NeedsRehash(encoded string) bool
+}
+
+// verifiers knows every algorithm a stored hash may use.
+var verifiers = map[string]Verifier{
+	AlgorithmBcrypt:       bcryptVerifier{},
+	AlgorithmArgon2id:     argon2Verifier{},
+	AlgorithmPBKDF2SHA256: pbkdf2Verifier{},
+	AlgorithmScrypt:       scryptVerifier{},
+	AlgorithmSaltedSHA1:   sha1Verifier{},
+}
+
+// Verify reports whether password matches encoded, a hash made by any
+// supported algorithm. It returns ErrUnknownHash for formats it does not
+// recognise.
+func Verify(encoded, password string) (bool, error) {
+	verifier, ok := verifiers[Identify(encoded)]
+	if !ok {
+		return false, ErrUnknownHash
+	}
+	return verifier.Verify(encoded, password)
+}
+
+// Identify returns the algorithm that produced encoded, or "" if the format
+// is not recognised.
+func Identify(encoded string) string {
+	if isBcrypt(encoded) {
+		return AlgorithmBcrypt
+	}
+	if !strings.HasPrefix(encoded, "$") {
+		return ""
+	}
+	id, _, _ := strings.Cut(encoded[1:], "$")
+	if _, ok := verifiers[id]; !ok {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,196 @@
+package password
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Algorithms of hashes imported from other systems. They can be verified
+// but are never produced; a successful login replaces them with a hash
+// from the configured Hasher.
+const (
+	AlgorithmPBKDF2SHA256 = "pbkdf2-sha256"
+	AlgorithmScrypt       = "scrypt"
+	AlgorithmSaltedSHA1   = "sha1"
+)
+
+// Bounds on imported parameters, so a bad record cannot make a login
+// burn minutes of CPU or gigabytes of memory
+const (
+	maxPBKDF2Iterations = 10_000_000
+	maxScryptMemory     = 1 << 30 // Bytes: 128 * N * r
+	maxScryptThreads    = 16      // p
+	maxScryptWork       = 1 << 24 // N * r * p, each unit mixing 128 bytes twice
+	maxLegacyKeyBytes   = 64      // Every extra block of key repeats the work
+)
+
+// Import turns a hash exported from another system into the form stored
+// for users. Native bcrypt and argon2id hashes are kept as they are.
+// Legacy hashes are kept verbatim behind an algorithm marker,
+// "$<algorithm>$<hash>", and must be in the formats Django writes:
+//
+//	pbkdf2-sha256  pbkdf2_sha256$<iterations>$<salt>$<base64 hash>
+//	scrypt         scrypt$<salt>$<N>$<r>$<p>$<base64 hash>
+//	sha1           sha1$<salt>$<hex sha1(salt + password)>
+func Import(algorithm, hash string) (string, error) {
+	var stored string
+	switch algorithm {
+	case AlgorithmBcrypt, AlgorithmArgon2id:
+		stored = hash
+	case AlgorithmPBKDF2SHA256, AlgorithmScrypt, AlgorithmSaltedSHA1:
+		stored = "$" + algorithm + "$" + hash
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownHash, algorithm)
+	}
+
+	if Identify(stored) != algorithm {
+		return "", ErrMalformedHash
+	}
+	if err := verifiers[algorithm].Validate(stored); err != nil {
+		return "", err
+	}
+	return stored, nil
+}
+
+// legacyFields strips the algorithm marker and splits the verbatim hash,
+// which must start with prefix and have n fields in total.
+func legacyFields(encoded, algorithm, prefix string, n int) ([]string, error) {
+	verbatim, ok := strings.CutPrefix(encoded, "$"+algorithm+"$")
+	if !ok {
+		return nil, ErrMalformedHash
+	}
+	fields := strings.Split(verbatim, "$")
+	if len(fields) != n || fields[0] != prefix {
+		return nil, ErrMalformedHash
+	}
+	return fields, nil
+}
+
+// decodeBase64 accepts padded or unpadded standard base64.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+type pbkdf2Verifier struct{}
+
+func (pbkdf2Verifier) parse(encoded string) (iterations int, salt string, key []byte, err error) {
+	fields, err := legacyFields(encoded, AlgorithmPBKDF2SHA256, "pbkdf2_sha256", 4)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	iterations, err = strconv.Atoi(fields[1])
+	if err != nil || iterations < 1 || iterations > maxPBKDF2Iterations {
+		return 0, "", nil, ErrMalformedHash
+	}
+	key, err = decodeBase64(fields[3])
+	if err != nil || len(key) == 0 || len(key) > maxLegacyKeyBytes {
+		return 0, "", nil, ErrMalformedHash
+	}
+	return iterations, fields[2], key, nil
+}
+
+func (v pbkdf2Verifier) Validate(encoded string) error {
+	_, _, _, err := v.parse(encoded)
+	return err
+}
+
+func (v pbkdf2Verifier) Verify(encoded, password string) (bool, error) {
+	iterations, salt, key, err := v.parse(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed, err := pbkdf2.Key(sha256.New, password, []byte(salt), iterations, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+type scryptVerifier struct{}
+
+type scryptParams struct {
+	salt    string
+	n, r, p int
+	key     []byte
+}
+
+func (scryptVerifier) parse(encoded string) (scryptParams, error) {
+	var params scryptParams
+	fields, err := legacyFields(encoded, AlgorithmScrypt, "scrypt", 6)
+	if err != nil {
+		return params, err
+	}
+
+	params.salt = fields[1]
+	for i, dst := range []*int{&params.n, &params.r, &params.p} {
+		if *dst, err = strconv.Atoi(fields[2+i]); err != nil || *dst < 1 {
+			return params, ErrMalformedHash
+		}
+	}
+	// N and r are checked one at a time so their product cannot overflow
+	if params.n < 2 || params.n&(params.n-1) != 0 || params.n > maxScryptMemory/128 ||
+		params.r > maxScryptMemory/(128*params.n) || params.p > maxScryptThreads ||
+		params.n*params.r*params.p > maxScryptWork {
+		return params, ErrMalformedHash
+	}
+
+	params.key, err = decodeBase64(fields[5])
+	if err != nil || len(params.key) == 0 || len(params.key) > maxLegacyKeyBytes {
+		return params, ErrMalformedHash
+	}
+	return params, nil
+}
+
+func (v scryptVerifier) Validate(encoded string) error {
+	_, err := v.parse(encoded)
+	return err
+}
+
+func (v scryptVerifier) Verify(encoded, password string) (bool, error) {
+	params, err := v.parse(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed, err := scrypt.Key([]byte(password), []byte(params.salt), params.n, params.r, params.p, len(params.key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(computed, params.key) == 1, nil
+}
+
+type sha1Verifier struct{}
+
+func (sha1Verifier) parse(encoded string) (salt string, sum []byte, err error) {
+	fields, err := legacyFields(encoded, AlgorithmSaltedSHA1, "sha1", 3)
+	if err != nil {
+		return "", nil, err
+	}
+	sum, err = hex.DecodeString(fields[2])
+	if err != nil || len(sum) != sha1.Size {
+		return "", nil, ErrMalformedHash
+	}
+	return fields[1], sum, nil
+}
+
+func (v sha1Verifier) Validate(encoded string) error {
+	_, _, err := v.parse(encoded)
+	return err
+}
+
+func (v sha1Verifier) Verify(encoded, password string) (bool, error) {
+	salt, sum, err := v.parse(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed := sha1.Sum([]byte(salt + password))
+	return subtle.ConstantTimeCompare(computed[:], sum) == 1, nil
+}
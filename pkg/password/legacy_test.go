@@ -0,0 +1,135 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Hashes of "hunter2" with the salt "saltysalt", in the formats Django writes
+const (
+	djangoPBKDF2 = "pbkdf2_sha256$1000$saltysalt$4idrNgJDcgpjZOduHmFH8FbAQXA5uaxdDE8hj0bMuew="
+	djangoScrypt = "scrypt$saltysalt$1024$8$1$NPYNOiQVriR/7Rjn+43rEggaHjjNzs8+rnxvz8WeubH2RbSfKOVP0ub62D5ws7OoyMXRhV+bPttJJOfv8MxklQ=="
+	djangoSHA1   = "sha1$saltysalt$49f3811096e15395a434a8d9ecd0274b5e6839dd"
+)
+
+func TestImportAndVerifyLegacyHashes(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		hash      string
+	}{
+		{AlgorithmPBKDF2SHA256, djangoPBKDF2},
+		{AlgorithmScrypt, djangoScrypt},
+		{AlgorithmSaltedSHA1, djangoSHA1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			stored, err := Import(tt.algorithm, tt.hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "$" + tt.algorithm + "$" + tt.hash; stored != want {
+				t.Errorf("Import = %q, want %q", stored, want)
+			}
+			if Identify(stored) != tt.algorithm {
+				t.Errorf("Identify(%q) = %q, want %q", stored, Identify(stored), tt.algorithm)
+			}
+
+			for password, want := range map[string]bool{"hunter2": true, "hunter3": false, "": false} {
+				ok, err := Verify(stored, password)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != want {
+					t.Errorf("Verify(%q) = %v, want %v", password, ok, want)
+				}
+			}
+
+			// A successful login replaces a legacy hash
+			hasher, err := NewArgon2Hasher(testArgon2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasher.NeedsRehash(stored) {
+				t.Error("NeedsRehash = false for a legacy hash")
+			}
+		})
+	}
+}
+
+func TestImportRejectsBadHashes(t *testing.T) {
+	key := "4idrNgJDcgpjZOduHmFH8FbAQXA5uaxdDE8hj0bMuew="
+	long := strings.Repeat("A", 128)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		hash      string
+	}{
+		{"unknown algorithm", "md5", "5f4dcc3b5aa765d61d8327deb882cf99"},
+		{"pbkdf2 wrong prefix", AlgorithmPBKDF2SHA256, "pbkdf2_sha1$1000$salt$" + key},
+		{"pbkdf2 missing field", AlgorithmPBKDF2SHA256, "pbkdf2_sha256$1000$" + key},
+		{"pbkdf2 zero iterations", AlgorithmPBKDF2SHA256, "pbkdf2_sha256$0$salt$" + key},
+		{"pbkdf2 too many iterations", AlgorithmPBKDF2SHA256, "pbkdf2_sha256$10000001$salt$" + key},
+		{"pbkdf2 bad base64", AlgorithmPBKDF2SHA256, "pbkdf2_sha256$1000$salt$!!"},
+		{"pbkdf2 key too long", AlgorithmPBKDF2SHA256, "pbkdf2_sha256$1000$salt$" + long},
+		{"scrypt N not a power of two", AlgorithmScrypt, "scrypt$salt$1000$8$1$" + key},
+		{"scrypt N too small", AlgorithmScrypt, "scrypt$salt$1$8$1$" + key},
+		{"scrypt too much memory", AlgorithmScrypt, "scrypt$salt$1048576$16$1$" + key},
+		{"scrypt N*r overflows", AlgorithmScrypt, "scrypt$salt$2$4611686018427387904$1$" + key},
+		{"scrypt N overflows", AlgorithmScrypt, "scrypt$salt$4611686018427387904$1$1$" + key},
+		{"scrypt too many threads", AlgorithmScrypt, "scrypt$salt$16384$8$17$" + key},
+		{"scrypt too much work", AlgorithmScrypt, "scrypt$salt$1048576$8$4$" + key},
+		{"scrypt zero p", AlgorithmScrypt, "scrypt$salt$16384$8$0$" + key},
+		{"scrypt key too long", AlgorithmScrypt, "scrypt$salt$16384$8$1$" + long},
+		{"sha1 short digest", AlgorithmSaltedSHA1, "sha1$salt$49f3811096e15395"},
+		{"sha1 not hex", AlgorithmSaltedSHA1, "sha1$salt$" + strings.Repeat("z", 40)},
+		{"argon2id that is bcrypt", AlgorithmArgon2id, "$2a$10$abcdefghijklmnopqrstuuabcdefghijklmnopqrstuvwxyzABCDE"},
+		{"bcrypt garbage", AlgorithmBcrypt, "$2a$10$short"},
+		{"bcrypt cost too high", AlgorithmBcrypt, "$2b$31$abcdefghijklmnopqrstuuabcdefghijklmnopqrstuvwxyzABCDE"},
+		{"bcrypt cost above the bound", AlgorithmBcrypt, "$2b$16$abcdefghijklmnopqrstuuabcdefghijklmnopqrstuvwxyzABCDE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if stored, err := Import(tt.algorithm, tt.hash); err == nil {
+				t.Errorf("Import accepted %q as %q", tt.hash, stored)
+			}
+		})
+	}
+}
+
+func TestScryptBoundsAllowDjangoDefaults(t *testing.T) {
+	// Django's defaults, and the largest memory allowed with a single thread
+	for _, hash := range []string{
+		"scrypt$salt$16384$8$1$" + strings.Repeat("A", 86),
+		"scrypt$salt$1048576$8$1$" + strings.Repeat("A", 86),
+		"scrypt$salt$16384$8$16$" + strings.Repeat("A", 86),
+	} {
+		if _, err := Import(AlgorithmScrypt, hash); err != nil {
+			t.Errorf("Import(%q): %v", hash, err)
+		}
+	}
+}
+
+func TestVerifyUnknownHash(t *testing.T) {
+	for _, encoded := range []string{"", "plaintext", "$md5$abc", "$sha1"} {
+		if _, err := Verify(encoded, "password"); !errors.Is(err, ErrUnknownHash) && !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("Verify(%q): got %v, want ErrUnknownHash or ErrMalformedHash", encoded, err)
+		}
+	}
+}
+
+func TestBcryptCostBound(t *testing.T) {
+	const salted = "abcdefghijklmnopqrstuuabcdefghijklmnopqrstuvwxyzABCDE"
+
+	if _, err := Import(AlgorithmBcrypt, "$2b$15$"+salted); err != nil {
+		t.Errorf("Import at the largest cost: %v", err)
+	}
+	// Hashes stored before the bound are refused rather than verified
+	if _, err := Verify("$2b$31$"+salted, "password"); !errors.Is(err, ErrMalformedHash) {
+		t.Errorf("Verify at cost 31: got %v, want ErrMalformedHash", err)
+	}
+	if _, err := NewBcryptHasher(16); err == nil {
+		t.Error("NewBcryptHasher accepted cost 16")
+	}
+}
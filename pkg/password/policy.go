@@ -0,0 +1,183 @@
+// Package password decides whether a new password is acceptable and hashes
+// and verifies passwords. A Policy combines length and character class
+// rules, a ban on the user's own email address, an estimate of how
+// guessable the password is and, optionally, a corpus of passwords known
+// from data breaches. A Hasher produces argon2id or bcrypt hashes.
+package password
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BcryptMaxBytes is the longest password bcrypt hashes in full; it rejects
+// anything longer.
+const BcryptMaxBytes = 72
+
+// Reasons a password is rejected. They are part of the API, so clients can
+// show their own messages.
+const (
+	ReasonTooShort      = "too_short"
+	ReasonTooLong       = "too_long"
+	ReasonTooFewClasses = "too_few_character_classes"
+	ReasonContainsEmail = "contains_email"
+	ReasonTooWeak       = "too_weak"
+	ReasonBreached      = "breached"
+)
+
+// minEmailPartLength is the shortest part of an email address that a
+// password may not contain; shorter parts match too many passwords by chance.
+const minEmailPartLength = 3
+
+// Violation is one rule a password breaks.
+type Violation struct {
+	Reason  string
+	Message string
+}
+
+// PolicyError lists every rule a rejected password breaks.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return "password rejected: " + strings.Join(messages, "; ")
+}
+
+// Policy is the set of rules new passwords must satisfy. The zero value
+// accepts everything but the empty password.
+type Policy struct {
+	MinLength   int     // Minimum length in characters
+	MaxLength   int     // Maximum length in bytes; 0 means no limit
+	MinClasses  int     // Minimum number of character classes: lowercase, uppercase, digits, symbols
+	MinStrength int     // Minimum Strength score, 0 to 4
+	Breached    *Corpus // Known breached passwords; nil disables the check
+}
+
+// Check returns a *PolicyError if password breaks any rule. email is the
+// account's address; the password may not contain it or its parts.
+func (p *Policy) Check(password, email string) error {
+	var violations []Violation
+
+	// A password over the limit is refused before looking any further, so
+	// nothing expensive runs on arbitrarily long input
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return &PolicyError{Violations: []Violation{{
+			Reason:  ReasonTooLong,
+			Message: "password must be at most " + strconv.Itoa(p.MaxLength) + " bytes",
+		}}}
+	}
+
+	if n := utf8.RuneCountInString(password); n < max(p.MinLength, 1) {
+		violations = append(violations, Violation{
+			Reason:  ReasonTooShort,
+			Message: "password must be at least " + strconv.Itoa(max(p.MinLength, 1)) + " characters",
+		})
+	}
+
+	if p.MinClasses > 0 && characterClasses(password) < p.MinClasses {
+		violations = append(violations, Violation{
+			Reason: ReasonTooFewClasses,
+			Message: "password must mix at least " + strconv.Itoa(p.MinClasses) +
+				" of lowercase letters, uppercase letters, digits and symbols",
+		})
+	}
+
+	parts := emailParts(email)
+	if containsAny(password, parts) {
+		violations = append(violations, Violation{
+			Reason:  ReasonContainsEmail,
+			Message: "password must not contain your email address or parts of it",
+		})
+	}
+
+	if p.MinStrength > 0 && Strength(password, parts...) < p.MinStrength {
+		violations = append(violations, Violation{
+			Reason:  ReasonTooWeak,
+			Message: "password is too easy to guess; try a longer phrase of unrelated words",
+		})
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, Violation{
+			Reason:  ReasonBreached,
+			Message: "password has appeared in a data breach and must not be used",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// characterClasses counts how many of lowercase letters, uppercase letters,
+// digits and other characters password contains.
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// emailParts splits an address into the lowercase words a password should
+// not be built from: the local part, its pieces around punctuation, and
+// the domain labels other than the top-level domain.
+func emailParts(email string) []string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	local, domain, _ := strings.Cut(email, "@")
+
+	candidates := []string{local}
+	candidates = append(candidates, strings.FieldsFunc(local, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})...)
+	if labels := strings.Split(domain, "."); len(labels) > 1 {
+		candidates = append(candidates, labels[:len(labels)-1]...)
+	}
+
+	var parts []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if utf8.RuneCountInString(c) >= minEmailPartLength && !seen[c] {
+			seen[c] = true
+			parts = append(parts, c)
+		}
+	}
+	return parts
+}
+
+// containsAny reports whether password, ignoring case and common character
+// substitutions, contains any of words.
+func containsAny(password string, words []string) bool {
+	for _, variant := range normalize(password) {
+		for _, w := range words {
+			if strings.Contains(variant, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
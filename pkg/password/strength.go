@@ -0,0 +1,308 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// common.txt holds frequently used passwords and words, most common first.
+//
+//go:embed common.txt
+var commonList string
+
+// commonRank maps each common password to its 1-based rank.
+var commonRank = func() map[string]int {
+	ranks := make(map[string]int)
+	for i, word := range strings.Fields(commonList) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
+
+// Guess estimation constants, in the spirit of zxcvbn
+const (
+	bruteforceCardinality = 10   // Guesses per character not explained by any pattern
+	minWordGuesses        = 50   // Floor for a dictionary match of more than one character
+	keyboardGuesses       = 100  // Guesses per character of a straight keyboard row
+	referenceYear         = 2000 // Years are guessed outward from here
+	minYearSpace          = 20
+	minPatternLength      = 3
+	maxEstimateLength     = 100 // Only this many leading characters are analysed
+)
+
+// Score thresholds on log10(guesses): below 10^3 is score 0, below 10^6 is
+// 1, below 10^8 is 2 and below 10^10 is 3; anything harder scores 4.
+var scoreThresholds = []float64{3, 6, 8, 10}
+
+var keyboardRows = []string{
+	"1234567890",
+	"qwertyuiop",
+	"asdfghjkl",
+	"zxcvbnm",
+}
+
+// leet undoes common character substitutions. '1' is ambiguous, so
+// normalize produces one variant for each reading.
+var leet = map[rune]rune{
+	'4': 'a', '@': 'a', '3': 'e', '0': 'o', '$': 's', '5': 's',
+	'7': 't', '+': 't', '!': 'i', '|': 'l', '9': 'g', '8': 'b',
+}
+
+// Strength estimates how hard password is to guess, from 0 (trivial) to 4
+// (very hard), the same scale as zxcvbn. The password is split into the
+// cheapest combination of patterns an attacker would try: common passwords
+// (also reversed, capitalised or with character substitutions), the
+// userInputs, sequences like "abc" or "987", repeats, keyboard rows and
+// years. Characters that fit no pattern count as brute force.
+func Strength(password string, userInputs ...string) int {
+	guesses := guessesLog10(password, userInputs)
+	for score, threshold := range scoreThresholds {
+		if guesses < threshold {
+			return score
+		}
+	}
+	return len(scoreThresholds)
+}
+
+// guessesLog10 is log10 of the estimated number of guesses to find password.
+func guessesLog10(password string, userInputs []string) float64 {
+	original := []rune(password)
+	if len(original) > maxEstimateLength {
+		original = original[:maxEstimateLength]
+	}
+	n := len(original)
+	if n == 0 {
+		return 0
+	}
+
+	inputs := make(map[string]int, len(userInputs))
+	for i, in := range userInputs {
+		inputs[strings.ToLower(in)] = i + 1
+	}
+
+	// cost[i][j] is the cheapest pattern covering original[i:j], in log10
+	cost := make([][]float64, n)
+	for i := range cost {
+		cost[i] = make([]float64, n+1)
+		for j := range cost[i] {
+			cost[i][j] = math.Inf(1)
+		}
+	}
+	match := func(i, j int, guesses float64) {
+		if g := math.Log10(guesses); g < cost[i][j] {
+			cost[i][j] = g
+		}
+	}
+
+	matchWords(original, inputs, match)
+	matchSequences(original, match)
+	matchRepeats(original, userInputs, match)
+	matchKeyboard(original, match)
+	matchYears(original, match)
+
+	// Cheapest cover of the whole password, one prefix at a time
+	best := make([]float64, n+1)
+	for j := 1; j <= n; j++ {
+		best[j] = best[j-1] + math.Log10(bruteforceCardinality)
+		for i := 0; i < j; i++ {
+			best[j] = math.Min(best[j], best[i]+cost[i][j])
+		}
+	}
+	return best[n]
+}
+
+// matchWords finds common passwords and user inputs, forwards or reversed.
+func matchWords(original []rune, inputs map[string]int, match func(i, j int, guesses float64)) {
+	lower := lowerRunes(original)
+	for _, variant := range normalize(string(original)) {
+		runes := []rune(variant)
+		for i := range runes {
+			for j := i + minPatternLength; j <= len(runes); j++ {
+				word := string(runes[i:j])
+				substituted := word != string(lower[i:j])
+
+				for _, candidate := range []struct {
+					word       string
+					multiplier float64
+				}{{word, 1}, {reverse(word), 2}} {
+					rank, ok := inputs[candidate.word]
+					if !ok {
+						rank, ok = commonRank[candidate.word]
+					}
+					if !ok {
+						continue
+					}
+
+					guesses := float64(rank) * candidate.multiplier * caseVariations(original[i:j])
+					if substituted {
+						guesses *= 2
+					}
+					match(i, j, math.Max(guesses, minWordGuesses))
+				}
+			}
+		}
+	}
+}
+
+// matchSequences finds runs like "abcd", "4567" or "zyx".
+func matchSequences(original []rune, match func(i, j int, guesses float64)) {
+	lower := lowerRunes(original)
+	for i := 0; i+1 < len(lower); {
+		delta := lower[i+1] - lower[i]
+		if delta != 1 && delta != -1 {
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end+1 < len(lower) && lower[end+1]-lower[end] == delta {
+			end++
+		}
+		if length := end - i + 1; length >= minPatternLength {
+			base := 26.0
+			switch {
+			case strings.ContainsRune("az019", lower[i]):
+				base = 4
+			case unicode.IsDigit(lower[i]):
+				base = 10
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			match(i, end+1, base*float64(length))
+		}
+		i = end
+	}
+}
+
+// matchRepeats finds a character or a block repeated back to back, like
+// "aaaa" or "abcabc". A block costs what the block alone would.
+func matchRepeats(original []rune, userInputs []string, match func(i, j int, guesses float64)) {
+	n := len(original)
+	for i := 0; i < n; i++ {
+		for size := 1; i+2*size <= n; size++ {
+			block := string(original[i : i+size])
+			count := 1
+			for i+(count+1)*size <= n && string(original[i+count*size:i+(count+1)*size]) == block {
+				count++
+			}
+			if count < 2 || count*size < minPatternLength {
+				continue
+			}
+
+			blockGuesses := float64(bruteforceCardinality)
+			if size > 1 {
+				blockGuesses = math.Pow(10, guessesLog10(block, userInputs))
+			}
+			match(i, i+count*size, blockGuesses*float64(count))
+		}
+	}
+}
+
+// matchKeyboard finds straight runs along a row of a QWERTY keyboard, in
+// either direction.
+func matchKeyboard(original []rune, match func(i, j int, guesses float64)) {
+	lower := string(lowerRunes(original))
+	for _, row := range keyboardRows {
+		for _, line := range []string{row, reverse(row)} {
+			for length := len(line); length >= minPatternLength; length-- {
+				for start := 0; start+length <= len(line); start++ {
+					run := line[start : start+length]
+					for offset := 0; ; {
+						k := strings.Index(lower[offset:], run)
+						if k < 0 {
+							break
+						}
+						i := len([]rune(lower[:offset+k]))
+						match(i, i+length, keyboardGuesses*float64(length))
+						offset += k + 1
+					}
+				}
+			}
+		}
+	}
+}
+
+// matchYears finds four-digit years between 1900 and 2099.
+func matchYears(original []rune, match func(i, j int, guesses float64)) {
+	for i := 0; i+4 <= len(original); i++ {
+		year, err := strconv.Atoi(string(original[i : i+4]))
+		if err != nil || year < 1900 || year > 2099 {
+			continue
+		}
+		span := year - referenceYear
+		if span < 0 {
+			span = -span
+		}
+		match(i, i+4, float64(max(span, minYearSpace)))
+	}
+}
+
+// caseVariations is how many capitalisations of a word an attacker tries
+// before reaching word's: all lowercase is free, and a leading capital or
+// all caps only doubles the work.
+func caseVariations(word []rune) float64 {
+	var upper, lower int
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0 || (upper == 1 && unicode.IsUpper(word[0])):
+		return 2
+	default:
+		return math.Pow(2, float64(min(upper, lower)))
+	}
+}
+
+// normalize returns password lowercased, plus the readings with common
+// character substitutions undone. Every variant has one rune per rune of
+// password, so positions line up.
+func normalize(password string) []string {
+	lower := lowerRunes([]rune(password))
+	variants := []string{string(lower)}
+
+	for _, one := range []rune{'i', 'l'} {
+		substituted := make([]rune, len(lower))
+		for i, r := range lower {
+			substituted[i] = r
+			if r == '1' {
+				substituted[i] = one
+			} else if plain, ok := leet[r]; ok {
+				substituted[i] = plain
+			}
+		}
+		if variant := string(substituted); !slices.Contains(variants, variant) {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
@@ -10,49 +10,179 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
+// jtiBytes is the entropy of the unique token identifier (jti claim).
+const jtiBytes = 16
+
+// Token types, sent in the "typ" header so that one kind of token can never
+// be replayed as another (e.g. an ID token presented as an access token).
+const (
+	TypeAccessToken = "at+jwt" // RFC 9068
+	TypeIDToken     = "JWT"
+
+	TypeEmailVerification = "email-verification+jwt"
+)
+
+// Claims are the claims of an access token. The registered claims carry
+// iss, sub (the user ID), aud, exp, iat, nbf and jti.
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
+
+	ClientID string `json:"client_id,omitempty"` // OAuth client the token was issued to
+	Scope    string `json:"scope,omitempty"`     // Space-delimited granted scope
+	TokenUse string `json:"token_use,omitempty"` // TokenUseService for machine identities
+
+	AMR  []string   `json:"amr,omitempty"`  // Authentication methods used at login (RFC 8176)
+	Risk *RiskClaim `json:"risk,omitempty"` // Risk assessment of the login, when it was scored
+
+	// Restricted tokens belong to users who have not verified their email
+	// yet; they are only good for the account's own verification endpoints.
+	Restricted bool `json:"restricted,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, email, secret string, expiry time.Duration) (string, error) {
-	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+// RiskClaim is the risk assessment of the login a token descends from:
+// a score from 0 to 100 and the codes of the signals that contributed.
+type RiskClaim struct {
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// TokenUseService marks a client_credentials token: the subject is an OAuth
+// client (a downstream service), not a human user, and UserID is empty.
+const TokenUseService = "service"
+
+// IsServiceToken reports whether the token identifies a service rather than a user.
+func (c *Claims) IsServiceToken() bool {
+	return c.TokenUse == TokenUseService
+}
+
+// IDTokenClaims are the claims of an OpenID Connect ID token.
+type IDTokenClaims struct {
+	Email    string           `json:"email,omitempty"`
+	Nonce    string           `json:"nonce,omitempty"`
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR      []string         `json:"amr,omitempty"`
+
+	EmailVerified bool `json:"email_verified"`
+	jwt.RegisteredClaims
+}
+
+// EmailVerificationClaims are the claims of an emailed verification link.
+// The subject is the user ID; Email pins the address the link was sent to.
+type EmailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// GenerateToken signs access token claims with the active key of keys. The
+// time claims and a fresh jti are filled in here; callers set the identity.
+func GenerateToken(claims *Claims, keys KeySet, expiry time.Duration) (string, error) {
+	if err := stampRegisteredClaims(&claims.RegisteredClaims, expiry); err != nil {
+		return "", err
+	}
+	return sign(claims, TypeAccessToken, keys)
+}
+
+// GenerateIDToken signs OpenID Connect ID token claims.
+func GenerateIDToken(claims *IDTokenClaims, keys KeySet, expiry time.Duration) (string, error) {
+	if err := stampRegisteredClaims(&claims.RegisteredClaims, expiry); err != nil {
+		return "", err
+	}
+	return sign(claims, TypeIDToken, keys)
+}
+
+// GenerateEmailVerificationToken signs the claims of a verification link.
+func GenerateEmailVerificationToken(claims *EmailVerificationClaims, keys KeySet, expiry time.Duration) (string, error) {
+	if err := stampRegisteredClaims(&claims.RegisteredClaims, expiry); err != nil {
+		return "", err
+	}
+	return sign(claims, TypeEmailVerification, keys)
+}
+
+// ParseToken verifies an access token. Extra parser options (such as
+// jwt.WithIssuer and jwt.WithAudience) add registered claim checks.
+func ParseToken(tokenString string, keys KeySet, opts ...jwt.ParserOption) (*Claims, error) {
+	claims := &Claims{}
+	if err := parse(tokenString, TypeAccessToken, claims, keys, opts); err != nil {
+		return nil, err
 	}
+	return claims, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+// ParseEmailVerificationToken verifies the token of a verification link.
+func ParseEmailVerificationToken(tokenString string, keys KeySet, opts ...jwt.ParserOption) (*EmailVerificationClaims, error) {
+	claims := &EmailVerificationClaims{}
+	if err := parse(tokenString, TypeEmailVerification, claims, keys, opts); err != nil {
+		return nil, err
+	}
+	return claims, nil
 }
 
-func ParseToken(tokenString, secret string) (*Claims, error) {
+// parse verifies a token of the given type into claims.
+func parse(tokenString, typ string, claims jwt.Claims, keys KeySet, opts []jwt.ParserOption) error {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
-		&Claims{},
+		claims,
 		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			if header, _ := token.Header["typ"].(string); header != typ {
+				return nil, ErrInvalidToken
+			}
+			kid, _ := token.Header["kid"].(string)
+			key, ok := keys.VerificationKey(kid)
+			if !ok {
+				return nil, ErrInvalidToken
+			}
+			// The algorithm is pinned by the key, never chosen by the token
+			if token.Method.Alg() != key.Method.Alg() {
 				return nil, ErrInvalidToken
 			}
-			return []byte(secret), nil
+			return key.verifyKey, nil
 		},
+		opts...,
 	)
 
 	if err != nil {
-		return nil, ErrInvalidToken
+		// Expired tokens are reported separately so clients know to re-authenticate
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return ErrExpiredToken
+		}
+		return ErrInvalidToken
 	}
 
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, ErrInvalidToken
+	if !token.Valid {
+		return ErrInvalidToken
 	}
 
-	return claims, nil
+	return nil
+}
+
+func stampRegisteredClaims(rc *jwt.RegisteredClaims, expiry time.Duration) error {
+	jti, err := GenerateRandomToken(jtiBytes)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rc.ID = jti
+	rc.ExpiresAt = jwt.NewNumericDate(now.Add(expiry))
+	rc.IssuedAt = jwt.NewNumericDate(now)
+	rc.NotBefore = jwt.NewNumericDate(now)
+	return nil
+}
+
+func sign(claims jwt.Claims, typ string, keys KeySet) (string, error) {
+	key, err := keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["typ"] = typ
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
 }
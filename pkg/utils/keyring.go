@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrNoSigningKey = errors.New("no active signing key")
+
+// rsaKeyBits is the modulus size of generated RS256 keys.
+const rsaKeyBits = 3072
+
+// KeyRing holds one active signing key plus any number of verification-only
+// keys. Rotating swaps the whole ring atomically, so tokens signed by a
+// retired key keep verifying until that key is dropped from the ring.
+type KeyRing struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey // kid -> key, including the active one
+}
+
+// NewKeyRing returns a ring signing with active. active may be nil until the
+// first Replace, in which case SigningKey returns ErrNoSigningKey.
+func NewKeyRing(active *SigningKey, verificationKeys ...*SigningKey) *KeyRing {
+	r := &KeyRing{}
+	r.Replace(active, verificationKeys)
+	return r
+}
+
+// Replace swaps the ring contents.
+func (r *KeyRing) Replace(active *SigningKey, verificationKeys []*SigningKey) {
+	keys := make(map[string]*SigningKey, len(verificationKeys)+1)
+	for _, k := range verificationKeys {
+		keys[k.ID] = k.VerificationOnly()
+	}
+	if active != nil {
+		keys[active.ID] = active
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active = active
+	r.keys = keys
+}
+
+func (r *KeyRing) SigningKey() (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.active == nil {
+		return nil, ErrNoSigningKey
+	}
+	return r.active, nil
+}
+
+// VerificationKey returns the key named by kid. Every token this service
+// issues carries a kid, so a token without one never verifies.
+func (r *KeyRing) VerificationKey(kid string) (*SigningKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	k, ok := r.keys[kid]
+	return k, ok
+}
+
+func (r *KeyRing) PublicKeys() []*SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []*SigningKey
+	if r.active != nil && r.active.IsAsymmetric() {
+		keys = append(keys, r.active)
+	}
+	for kid, k := range r.keys {
+		if r.active != nil && kid == r.active.ID {
+			continue
+		}
+		if k.IsAsymmetric() {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// VerificationOnly returns a copy of the key without its private half.
+// HMAC keys cannot be split and are returned unchanged.
+func (k *SigningKey) VerificationOnly() *SigningKey {
+	if !k.IsAsymmetric() {
+		return k
+	}
+	return &SigningKey{
+		ID:        k.ID,
+		Method:    k.Method,
+		verifyKey: k.verifyKey,
+	}
+}
+
+// GenerateSigningKey creates a fresh asymmetric key identified by its thumbprint.
+func GenerateSigningKey(alg string) (*SigningKey, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+
+	switch alg {
+	case AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: cannot generate %s keys", ErrUnsupportedAlgorithm, alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPrivateKey("", alg, key)
+}
+
+// MarshalPrivateKeyPEM encodes the private half as a PKCS#8 PEM block.
+func (k *SigningKey) MarshalPrivateKeyPEM() ([]byte, error) {
+	if !k.IsAsymmetric() || !k.CanSign() {
+		return nil, ErrKeyAlgorithmMismatch
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k.signKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
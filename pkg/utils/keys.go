@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrKeyAlgorithmMismatch = errors.New("key type does not match signing algorithm")
+)
+
+// Supported signing algorithms.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey is a single key used to sign and/or verify tokens.
+// For HMAC both halves are the shared secret; for asymmetric algorithms
+// only the public half is ever published.
+type SigningKey struct {
+	ID     string // Published as the "kid" header
+	Method jwt.SigningMethod
+
+	signKey   interface{} // []byte or crypto.Signer; nil for verification-only keys
+	verifyKey interface{} // []byte or crypto.PublicKey
+}
+
+// NewHMACKey wraps a shared secret as an HS256 key.
+func NewHMACKey(id string, secret []byte) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// NewPrivateKey wraps an asymmetric private key for the given algorithm.
+// An empty id is replaced by the key's RFC 7638 thumbprint.
+func NewPrivateKey(id, alg string, key crypto.Signer) (*SigningKey, error) {
+	method, err := asymmetricMethod(alg, key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	k := &SigningKey{
+		ID:        id,
+		Method:    method,
+		signKey:   key,
+		verifyKey: key.Public(),
+	}
+
+	if k.ID == "" {
+		if k.ID, err = k.Thumbprint(); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// ParsePrivateKeyPEM parses a PEM encoded private key for the given algorithm.
+// PKCS#1, PKCS#8 and SEC 1 encodings are accepted.
+func ParsePrivateKeyPEM(id, alg string, data []byte) (*SigningKey, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+
+	switch alg {
+	case AlgRS256:
+		key, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+	case AlgES256:
+		key, err = jwt.ParseECPrivateKeyFromPEM(data)
+	case AlgEdDSA:
+		var parsed crypto.PrivateKey
+		parsed, err = jwt.ParseEdPrivateKeyFromPEM(data)
+		if err == nil {
+			key, _ = parsed.(crypto.Signer)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s private key: %w", alg, err)
+	}
+
+	return NewPrivateKey(id, alg, key)
+}
+
+// LoadPrivateKeyFile reads a PEM encoded private key from disk.
+func LoadPrivateKeyFile(id, alg, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key %s: %w", path, err)
+	}
+	return ParsePrivateKeyPEM(id, alg, data)
+}
+
+// IsAsymmetric reports whether the key can be published in a JWKS.
+func (k *SigningKey) IsAsymmetric() bool {
+	_, isHMAC := k.verifyKey.([]byte)
+	return !isHMAC
+}
+
+// CanSign reports whether the key holds private material.
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// PublicKey returns the verification half of an asymmetric key, or nil for HMAC keys.
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	if !k.IsAsymmetric() {
+		return nil
+	}
+	return k.verifyKey
+}
+
+func asymmetricMethod(alg string, pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgRS256:
+		if _, ok := pub.(*rsa.PublicKey); !ok {
+			return nil, ErrKeyAlgorithmMismatch
+		}
+		return jwt.SigningMethodRS256, nil
+	case AlgES256:
+		ec, ok := pub.(*ecdsa.PublicKey)
+		if !ok || ec.Curve != elliptic.P256() {
+			return nil, ErrKeyAlgorithmMismatch
+		}
+		return jwt.SigningMethodES256, nil
+	case AlgEdDSA:
+		if _, ok := pub.(ed25519.PublicKey); !ok {
+			return nil, ErrKeyAlgorithmMismatch
+		}
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+// =============================================================================
+// KEY SETS
+// =============================================================================
+
+// KeySet resolves the keys used to sign new tokens and verify presented ones.
+type KeySet interface {
+	// SigningKey returns the key new tokens are signed with.
+	SigningKey() (*SigningKey, error)
+
+	// VerificationKey returns the key matching a token's "kid" header.
+	VerificationKey(kid string) (*SigningKey, bool)
+
+	// PublicKeys returns every asymmetric key that may still verify tokens.
+	PublicKeys() []*SigningKey
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// PKCEMethodS256 is the only code challenge method accepted (OAuth 2.1).
+const PKCEMethodS256 = "S256"
+
+// ValidPKCEVerifier reports whether v is a well-formed RFC 7636 code verifier:
+// 43 to 128 characters from the unreserved URI character set.
+func ValidPKCEVerifier(v string) bool {
+	if len(v) < 43 || len(v) > 128 {
+		return false
+	}
+	for _, r := range v {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case r == '-', r == '.', r == '_', r == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidPKCEChallenge reports whether c looks like an S256 code challenge
+// (the unpadded base64url encoding of a SHA-256 digest).
+func ValidPKCEChallenge(c string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(c)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// VerifyPKCE checks a code verifier against an S256 code challenge.
+func VerifyPKCE(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
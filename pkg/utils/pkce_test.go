@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testVerifier  = "dBjftJeZ4CVP-mJ92K9JeWaMjM6FdDqY8Nap5GFQ3DM"
+	testChallenge = "h1m1DLsSo5GcqeO8H9OZ9LhPvMth67u9aMzrqhta510"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	tests := []struct {
+		verifier, challenge string
+		want                bool
+	}{
+		{testVerifier, testChallenge, true},
+		{testVerifier + "x", testChallenge, false},
+		{testVerifier, testChallenge + "=", false},
+		{testVerifier, strings.ToLower(testChallenge), false},
+		{testChallenge, testVerifier, false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.want {
+			t.Errorf("VerifyPKCE(%q, %q) = %v, want %v", tt.verifier, tt.challenge, got, tt.want)
+		}
+	}
+}
+
+func TestValidPKCEVerifier(t *testing.T) {
+	tests := []struct {
+		verifier string
+		want     bool
+	}{
+		{testVerifier, true},
+		{strings.Repeat("a", 43), true},
+		{strings.Repeat("a", 128), true},
+		{"AZaz09-._~" + strings.Repeat("a", 33), true},
+		{strings.Repeat("a", 42), false},
+		{strings.Repeat("a", 129), false},
+		{strings.Repeat("a", 42) + "+", false},
+		{strings.Repeat("a", 42) + "/", false},
+		{strings.Repeat("a", 42) + "=", false},
+		{strings.Repeat("é", 43), false},
+	}
+	for _, tt := range tests {
+		if got := ValidPKCEVerifier(tt.verifier); got != tt.want {
+			t.Errorf("ValidPKCEVerifier(%q) = %v, want %v", tt.verifier, got, tt.want)
+		}
+	}
+}
+
+func TestValidPKCEChallenge(t *testing.T) {
+	tests := []struct {
+		challenge string
+		want      bool
+	}{
+		{testChallenge, true},
+		{testChallenge + "=", false}, // Padded
+		{testChallenge[:42], false},  // Too short for SHA-256
+		{testVerifier + testVerifier, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidPKCEChallenge(tt.challenge); got != tt.want {
+			t.Errorf("ValidPKCEChallenge(%q) = %v, want %v", tt.challenge, got, tt.want)
+		}
+	}
+}
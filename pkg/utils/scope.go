@@ -0,0 +1,13 @@
+package utils
+
+import "strings"
+
+// HasScope reports whether a space-delimited OAuth scope string contains scope.
+func HasScope(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}